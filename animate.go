@@ -0,0 +1,104 @@
+package png2svg
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"strconv"
+	"strings"
+
+	"github.com/xyproto/tinysvg"
+)
+
+// ErrFrameSize is returned by AnimatedSVG if the frames are not of the same size
+var ErrFrameSize = errors.New("all frames must have the same size")
+
+// ErrNoFrames is returned by AnimatedSVG if there are no frames
+var ErrNoFrames = errors.New("no frames to animate")
+
+// AnimatedSVG converts each of the given frames, which must have the same size,
+// and returns an SVG document where the frames are shown one at a time, in a loop,
+// with the given number of frames per second. Each frame is placed in a <g> tag,
+// where the visibility is animated with SMIL. Like for Convert, ErrTooManyRects is returned
+// if a frame needs more than opts.MaxRects rectangles, and ErrNotCovered if a frame could
+// not be covered.
+func AnimatedSVG(frames []image.Image, opts Options, fps float64) ([]byte, error) {
+	if len(frames) == 0 {
+		return nil, ErrNoFrames
+	}
+	if fps <= 0 {
+		fps = 10
+	}
+	size := frames[0].Bounds().Size()
+	for _, frame := range frames[1:] {
+		if frame.Bounds().Size() != size {
+			return nil, ErrFrameSize
+		}
+	}
+
+	// The visibility of each frame, at each key time
+	n := len(frames)
+	keyTimes := make([]string, n)
+	for i := range keyTimes {
+		keyTimes[i] = strconv.FormatFloat(float64(i)/float64(n), 'g', 4, 64)
+	}
+	duration := strconv.FormatFloat(float64(n)/fps, 'g', -1, 64) + "s"
+
+	var (
+		buf        bytes.Buffer
+		needsXlink bool
+	)
+	if opts.Provenance {
+		writeProvenance(&buf, opts, opts.Timestamp)
+	}
+	for i, frame := range frames {
+		pi := NewPixelImage(frame, false)
+		pi.SetOptions(opts)
+		pi.classPrefix = "f" + strconv.Itoa(i)
+		pi.Cover()
+		if pi.TooManyRects() {
+			return nil, ErrTooManyRects
+		}
+		if !pi.Done(0, 0) {
+			return nil, ErrNotCovered
+		}
+		needsXlink = needsXlink || pi.usesXlink()
+
+		values := make([]string, n)
+		for j := range values {
+			values[j] = "hidden"
+		}
+		values[i] = "visible"
+
+		buf.WriteString("<g visibility=\"")
+		buf.WriteString(values[0])
+		buf.WriteString("\"><animate attributeName=\"visibility\" calcMode=\"discrete\" values=\"")
+		buf.WriteString(strings.Join(values, ";"))
+		buf.WriteString("\" keyTimes=\"")
+		buf.WriteString(strings.Join(keyTimes, ";"))
+		buf.WriteString("\" dur=\"")
+		buf.WriteString(duration)
+		buf.WriteString("\" repeatCount=\"indefinite\"/>")
+		pi.writeGroups(&buf)
+		buf.WriteString("</g>")
+	}
+
+	_, svgTag := tinysvg.NewTinySVG(size.X, size.Y)
+	svgTag.AppendContent(buf.Bytes())
+	svgDocument := svgTag.Bytes()
+	if needsXlink {
+		svgDocument = addXlinkNamespace(svgDocument)
+	}
+	return optimize(append([]byte(xmlHeader), svgDocument...)), nil
+}
+
+// WriteAnimatedSVG will save the given frames as an animated SVG image,
+// or write it to stdout if filename is "-".
+// The line endings and byte order mark are given by opts.CRLF and opts.BOM.
+func WriteAnimatedSVG(filename string, frames []image.Image, opts Options, fps float64) error {
+	svgDocument, err := AnimatedSVG(frames, opts, fps)
+	if err != nil {
+		return err
+	}
+	return writeFile(filename, encode(svgDocument, opts.CRLF, opts.BOM))
+}
@@ -0,0 +1,74 @@
+package png2svg
+
+import (
+	"encoding/xml"
+	"image"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestAnimatedSVG(t *testing.T) {
+	frames := []image.Image{
+		newTestImage(testPalette, "rr.", "bbb"),
+		newTestImage(testPalette, ".rr", "bbb"),
+		newTestImage(testPalette, "r.r", "ggb"),
+	}
+	svgDocument, err := AnimatedSVG(frames, Options{FillMode: FillClass}, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var root svgNode
+	if err := xml.Unmarshal(svgDocument, &root); err != nil {
+		t.Fatal(err)
+	}
+	if len(root.Children) != len(frames) {
+		t.Fatalf("expected one group per frame, got %d", len(root.Children))
+	}
+	for i, group := range root.Children {
+		animate := group.Children[0]
+		if animate.XMLName.Local != "animate" {
+			t.Fatalf("expected an <animate> tag first in frame %d", i)
+		}
+		values, _ := animate.attr("values")
+		want := []string{"hidden", "hidden", "hidden"}
+		want[i] = "visible"
+		if values != strings.Join(want, ";") {
+			t.Errorf("frame %d: expected the values %q, got %q", i, strings.Join(want, ";"), values)
+		}
+		if keyTimes, _ := animate.attr("keyTimes"); keyTimes != "0;0.3333;0.6667" {
+			t.Errorf("frame %d: unexpected keyTimes %q", i, keyTimes)
+		}
+		if dur, _ := animate.attr("dur"); dur != "0.3s" {
+			t.Errorf("frame %d: expected a duration of 0.3s, got %q", i, dur)
+		}
+	}
+
+	// Each frame renders as the original frame when only that frame is visible,
+	// even though the CSS classes of all frames are in the same document
+	visibility := regexp.MustCompile(`<g visibility="(visible|hidden)">`)
+	for i, frame := range frames {
+		n := 0
+		shown := visibility.ReplaceAllFunc(svgDocument, func([]byte) []byte {
+			n++
+			if n-1 == i {
+				return []byte(`<g visibility="visible">`)
+			}
+			return []byte(`<g visibility="hidden">`)
+		})
+		checkRendersAs(t, shown, frame, 0)
+	}
+
+	if _, err := AnimatedSVG(nil, Options{}, 10); err != ErrNoFrames {
+		t.Errorf("expected ErrNoFrames, got %v", err)
+	}
+	frames = append(frames, newTestImage(testPalette, "rr"))
+	if _, err := AnimatedSVG(frames, Options{}, 10); err != ErrFrameSize {
+		t.Errorf("expected ErrFrameSize, got %v", err)
+	}
+	// Each frame must be covered with at most MaxRects rectangles
+	noise := []image.Image{newTestImage(testPalette, "rr"), noiseImage(2, 1, 2)}
+	if _, err := AnimatedSVG(noise, Options{MaxRects: 1}, 10); err != ErrTooManyRects {
+		t.Errorf("expected ErrTooManyRects, got %v", err)
+	}
+}
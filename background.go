@@ -0,0 +1,186 @@
+package png2svg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/color"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// pngSignature is the first 8 bytes of all PNG files
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// ErrNotPNG is returned by PNGBackground if the data is not a PNG image
+var ErrNotPNG = errors.New("not a PNG image")
+
+// PNGBackground reads the PNG chunks from the given io.Reader and returns the
+// suggested background color from the bKGD chunk, or nil if there is none.
+func PNGBackground(r io.Reader) (*color.NRGBA, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, ErrNotPNG
+	}
+	var (
+		pos       = len(pngSignature)
+		bitDepth  byte
+		colorType byte
+		palette   []byte
+	)
+	for pos+8 <= len(data) {
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		chunkType := string(data[pos+4 : pos+8])
+		pos += 8
+		if length < 0 || pos+length > len(data) {
+			break
+		}
+		chunk := data[pos : pos+length]
+		// Skip the chunk data and the CRC
+		pos += length + 4
+		switch chunkType {
+		case "IHDR":
+			if len(chunk) >= 10 {
+				bitDepth, colorType = chunk[8], chunk[9]
+			}
+		case "PLTE":
+			palette = chunk
+		case "bKGD":
+			return backgroundFromChunk(chunk, bitDepth, colorType, palette)
+		case "IDAT", "IEND":
+			// bKGD must come before the image data
+			return nil, nil
+		}
+	}
+	return nil, nil
+}
+
+// sample converts a grayscale or color sample with the given bit depth to 0..255
+func sample(x uint16, bitDepth byte) uint8 {
+	switch {
+	case bitDepth == 16:
+		return uint8(x >> 8)
+	case bitDepth < 8 && bitDepth > 0:
+		return uint8(uint32(x) * 255 / (1<<bitDepth - 1))
+	}
+	return uint8(x)
+}
+
+// backgroundFromChunk interprets the data of a bKGD chunk, which depends on the color type
+func backgroundFromChunk(chunk []byte, bitDepth, colorType byte, palette []byte) (*color.NRGBA, error) {
+	switch colorType {
+	case 3: // indexed
+		if len(chunk) < 1 || int(chunk[0])*3+3 > len(palette) {
+			return nil, fmt.Errorf("invalid bKGD palette index")
+		}
+		i := int(chunk[0]) * 3
+		return &color.NRGBA{palette[i], palette[i+1], palette[i+2], 0xff}, nil
+	case 0, 4: // grayscale, grayscale with alpha
+		if len(chunk) < 2 {
+			return nil, fmt.Errorf("invalid bKGD grayscale value")
+		}
+		v := sample(binary.BigEndian.Uint16(chunk), bitDepth)
+		return &color.NRGBA{v, v, v, 0xff}, nil
+	case 2, 6: // RGB, RGB with alpha
+		if len(chunk) < 6 {
+			return nil, fmt.Errorf("invalid bKGD color value")
+		}
+		return &color.NRGBA{
+			sample(binary.BigEndian.Uint16(chunk[0:]), bitDepth),
+			sample(binary.BigEndian.Uint16(chunk[2:]), bitDepth),
+			sample(binary.BigEndian.Uint16(chunk[4:]), bitDepth),
+			0xff,
+		}, nil
+	}
+	return nil, nil
+}
+
+// ReadPNGBackground returns the suggested background color from the bKGD chunk
+// of the given PNG image filename, or nil if there is none
+func ReadPNGBackground(filename string) (*color.NRGBA, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return PNGBackground(f)
+}
+
+// SetBackground places all pixels on top of the given background color,
+// so that transparent pixels gets the background color and partially
+// transparent pixels are blended with it. All pixels become opaque.
+// In verbose mode, a warning is printed for each set of distinct colors
+// that end up as the same opaque color. Pixels that are already covered are
+// also placed on the background, so SetBackground should be called before
+// SetPixelFilter and SetChromaKey, which is the order that SetOptions uses.
+func (pi *PixelImage) SetBackground(bg color.NRGBA) {
+	pi.background = &bg
+	blend := func(fg, bg, a int) int {
+		return (fg*a + bg*(255-a) + 127) / 255
+	}
+	// The distinct colors that each opaque color comes from
+	var sources map[color.NRGBA]map[color.NRGBA]bool
+	if pi.verbose {
+		sources = make(map[color.NRGBA]map[color.NRGBA]bool)
+	}
+	for _, p := range pi.pixels {
+		before := color.NRGBA{uint8(p.r), uint8(p.g), uint8(p.b), uint8(p.a)}
+		if p.a == 0 {
+			// All fully transparent pixels look the same
+			before = color.NRGBA{}
+		}
+		if p.a != 255 {
+			p.r = blend(p.r, int(bg.R), p.a)
+			p.g = blend(p.g, int(bg.G), p.a)
+			p.b = blend(p.b, int(bg.B), p.a)
+			p.a = 255
+			p.covered = false
+		}
+		if sources != nil {
+			after := color.NRGBA{uint8(p.r), uint8(p.g), uint8(p.b), 255}
+			if sources[after] == nil {
+				sources[after] = make(map[color.NRGBA]bool)
+			}
+			sources[after][before] = true
+		}
+	}
+	if sources != nil {
+		warnCollapsedColors(sources)
+	}
+}
+
+// warnCollapsedColors prints a warning for each opaque color that several
+// distinct colors were blended into, sorted by the opaque color
+func warnCollapsedColors(sources map[color.NRGBA]map[color.NRGBA]bool) {
+	hex := func(c color.NRGBA) string {
+		return string(hexColor(int(c.R), int(c.G), int(c.B)))
+	}
+	var collapsed []string
+	for after, befores := range sources {
+		if len(befores) < 2 {
+			continue
+		}
+		var names []string
+		for before := range befores {
+			if before.A == 0 {
+				names = append(names, "transparent")
+			} else {
+				names = append(names, hex(before)+" with alpha "+strconv.Itoa(int(before.A)))
+			}
+		}
+		sort.Strings(names)
+		collapsed = append(collapsed, hex(after)+" <- "+strings.Join(names, ", "))
+	}
+	sort.Strings(collapsed)
+	for _, line := range collapsed {
+		fmt.Println("Warning: distinct colors became the same color on the background: " + line)
+	}
+}
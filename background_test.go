@@ -0,0 +1,165 @@
+package png2svg
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// withChunk encodes the given image as a PNG image, with the given chunk inserted before the IDAT chunk
+func withChunk(t *testing.T, img image.Image, chunkType string, data []byte) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	encoded := buf.Bytes()
+	// The chunk starts 4 bytes before the chunk type, with the length
+	idat := bytes.Index(encoded, []byte("IDAT")) - 4
+	chunk := make([]byte, 8, 12+len(data))
+	binary.BigEndian.PutUint32(chunk, uint32(len(data)))
+	copy(chunk[4:], chunkType)
+	chunk = append(chunk, data...)
+	crc := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc, crc32.ChecksumIEEE(chunk[4:]))
+	chunk = append(chunk, crc...)
+	return append(append(append([]byte{}, encoded[:idat]...), chunk...), encoded[idat:]...)
+}
+
+func TestPNGBackground(t *testing.T) {
+	rgba := image.NewNRGBA(image.Rect(0, 0, 2, 2))
+	rgba.SetNRGBA(0, 0, color.NRGBA{1, 2, 3, 128})
+	gray := image.NewGray16(image.Rect(0, 0, 2, 2))
+	paletted := image.NewPaletted(image.Rect(0, 0, 2, 2), color.Palette{color.Black, color.NRGBA{0x12, 0x34, 0x56, 255}})
+
+	for _, tc := range []struct {
+		name string
+		data []byte
+		want *color.NRGBA
+	}{
+		{"rgba", withChunk(t, rgba, "bKGD", []byte{0, 0xff, 0, 0x80, 0, 0x10}), &color.NRGBA{0xff, 0x80, 0x10, 255}},
+		{"gray16", withChunk(t, gray, "bKGD", []byte{0x80, 0x00}), &color.NRGBA{0x80, 0x80, 0x80, 255}},
+		{"paletted", withChunk(t, paletted, "bKGD", []byte{1}), &color.NRGBA{0x12, 0x34, 0x56, 255}},
+		{"no bKGD", withChunk(t, rgba, "tEXt", []byte("Comment\x00no background")), nil},
+	} {
+		got, err := PNGBackground(bytes.NewReader(tc.data))
+		if err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+			continue
+		}
+		if (got == nil) != (tc.want == nil) || (got != nil && *got != *tc.want) {
+			t.Errorf("%s: expected the background %v, got %v", tc.name, tc.want, got)
+		}
+		// The image can still be decoded
+		if _, err := png.Decode(bytes.NewReader(tc.data)); err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+	}
+
+	if _, err := PNGBackground(bytes.NewReader(withChunk(t, paletted, "bKGD", []byte{7}))); err == nil {
+		t.Error("expected an error for a palette index that is out of range")
+	}
+	if _, err := PNGBackground(bytes.NewReader([]byte("GIF89a"))); err != ErrNotPNG {
+		t.Errorf("expected ErrNotPNG, got %v", err)
+	}
+}
+
+func TestSample(t *testing.T) {
+	for _, tc := range []struct {
+		x        uint16
+		bitDepth byte
+		want     uint8
+	}{
+		{1, 1, 255},
+		{2, 2, 170},
+		{15, 4, 255},
+		{0x7f, 8, 0x7f},
+		{0xabcd, 16, 0xab},
+	} {
+		if got := sample(tc.x, tc.bitDepth); got != tc.want {
+			t.Errorf("sample(%d, %d) = %d, expected %d", tc.x, tc.bitDepth, got, tc.want)
+		}
+	}
+}
+
+func TestSetBackground(t *testing.T) {
+	img := newTestImage(testPalette,
+		"rr..",
+		"hbb.",
+	)
+	pi := NewPixelImage(img, false)
+	pi.SetBackground(color.NRGBA{255, 255, 255, 255})
+	pi.Cover()
+	palette := map[byte]color.NRGBA{
+		'r': testPalette['r'],
+		'b': testPalette['b'],
+		'w': testPalette['w'],
+		'p': {255, 127, 127, 255}, // half transparent red on white
+	}
+	checkRendersAs(t, pi.Bytes(), newTestImage(palette, "rrww", "pbbw"), 0)
+}
+
+// captureStdout returns what the given function writes to stdout
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	f()
+	w.Close()
+	output, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(output)
+}
+
+func TestCollapsedColorWarnings(t *testing.T) {
+	palette := map[byte]color.NRGBA{
+		'w': {255, 255, 255, 255},
+		'a': {255, 0, 0, 0},       // transparent red
+		'b': {0, 0, 255, 0},       // transparent blue
+		'c': {255, 255, 255, 128}, // translucent white
+		'r': {255, 0, 0, 255},
+		's': {255, 0, 0, 254}, // almost opaque red, which becomes #ff0101
+	}
+	img := newTestImage(palette,
+		"wabc",
+		"rsrr",
+	)
+	white := palette['w']
+	output := captureStdout(t, func() {
+		pi := NewPixelImage(img, true)
+		pi.SetBackground(white)
+	})
+	want := []string{
+		"Warning: distinct colors became the same color on the background: #ffffff <- #ffffff with alpha 128, #ffffff with alpha 255, transparent",
+	}
+	var got []string
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "Warning:") {
+			got = append(got, line)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the warnings\n%s\ngot\n%s", strings.Join(want, "\n"), output)
+	}
+
+	// Without verbose mode, nothing is printed
+	if output := captureStdout(t, func() {
+		NewPixelImage(img, false).SetBackground(white)
+	}); output != "" {
+		t.Errorf("expected no output, got %q", output)
+	}
+}
@@ -0,0 +1,76 @@
+package png2svg
+
+import (
+	"bytes"
+	"image"
+	"strconv"
+
+	"github.com/xyproto/tinysvg"
+)
+
+// BandedSVG converts the given image in horizontal bands of the given height, one band
+// at a time, and returns an SVG document where each band is placed in a <g> tag that
+// is translated to the y offset of the band. Only the pixels of one band are kept in
+// memory at a time, which makes it possible to convert very tall images. Note that the
+// given image itself is not split up, so it still needs to fit in memory.
+// Rectangles do not span the band boundaries, so more rectangles may be needed than
+// when converting the whole image at once. Like for Convert, ErrTooManyRects is returned
+// if a band needs more than opts.MaxRects rectangles, and ErrNotCovered if a band could
+// not be covered.
+func BandedSVG(img image.Image, opts Options, bandHeight int) ([]byte, error) {
+	bounds := img.Bounds()
+	if bandHeight < 1 {
+		bandHeight = bounds.Dy()
+	}
+	var (
+		buf        bytes.Buffer
+		needsXlink bool
+	)
+	if opts.Provenance {
+		writeProvenance(&buf, opts, opts.Timestamp)
+	}
+	writeViews(&buf, opts.Views)
+	for i, y := 0, 0; y < bounds.Dy(); i, y = i+1, y+bandHeight {
+		bandRect := image.Rect(0, y, bounds.Dx(), y+bandHeight).Add(bounds.Min)
+		pi := NewPixelImage(cropImage(img, bandRect), false)
+		pi.SetOptions(opts)
+		pi.classPrefix = "b" + strconv.Itoa(i)
+		pi.Cover()
+		if pi.TooManyRects() {
+			return nil, ErrTooManyRects
+		}
+		if !pi.Done(0, 0) {
+			return nil, ErrNotCovered
+		}
+		needsXlink = needsXlink || pi.usesXlink()
+
+		if y == 0 {
+			buf.WriteString("<g>")
+		} else {
+			buf.WriteString("<g transform=\"translate(0,")
+			buf.WriteString(strconv.Itoa(y))
+			buf.WriteString(")\">")
+		}
+		pi.writeGroups(&buf)
+		buf.WriteString("</g>")
+	}
+
+	_, svgTag := tinysvg.NewTinySVG(bounds.Dx(), bounds.Dy())
+	svgTag.AppendContent(buf.Bytes())
+	svgDocument := svgTag.Bytes()
+	if needsXlink {
+		svgDocument = addXlinkNamespace(svgDocument)
+	}
+	return optimize(append([]byte(xmlHeader), svgDocument...)), nil
+}
+
+// WriteBandedSVG will convert the given image in horizontal bands of the given height,
+// like BandedSVG, and save it as an SVG image, or write it to stdout if filename is "-".
+// The line endings and byte order mark are given by opts.CRLF and opts.BOM.
+func WriteBandedSVG(filename string, img image.Image, opts Options, bandHeight int) error {
+	svgDocument, err := BandedSVG(img, opts, bandHeight)
+	if err != nil {
+		return err
+	}
+	return writeFile(filename, encode(svgDocument, opts.CRLF, opts.BOM))
+}
@@ -0,0 +1,59 @@
+package png2svg
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+)
+
+func TestBandedSVG(t *testing.T) {
+	img := newTestImage(testPalette,
+		"rrrr",
+		"rrrr",
+		"rrbb",
+		"rrbb",
+		"gg.y",
+	)
+	for _, tc := range []struct {
+		bandHeight int
+		bands      int
+		rects      int
+	}{
+		{0, 1, 5},
+		{5, 1, 5},
+		{2, 3, 5},
+		{3, 2, 7},
+		{100, 1, 5},
+	} {
+		svgDocument, err := BandedSVG(img, Options{}, tc.bandHeight)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkRendersAs(t, svgDocument, img, 0)
+		var root svgNode
+		if err := xml.Unmarshal(svgDocument, &root); err != nil {
+			t.Fatal(err)
+		}
+		if len(root.Children) != tc.bands {
+			t.Errorf("band height %d: expected %d bands, got %d", tc.bandHeight, tc.bands, len(root.Children))
+		}
+		// Rectangles do not span the band boundaries
+		if got := bytes.Count(svgDocument, []byte("<rect")); got != tc.rects {
+			t.Errorf("band height %d: expected %d rectangles, got %d", tc.bandHeight, tc.rects, got)
+		}
+	}
+
+	// Each band has its own class names
+	svgDocument, err := BandedSVG(img, Options{FillMode: FillClass}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRendersAs(t, svgDocument, img, 0)
+	svgContains(t, svgDocument, `class="b0`)
+	svgContains(t, svgDocument, `class="b2`)
+
+	// Each band must be covered with at most MaxRects rectangles
+	if _, err := BandedSVG(noiseImage(8, 8, 4), Options{MaxRects: 4}, 4); err != ErrTooManyRects {
+		t.Errorf("expected ErrTooManyRects, got %v", err)
+	}
+}
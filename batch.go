@@ -0,0 +1,68 @@
+package png2svg
+
+import (
+	"runtime"
+)
+
+// convertFile reads and converts the given PNG image, and returns the SVG document
+func convertFile(path string, opts Options) ([]byte, error) {
+	img, err := ReadPNG(path, false)
+	if err != nil {
+		return nil, err
+	}
+	result, err := Convert(img, opts)
+	if err != nil {
+		return nil, err
+	}
+	return result.pi.Bytes(), nil
+}
+
+// ConvertFiles reads and converts all the given PNG images with the given options,
+// and calls onResult once per path, with either the SVG document or an error.
+// The images are converted concurrently, by up to runtime.NumCPU() goroutines, so
+// a DistanceFunc, SeedFunc or PixelFilter in the options must be safe for concurrent
+// use, for instance by not modifying any shared state without a mutex.
+// onResult is never called concurrently, and is called in the same order as the
+// paths are given. ConvertFiles returns when onResult has been called for all paths.
+func ConvertFiles(paths []string, opts Options, onResult func(path string, svg []byte, err error)) {
+	convertFiles(paths, opts, runtime.NumCPU(), onResult)
+}
+
+// convertFiles is ConvertFiles, with the given number of goroutines
+func convertFiles(paths []string, opts Options, workers int, onResult func(path string, svg []byte, err error)) {
+	type result struct {
+		svg []byte
+		err error
+	}
+	var (
+		results = make([]chan result, len(paths))
+		jobs    = make(chan int)
+	)
+	for i := range results {
+		results[i] = make(chan result, 1)
+	}
+	if workers > len(paths) {
+		workers = len(paths)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	for w := 0; w < workers; w++ {
+		go func() {
+			for i := range jobs {
+				svg, err := convertFile(paths[i], opts)
+				results[i] <- result{svg, err}
+			}
+		}()
+	}
+	go func() {
+		for i := range paths {
+			jobs <- i
+		}
+		close(jobs)
+	}()
+	for i, path := range paths {
+		r := <-results[i]
+		onResult(path, r.svg, r.err)
+	}
+}
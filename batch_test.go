@@ -0,0 +1,96 @@
+package png2svg
+
+import (
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// writePNG writes the given image as a PNG image to the given filename
+func writePNG(t *testing.T, filename string, img image.Image) {
+	t.Helper()
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConvertFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "png2svg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The larger images are first, so that the smaller images are converted before them
+	var (
+		paths  []string
+		images = make(map[string]*image.NRGBA)
+	)
+	for i, size := range []int{64, 48, 2, 32, 1, 16, 4, 8} {
+		path := filepath.Join(dir, strconv.Itoa(i)+".png")
+		images[path] = noiseImage(size, size, 5)
+		writePNG(t, path, images[path])
+		paths = append(paths, path)
+	}
+	missing := filepath.Join(dir, "missing.png")
+	paths = append(paths[:3], append([]string{missing}, paths[3:]...)...)
+
+	// The PixelFilter is called concurrently, so it must be safe for concurrent use
+	var (
+		mu       sync.Mutex
+		filtered = make(map[int]bool)
+	)
+	opts := Options{PixelFilter: func(x, y, r, g, b, a int) bool {
+		mu.Lock()
+		filtered[x] = true
+		mu.Unlock()
+		return true
+	}}
+
+	for _, workers := range []int{1, 4} {
+		var (
+			calls  []string
+			active int32
+		)
+		convertFiles(paths, opts, workers, func(path string, svg []byte, err error) {
+			if atomic.AddInt32(&active, 1) != 1 {
+				t.Errorf("%d workers: onResult is called concurrently", workers)
+			}
+			defer atomic.AddInt32(&active, -1)
+			calls = append(calls, path)
+			if path == missing {
+				if err == nil {
+					t.Errorf("%d workers: expected an error for the missing file", workers)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("%d workers: %v", workers, err)
+				return
+			}
+			checkRendersAs(t, svg, images[path], 0)
+		})
+		if len(calls) != len(paths) {
+			t.Fatalf("%d workers: expected %d calls, got %d", workers, len(paths), len(calls))
+		}
+		for i := range paths {
+			if calls[i] != paths[i] {
+				t.Errorf("%d workers: call %d was for %s, expected %s", workers, i, calls[i], paths[i])
+			}
+		}
+	}
+	if len(filtered) != 64 {
+		t.Errorf("expected the pixel filter to be used for all columns, got %d", len(filtered))
+	}
+}
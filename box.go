@@ -3,9 +3,6 @@ package png2svg
 import (
 	"fmt"
 	"math/rand"
-	"strconv"
-
-	"github.com/xyproto/tinysvg"
 )
 
 // Box represents a box with the following properties:
@@ -55,8 +52,20 @@ func (pi *PixelImage) CreateBox(x, y int) *Box {
 	return &Box{x, y, w, h, r, g, b, a}
 }
 
+// expandsInto returns true if the box can be expanded to include the pixel at (x, y),
+// which is when the pixel has the same color as the box. Boxes with a translucent color
+// are not expanded over pixels that are already covered, since those pixels would
+// then be blended twice.
+func (pi *PixelImage) expandsInto(bo *Box, x, y int) bool {
+	r, g, b, a := pi.At2(x, y)
+	if (r != bo.r) || (g != bo.g) || (b != bo.b) || (a != bo.a) {
+		return false
+	}
+	return a == 255 || !pi.Covered(x, y)
+}
+
 // ExpandLeft will expand a box 1 pixel to the left,
-// if all new pixels have the same color
+// if all new pixels have the same color (see expandsInto)
 func (pi *PixelImage) ExpandLeft(bo *Box) bool {
 	// Loop from box top left (-1,0) to box bot left (-1,0)
 	x := bo.x - 1
@@ -64,8 +73,7 @@ func (pi *PixelImage) ExpandLeft(bo *Box) bool {
 		return false
 	}
 	for y := bo.y; y < (bo.y + bo.h); y++ {
-		r, g, b, a := pi.At2(x, y)
-		if (r != bo.r) || (g != bo.g) || (b != bo.b) || (a != bo.a) {
+		if !pi.expandsInto(bo, x, y) {
 			return false
 		}
 	}
@@ -76,7 +84,7 @@ func (pi *PixelImage) ExpandLeft(bo *Box) bool {
 }
 
 // ExpandUp will expand a box 1 pixel upwards,
-// if all new pixels have the same color
+// if all new pixels have the same color (see expandsInto)
 func (pi *PixelImage) ExpandUp(bo *Box) bool {
 	// Loop from box top left to box top right
 	y := bo.y - 1
@@ -84,8 +92,7 @@ func (pi *PixelImage) ExpandUp(bo *Box) bool {
 		return false
 	}
 	for x := bo.x; x < (bo.x + bo.w); x++ {
-		r, g, b, a := pi.At2(x, y)
-		if (r != bo.r) || (g != bo.g) || (b != bo.b) || (a != bo.a) {
+		if !pi.expandsInto(bo, x, y) {
 			return false
 		}
 	}
@@ -96,7 +103,7 @@ func (pi *PixelImage) ExpandUp(bo *Box) bool {
 }
 
 // ExpandRight will expand a box 1 pixel to the right,
-// if all new pixels have the same color
+// if all new pixels have the same color (see expandsInto)
 func (pi *PixelImage) ExpandRight(bo *Box) bool {
 	// Loop from box top right (+1,0) to box bot right (+1,0)
 	x := bo.x + bo.w //+ 1
@@ -104,8 +111,7 @@ func (pi *PixelImage) ExpandRight(bo *Box) bool {
 		return false
 	}
 	for y := bo.y; y < (bo.y + bo.h); y++ {
-		r, g, b, a := pi.At2(x, y)
-		if (r != bo.r) || (g != bo.g) || (b != bo.b) || (a != bo.a) {
+		if !pi.expandsInto(bo, x, y) {
 			return false
 		}
 	}
@@ -115,7 +121,7 @@ func (pi *PixelImage) ExpandRight(bo *Box) bool {
 }
 
 // ExpandDown will expand a box 1 pixel downwards,
-// if all new pixels have the same color
+// if all new pixels have the same color (see expandsInto)
 func (pi *PixelImage) ExpandDown(bo *Box) bool {
 	// Loop from box bot left to box bot right
 	y := bo.y + bo.h //+ 1
@@ -123,8 +129,7 @@ func (pi *PixelImage) ExpandDown(bo *Box) bool {
 		return false
 	}
 	for x := bo.x; x < (bo.x + bo.w); x++ {
-		r, g, b, a := pi.At2(x, y)
-		if (r != bo.r) || (g != bo.g) || (b != bo.b) || (a != bo.a) {
+		if !pi.expandsInto(bo, x, y) {
 			return false
 		}
 	}
@@ -133,64 +138,86 @@ func (pi *PixelImage) ExpandDown(bo *Box) bool {
 	return true
 }
 
-// ExpandOnce tries to expand the box to the right and downwards, once
+// withinAspect returns true if a box with the given width and height
+// does not exceed the maximum aspect ratio, if one is set.
+// A single pixel is always within the maximum aspect ratio.
+func (pi *PixelImage) withinAspect(w, h int) bool {
+	if pi.maxAspect <= 0 || (w <= 1 && h <= 1) {
+		return true
+	}
+	return float64(w) <= pi.maxAspect*float64(h) && float64(h) <= pi.maxAspect*float64(w)
+}
+
+// mayGrowTo returns true if a box with the given width and height is within the
+// maximum aspect ratio, or will be if it is expanded once more in the other direction
+func (pi *PixelImage) mayGrowTo(w, h int) bool {
+	return pi.withinAspect(w, h) || pi.withinAspect(w+1, h) || pi.withinAspect(w, h+1)
+}
+
+// ExpandOnce tries to expand the box to the right and downwards, once.
+// The box is preferably expanded within the maximum aspect ratio. If that is not possible,
+// it may be expanded to a shape that exceeds it, if expanding once more in the other
+// direction would bring it back within. This lets a box with a small maximum aspect ratio,
+// like 1.5, grow through shapes like 2x1 on the way to 2x2. Expand shrinks the final box
+// to the maximum aspect ratio.
 func (pi *PixelImage) ExpandOnce(bo *Box) bool {
-	if pi.ExpandRight(bo) {
+	if pi.withinAspect(bo.w+1, bo.h) && pi.ExpandRight(bo) {
+		return true
+	}
+	if pi.withinAspect(bo.w, bo.h+1) && pi.ExpandDown(bo) {
+		return true
+	}
+	if pi.mayGrowTo(bo.w+1, bo.h) && pi.ExpandRight(bo) {
 		return true
 	}
-	return pi.ExpandDown(bo)
+	return pi.mayGrowTo(bo.w, bo.h+1) && pi.ExpandDown(bo)
 }
 
-// Expand tries to expand the box to the right and downwards, until it can't expand any more.
-// Returns true if the box was expanded at least once.
-func (pi *PixelImage) Expand(bo *Box) (expanded bool) {
-	for {
-		if !pi.ExpandOnce(bo) {
-			break
-		}
-		expanded = true
+// shrinkToAspect shrinks the longest side of the box, if the box exceeds the maximum aspect ratio
+func (pi *PixelImage) shrinkToAspect(bo *Box) {
+	if pi.withinAspect(bo.w, bo.h) {
+		return
+	}
+	if bo.w > bo.h {
+		bo.w = int(pi.maxAspect * float64(bo.h))
+	} else {
+		bo.h = int(pi.maxAspect * float64(bo.w))
 	}
-	return
 }
 
-// singleHex returns a single digit hex number, as a string
-// the numbers are not rounded, just floored
-func singleHex(x int) string {
-	hex := strconv.FormatInt(int64(x), 16)
-	if len(hex) == 1 {
-		return "0"
+// Expand tries to expand the box to the right and downwards, until it can't expand any more,
+// and then shrinks it to the maximum aspect ratio. Returns true if the box is larger than 1x1.
+func (pi *PixelImage) Expand(bo *Box) bool {
+	for pi.ExpandOnce(bo) {
 	}
-	return string(hex[0])
+	pi.shrinkToAspect(bo)
+	return bo.w > 1 || bo.h > 1
 }
 
-// shortColorString returns a string representing a color on the short form "#000"
-func shortColorString(r, g, b int) string {
-	return "#" + singleHex(r) + singleHex(g) + singleHex(b)
+// hexDigits are the digits used when formatting a color channel as a single hex digit
+const hexDigits = "0123456789abcdef"
+
+// hexColor returns a string representing a color on the form "#aabbcc".
+// tinysvg.ColorBytes is not used, since it uses the green channel in place
+// of the blue channel when checking if the short form can be used.
+func hexColor(r, g, b int) []byte {
+	return []byte{'#', hexDigits[r>>4], hexDigits[r&0xf], hexDigits[g>>4], hexDigits[g&0xf], hexDigits[b>>4], hexDigits[b&0xf]}
 }
 
 // CoverBox creates rectangles in the SVG image, and also marks the pixels as covered
 // if pink is true, the rectangles will be pink
 // if optimizeColors is true, the color strings will be shortened (and quantized)
 func (pi *PixelImage) CoverBox(bo *Box, pink bool, optimizeColors bool) {
-	// Draw the rectangle
-	rect := pi.svgTag.AddRect(bo.x, bo.y, bo.w, bo.h)
-
 	// Generate a fill color string
-	var colorString string
+	var colorString []byte
 	if pink {
-		if optimizeColors {
-			colorString = "#b38"
-		} else {
-			colorString = "#bb3388"
-		}
-	} else if optimizeColors {
-		colorString = shortColorString(bo.r, bo.g, bo.b)
+		colorString = pi.colorBytes(0xbb, 0x33, 0x88, 0xff, optimizeColors)
 	} else {
-		colorString = string(tinysvg.ColorBytes(bo.r, bo.g, bo.b))
+		colorString = pi.colorBytes(bo.r, bo.g, bo.b, bo.a, optimizeColors)
 	}
 
-	// Set the fill color
-	rect.Fill(colorString)
+	// Draw the rectangle, with the fill color
+	pi.addRect(bo.x, bo.y, bo.w, bo.h, colorString)
 
 	// Mark all covered pixels in the PixelImage
 	for y := bo.y; y < (bo.y + bo.h); y++ {
@@ -199,3 +226,76 @@ func (pi *PixelImage) CoverBox(bo *Box, pink bool, optimizeColors bool) {
 		}
 	}
 }
+
+// CoverBoxes covers all pixels that are not yet covered, by creating boxes
+// at the first uncovered pixel and expanding them to the right and downwards,
+// until they can not expand any more.
+// If pink is true, the expanded boxes (larger than 1x1) will be pink.
+// If an order other than OrderRow is selected, the uncovered pixels are visited in that order.
+// If a SeedFunc is set, the pixels it selects are covered first.
+func (pi *PixelImage) CoverBoxes(pink bool) {
+	if pi.seed != nil {
+		if !pi.coverBoxesWithSeedFunc(pink) && pi.verbose {
+			fmt.Println("The seed function selected an invalid pixel, covering the rest in row order")
+		}
+		// Any remaining pixels are covered in row order, below
+	} else if pi.order == OrderLargest {
+		pi.coverLargestFirst(pink)
+		return
+	} else if pi.order != OrderRow {
+		pi.coverBoxesInOrder(pink)
+		return
+	}
+
+	var (
+		box          *Box
+		x, y         int
+		expanded     bool
+		lastx, lasty int
+		lastLine     int // one message per line / y coordinate
+	)
+
+	if pi.verbose {
+		fmt.Print("Placing rectangles... 0%")
+	}
+
+	percentage := 0
+	lastPercentage := 0
+
+	// Cover pixels by creating expanding rectangles, as long as there are uncovered pixels
+	for !pi.Done(lastx, lasty) && !pi.stopCovering() {
+
+		// Select the first uncovered pixel, searching from the given coordinate
+		x, y = pi.FirstUncovered(lastx, lasty)
+
+		if pi.verbose && y != lastLine {
+			lastPercentage = percentage
+			percentage = int((float64(y) / float64(pi.h)) * 100.0)
+			Erase(len(fmt.Sprintf("%d%%", lastPercentage)))
+			fmt.Printf("%d%%", percentage)
+			lastLine = y
+		}
+
+		// Create a box at that location
+		box = pi.CreateBox(x, y)
+		// Expand the box to the right and downwards, until it can not expand anymore
+		expanded = pi.Expand(box)
+
+		// NOTE: Random boxes gave worse results, even though they are expanding in all directions
+		// Create a random box
+		//box := pi.CreateRandomBox(false)
+		// Expand the box in all directions, until it can not expand anymore
+		//expanded = pi.ExpandRandom(box)
+
+		// Use the expanded box. Color pink if it is > 1x1, and pink is true
+		pi.CoverBox(box, expanded && pink, pi.colorOptimize)
+
+		// All pixels before (x,y) are covered, so the next search can start here
+		lastx, lasty = x, y
+	}
+
+	if pi.verbose {
+		Erase(len(fmt.Sprintf("%d%%", lastPercentage)))
+		fmt.Println("100%")
+	}
+}
@@ -0,0 +1,86 @@
+package png2svg
+
+import (
+	"testing"
+)
+
+func TestWithinAspect(t *testing.T) {
+	pi := &PixelImage{}
+	for _, tc := range []struct {
+		maxAspect float64
+		w, h      int
+		want      bool
+	}{
+		{0, 100, 1, true},
+		{2, 2, 1, true},
+		{2, 3, 1, false},
+		{2, 1, 3, false},
+		{1.5, 3, 2, true},
+		{1, 2, 2, true},
+		{1, 2, 1, false},
+		{1, 1, 1, true},
+		{1.5, 1, 1, true},
+		{1.5, 2, 1, false},
+	} {
+		pi.maxAspect = tc.maxAspect
+		if got := pi.withinAspect(tc.w, tc.h); got != tc.want {
+			t.Errorf("withinAspect(%d, %d) with the maximum %v = %v, expected %v", tc.w, tc.h, tc.maxAspect, got, tc.want)
+		}
+	}
+}
+
+func TestMaxAspect(t *testing.T) {
+	img := newTestImage(testPalette,
+		"rrrrrrrrrrrr",
+		"rrrrrrrrrrrr",
+		"bbbbbbbbbbbb",
+	)
+	for _, order := range []Order{OrderRow, OrderLargest} {
+		for _, tc := range []struct {
+			maxAspect float64
+			rects     int
+		}{
+			{0, 2},
+			// The 2x2 squares can grow through 2x1, even though it exceeds the ratio
+			{1, 6 + 12},
+			{1.5, 4 + 12},
+			{2, 3 + 6},
+			{4, 2 + 3},
+		} {
+			pi := NewPixelImage(img, false)
+			pi.SetOptions(Options{MaxAspect: tc.maxAspect, Order: order})
+			pi.Cover()
+			checkRendersAs(t, pi.Bytes(), img, 0)
+			for _, rect := range pi.rects {
+				if tc.maxAspect > 0 && !pi.withinAspect(rect.Width, rect.Height) {
+					t.Errorf("%v, maximum aspect %v: the %dx%d rectangle at (%d, %d) is too elongated", order, tc.maxAspect, rect.Width, rect.Height, rect.X, rect.Y)
+				}
+			}
+			if len(pi.rects) != tc.rects {
+				t.Errorf("%v, maximum aspect %v: expected %d rectangles, got %d", order, tc.maxAspect, tc.rects, len(pi.rects))
+			}
+		}
+	}
+}
+
+func TestSetMaxAspect(t *testing.T) {
+	pi := NewPixelImage(newTestImage(testPalette, "rr"), false)
+	for _, tc := range []struct {
+		maxAspect float64
+		err       error
+		want      float64
+	}{
+		{2, nil, 2},
+		{0.5, ErrInvalidMaxAspect, 2},
+		{1, nil, 1},
+		{0.99, ErrInvalidMaxAspect, 1},
+		{0, nil, 0},
+	} {
+		if err := pi.SetMaxAspect(tc.maxAspect); err != tc.err {
+			t.Errorf("SetMaxAspect(%v): expected %v, got %v", tc.maxAspect, tc.err, err)
+		}
+		if pi.maxAspect != tc.want {
+			t.Errorf("SetMaxAspect(%v): expected the maximum %v, got %v", tc.maxAspect, tc.want, pi.maxAspect)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package png2svg
+
+import (
+	"bytes"
+)
+
+// clipRegion is the opaque region of the image, expressed as rectangles within
+// a <clipPath>, together with the fill color of the background rectangle that
+// is clipped to this region
+type clipRegion struct {
+	rects bytes.Buffer
+	count int
+	fill  []byte
+}
+
+// SetClip can be used for covering the opaque region of the image with a single
+// background rectangle in the most used opaque color, clipped to the opaque region
+// with a <clipPath>. The pixels with the most used color are then left out of the
+// regular rectangles. This is useful for images with large flat opaque areas and
+// complex transparent cutouts. Must be called before the pixels are covered.
+func (pi *PixelImage) SetClip(enabled bool) {
+	pi.clip = enabled
+}
+
+// opaque returns true if the pixel at the given index is fully opaque
+func (pi *PixelImage) opaque(i int) bool {
+	return pi.pixels[i].a == 255
+}
+
+// coverClip finds the opaque region and the most used opaque color, and marks
+// all pixels with that color as covered, since they are covered by the clipped
+// background rectangle. Semi-transparent pixels are left out of the opaque region,
+// since they would otherwise be blended with the background rectangle.
+func (pi *PixelImage) coverClip() {
+	pi.clipRegion = nil
+
+	dominant, ok := pi.dominantOpaqueColor()
+	if !ok {
+		// No opaque pixels
+		return
+	}
+
+	region := &clipRegion{fill: shortestColor(pi.colorBytes(dominant[0], dominant[1], dominant[2], 255, pi.colorOptimize), pi.colorOptimize)}
+
+	// Cover the opaque region with rectangles. If the image is symmetric, only the
+	// first half is clipped, since the background is mirrored along with the rest.
+	opaque := make([]bool, len(pi.pixels))
+	for i := range pi.pixels {
+		opaque[i] = pi.opaque(i) && !pi.mirroredHalf(i)
+	}
+	coverMask(opaque, pi.w, pi.h, func(x, y, w, h int) {
+		pi.writeRect(&region.rects, x, y, w, h)
+		region.count++
+	})
+
+	pi.coverOpaqueColor(dominant)
+	pi.clipRegion = region
+}
+
+// dominantOpaqueColor returns the most used fully opaque color, or false if there are no opaque pixels.
+// If several colors are used equally often, the lowest color is returned.
+func (pi *PixelImage) dominantOpaqueColor() (rgb, bool) {
+	counts := make(map[rgb]int)
+	for i, p := range pi.pixels {
+		if pi.opaque(i) {
+			counts[rgb{p.r, p.g, p.b}]++
+		}
+	}
+	var (
+		dominant rgb
+		most     int
+	)
+	for c, count := range counts {
+		if count > most || (count == most && (c[0] < dominant[0] || (c[0] == dominant[0] && (c[1] < dominant[1] || (c[1] == dominant[1] && c[2] < dominant[2]))))) {
+			dominant, most = c, count
+		}
+	}
+	return dominant, most > 0
+}
+
+// coverOpaqueColor marks the fully opaque pixels with the given color as covered,
+// since they are covered by a background shape
+func (pi *PixelImage) coverOpaqueColor(c rgb) {
+	for i, p := range pi.pixels {
+		if pi.opaque(i) && p.r == c[0] && p.g == c[1] && p.b == c[2] {
+			p.covered = true
+		}
+	}
+}
+
+// writeClip writes the <clipPath> for the opaque region, and the background
+// rectangle that is clipped to it, if SetClip has been used
+func (pi *PixelImage) writeClip(buf *bytes.Buffer) {
+	if pi.clipRegion == nil {
+		return
+	}
+	id := pi.classPrefix + "opaque"
+	buf.WriteString("<defs><clipPath id=\"")
+	buf.WriteString(id)
+	buf.WriteString("\">")
+	buf.Write(pi.clipRegion.rects.Bytes())
+	buf.WriteString("</clipPath></defs>")
+	pi.writeRect(buf, 0, 0, pi.w, pi.h)
+	// Insert the fill and clip-path attributes before the closing "/>"
+	buf.Truncate(buf.Len() - 2)
+	buf.WriteString(" fill=\"")
+	buf.Write(pi.clipRegion.fill)
+	buf.WriteString("\" clip-path=\"url(#")
+	buf.WriteString(id)
+	buf.WriteString(")\"/>")
+}
+
+// coverMask covers all true values in the given mask with rectangles, that are
+// expanded to the right and then downwards. The rectangles are passed to the
+// given function.
+func coverMask(mask []bool, width, height int, rect func(x, y, w, h int)) {
+	visited := make([]bool, len(mask))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			i := y*width + x
+			if visited[i] || !mask[i] {
+				continue
+			}
+			w := 1
+			for x+w < width && !visited[i+w] && mask[i+w] {
+				w++
+			}
+			h := 1
+		expandDown:
+			for y+h < height {
+				for j := (y+h)*width + x; j < (y+h)*width+x+w; j++ {
+					if visited[j] || !mask[j] {
+						break expandDown
+					}
+				}
+				h++
+			}
+			for cy := y; cy < y+h; cy++ {
+				for cx := x; cx < x+w; cx++ {
+					visited[cy*width+cx] = true
+				}
+			}
+			rect(x, y, w, h)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package png2svg
+
+import (
+	"testing"
+)
+
+func TestClip(t *testing.T) {
+	img := newTestImage(testPalette,
+		"rrrrrrrr",
+		"r..rr.br",
+		"rrrrrrrr",
+		"rr.hhrrr",
+		"rrrrbbr.",
+	)
+	plain := NewPixelImage(img, false)
+	plain.SetColorFormat(ColorRGBA)
+	plain.Cover()
+
+	pi := NewPixelImage(img, false)
+	pi.SetOptions(Options{Clip: true, ColorFormat: ColorRGBA})
+	pi.Cover()
+	svgDocument := pi.Bytes()
+	svgContains(t, svgDocument, `clip-path="url(#opaque)"`)
+	r := checkRendersAs(t, svgDocument, img, 1)
+	for y := 0; y < r.h; y++ {
+		for x := 0; x < r.w; x++ {
+			if img.NRGBAAt(x, y).A == 0 && r.Painted(x, y) > 0 {
+				t.Errorf("the transparent pixel (%d, %d) is painted", x, y)
+			}
+			if img.NRGBAAt(x, y).A == 128 && r.Painted(x, y) != 1 {
+				t.Errorf("the half transparent pixel (%d, %d) is painted %d times", x, y, r.Painted(x, y))
+			}
+		}
+	}
+	// Only the blue and the half transparent pixels need regular rectangles
+	if len(pi.rects) >= len(plain.rects) || len(pi.rects) != 3 {
+		t.Errorf("expected 3 regular rectangles, got %d (%d without -clip)", len(pi.rects), len(plain.rects))
+	}
+
+	// Images without opaque pixels are converted as usual
+	transparent := newTestImage(testPalette, "h.", ".h")
+	svgDocument = convertBytes(t, transparent, Options{Clip: true, ColorFormat: ColorRGBA})
+	checkRendersAs(t, svgDocument, transparent, 1)
+}
+
+func TestCoverMask(t *testing.T) {
+	rows := []string{
+		"xx..x",
+		"xxx.x",
+		".xxxx",
+		"x...x",
+	}
+	width, height := len(rows[0]), len(rows)
+	mask := make([]bool, width*height)
+	for y, row := range rows {
+		for x := range row {
+			mask[y*width+x] = row[x] == 'x'
+		}
+	}
+	covered := make([]int, len(mask))
+	coverMask(mask, width, height, func(x, y, w, h int) {
+		for cy := y; cy < y+h; cy++ {
+			for cx := x; cx < x+w; cx++ {
+				covered[cy*width+cx]++
+			}
+		}
+	})
+	for i, m := range mask {
+		if want := map[bool]int{true: 1, false: 0}[m]; covered[i] != want {
+			t.Errorf("pixel (%d, %d) is covered %d times, expected %d", i%width, i/width, covered[i], want)
+		}
+	}
+}
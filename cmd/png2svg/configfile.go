@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"sort"
+)
+
+// applyConfigFile reads a JSON file where the keys are flag names, like
+// {"l": true, "qround": "round", "maxaspect": 4}, and sets the flags that were
+// not given on the command line. Flags given on the command line take precedence
+// over the values in the file. Unknown keys are reported as errors.
+func applyConfigFile(filename string) error {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	var values map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&values); err != nil {
+		return fmt.Errorf("could not parse %s: %v", filename, err)
+	}
+
+	// Find the flags that were given on the command line
+	given := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		given[f.Name] = true
+	})
+
+	// Apply the values in sorted order, so that errors are reported consistently
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if name == "config" || flag.Lookup(name) == nil {
+			return fmt.Errorf("unknown option in %s: %s", filename, name)
+		}
+		if given[name] {
+			continue
+		}
+		var value string
+		switch v := values[name].(type) {
+		case string:
+			value = v
+		case bool, json.Number:
+			value = fmt.Sprint(v)
+		default:
+			return fmt.Errorf("invalid value for %s in %s, use a string, number or boolean", name, filename)
+		}
+		if err := flag.Set(name, value); err != nil {
+			return fmt.Errorf("invalid value for %s in %s: %v", name, filename, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xyproto/png2svg"
+)
+
+// parseArgs parses the given command line arguments with NewConfigFromFlags
+func parseArgs(t *testing.T, args ...string) (*Config, error) {
+	t.Helper()
+	oldArgs, oldCommandLine := os.Args, flag.CommandLine
+	defer func() {
+		os.Args, flag.CommandLine = oldArgs, oldCommandLine
+	}()
+	os.Args = append([]string{"png2svg"}, args...)
+	flag.CommandLine = flag.NewFlagSet("png2svg", flag.ContinueOnError)
+	c, _, err := NewConfigFromFlags()
+	return c, err
+}
+
+// writeConfigFile writes the given JSON to a temporary config file, and returns the filename
+func writeConfigFile(t *testing.T, dir, contents string) string {
+	t.Helper()
+	filename := filepath.Join(dir, "png2svg.json")
+	if err := ioutil.WriteFile(filename, []byte(contents), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return filename
+}
+
+func TestConfigFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "png2svg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	configFilename := writeConfigFile(t, dir, `{"l": true, "qround": "round", "maxaspect": 4, "o": "out.svg"}`)
+
+	c, err := parseArgs(t, "-config", configFilename, "input.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.limit || c.quantizeRounding != png2svg.RoundNearest || c.maxAspect != 4 || c.outputFilename != "out.svg" {
+		t.Errorf("expected the values from the config file, got limit=%v rounding=%v maxaspect=%v o=%s", c.limit, c.quantizeRounding, c.maxAspect, c.outputFilename)
+	}
+
+	// Flags on the command line take precedence, also when given after -config
+	c, err = parseArgs(t, "-qround", "ceil", "-config", configFilename, "-maxaspect", "2", "input.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !c.limit || c.quantizeRounding != png2svg.RoundCeil || c.maxAspect != 2 {
+		t.Errorf("expected the flags to override the config file, got limit=%v rounding=%v maxaspect=%v", c.limit, c.quantizeRounding, c.maxAspect)
+	}
+
+	for _, contents := range []string{
+		`{"unknown": true}`,
+		`{"config": "other.json"}`,
+		`{"maxaspect": "wide"}`,
+		`{"l": [true]}`,
+		`not json`,
+	} {
+		if _, err := parseArgs(t, "-config", writeConfigFile(t, dir, contents), "input.png"); err == nil {
+			t.Errorf("expected an error for the config file %s", contents)
+		}
+	}
+}
@@ -1,11 +1,18 @@
 package main
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"errors"
 	"flag"
 	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"io/ioutil"
 	"math/rand"
 	"os"
+	"sort"
 	"strings"
 	"time"
 
@@ -19,20 +26,178 @@ func init() {
 
 // Config contains the results of parsing the flags and arguments
 type Config struct {
+	animate               bool
+	fps                   float64
+	frameFilenames        []string
+	background            *color.NRGBA
+	pngBackground         bool
+	benchmarkIterations   int
+	chromaKey             *color.NRGBA
+	chromaTolerance       int
 	inputFilename         string
+	order                 png2svg.Order
 	outputFilename        string
+	paths                 bool
+	pretty                bool
+	bom                   bool
+	clip                  bool
+	evenOdd               bool
+	hybrid                png2svg.Hybrid
 	colorOptimize         bool
+	colorFormat           png2svg.ColorFormat
 	colorPink             bool
+	components            bool
+	fallback              int
+	deadline              time.Duration
+	separations           bool
+	spotColors            map[string]string
+	alphaLevels           int
+	gridCSS               bool
+	views                 viewFlags
+	gradients             bool
+	gradientTolerance     int
+	profile               bool
+	dedup                 bool
+	goFilename            string
+	swatchFilename        string
+	goPackage             string
+	goVariable            string
+	interlace             bool
+	recolor               map[color.NRGBA]color.NRGBA
+	snapExtremes          int
+	outline               *color.NRGBA
+	outlineWidth          float64
+	crlf                  bool
+	currentColor          *color.NRGBA
+	dataURI               bool
+	downsample            int
+	fillMode              png2svg.FillMode
+	groupByRow            bool
+	gamma                 float64
+	incremental           bool
+	jsonFilename          string
 	limit                 bool
+	maxAspect             float64
+	maxRects              int
+	maxRectsPerGroup      int
+	mergeLeast            int
+	mono                  png2svg.Dither
+	noTimestamp           bool
+	provenance            bool
+	pyramidLevels         int
 	quantize              bool
+	quantizeRounding      png2svg.Rounding
+	referenceFilename     string
+	removeRedundant       bool
+	referenceTolerance    int
+	rootClass             string
+	rootID                string
+	rowTransform          string
+	tileSize              int
+	bandHeight            int
 	singlePixelRectangles bool
+	symmetry              bool
+	tooltips              bool
 	verbose               bool
 	version               bool
 }
 
+// Options returns the png2svg.Options that corresponds to this configuration
+func (c *Config) Options() png2svg.Options {
+	return png2svg.Options{
+		ColorOptimize:         c.limit,
+		Rounding:              c.quantizeRounding,
+		ColorFormat:           c.colorFormat,
+		Tooltips:              c.tooltips,
+		MaxRectsPerGroup:      c.maxRectsPerGroup,
+		MaxRects:              c.maxRects,
+		RemoveRedundant:       c.removeRedundant,
+		Mono:                  c.mono,
+		GroupByRow:            c.groupByRow,
+		Paths:                 c.paths,
+		Symmetry:              c.symmetry,
+		Components:            c.components,
+		Fallback:              c.fallback,
+		Deadline:              c.deadline,
+		Separations:           c.separations,
+		SpotColors:            c.spotColors,
+		AlphaLevels:           c.alphaLevels,
+		GridCSS:               c.gridCSS,
+		Views:                 c.views,
+		Gradients:             c.gradients,
+		GradientTolerance:     c.gradientTolerance,
+		Dedup:                 c.dedup,
+		Interlace:             c.interlace,
+		Recolor:               c.recolor,
+		SnapExtremes:          c.snapExtremes,
+		Outline:               c.outline,
+		OutlineWidth:          c.outlineWidth,
+		Provenance:            c.provenance,
+		Timestamp:             !c.noTimestamp,
+		CRLF:                  c.crlf,
+		BOM:                   c.bom,
+		RowTransform:          c.rowTransform,
+		SinglePixelRectangles: c.singlePixelRectangles,
+		Pink:                  c.colorPink,
+		ChromaKey:             c.chromaKey,
+		ChromaTolerance:       c.chromaTolerance,
+		MaxAspect:             c.maxAspect,
+		Order:                 c.order,
+		MergeLeast:            c.mergeLeast,
+		FillMode:              c.fillMode,
+		Background:            c.background,
+		Clip:                  c.clip,
+		EvenOdd:               c.evenOdd,
+		Hybrid:                c.hybrid,
+		CurrentColor:          c.currentColor,
+		RootID:                c.rootID,
+		RootClass:             c.rootClass,
+		Gamma:                 c.gamma,
+	}
+}
+
+// viewFlags is a list of views that can be given several times on the command line
+type viewFlags []png2svg.View
+
+// String returns the views, separated by spaces
+func (vf *viewFlags) String() string {
+	views := make([]string, len(*vf))
+	for i, v := range *vf {
+		views[i] = v.String()
+	}
+	return strings.Join(views, " ")
+}
+
+// Set parses and adds a view on the form name:x,y,w,h
+func (vf *viewFlags) Set(s string) error {
+	v, err := png2svg.ParseView(s)
+	if err != nil {
+		return err
+	}
+	*vf = append(*vf, v)
+	return nil
+}
+
 // NewConfigFromFlags returns a Config struct, a quit message (for -v) and/or an error
 func NewConfigFromFlags() (*Config, string, error) {
-	var c Config
+	var (
+		c           Config
+		qround      string
+		colorFormat string
+		chroma      string
+		bg          string
+		current     string
+		order       string
+		mono        string
+		hybrid      string
+		fillAs      string
+		classFill   bool
+		configFile  string
+		spotColors  string
+		recolor     string
+		outline     string
+		parseErr    error
+	)
 
 	flag.StringVar(&c.outputFilename, "o", "-", "SVG output filename")
 	flag.BoolVar(&c.singlePixelRectangles, "p", false, "use only single pixel rectangles")
@@ -42,39 +207,266 @@ func NewConfigFromFlags() (*Config, string, error) {
 	flag.BoolVar(&c.limit, "l", false, "limit colors to a maximum of 4096 (#abcdef -> #ace)")
 	flag.BoolVar(&c.quantize, "q", false, "deprecated (same as -l)")
 	flag.BoolVar(&c.colorOptimize, "z", false, "deprecated (same as -l)")
+	flag.IntVar(&c.benchmarkIterations, "bench", 0, "convert the image N times without writing any output, and report the timing")
+	flag.BoolVar(&c.dataURI, "datauri", false, "output the SVG image as a URL-encoded data URI, for use in CSS")
+	flag.IntVar(&c.downsample, "downsample", 1, "only use every Nth pixel in each direction, for creating smaller previews")
+	flag.BoolVar(&c.animate, "animate", false, "convert all the given PNG images to one animated SVG image, with one frame per image")
+	flag.Float64Var(&c.fps, "fps", 10, "frames per second, for -animate")
+	flag.BoolVar(&c.incremental, "incremental", false, "skip the conversion if the output file is newer than the input file and was created with the same flags")
+	flag.StringVar(&c.jsonFilename, "json", "", "also write the rectangles as JSON to this file, for other renderers")
+	flag.BoolVar(&c.pretty, "pretty", false, "write one tag per line, indented with two spaces")
+	flag.BoolVar(&c.crlf, "crlf", false, "use CRLF line endings for -pretty, for Windows tools")
+	flag.BoolVar(&c.bom, "bom", false, "write a UTF-8 byte order mark before the XML declaration, for Windows tools")
+	flag.IntVar(&c.pyramidLevels, "pyramid", 0, "write a pyramid of SVG tiles with this many zoom levels to the -o directory, for deep zoom viewers")
+	flag.IntVar(&c.tileSize, "tilesize", 256, "width and height of each tile, for -pyramid")
+	flag.IntVar(&c.bandHeight, "band", 0, "convert the image in horizontal bands of this height, for using less memory on very tall images")
+	flag.BoolVar(&c.provenance, "provenance", false, "add a <metadata> tag with the version, options and time of the conversion")
+	flag.BoolVar(&c.noTimestamp, "no-timestamp", false, "leave the time out of the <metadata> tag, for reproducible output")
+	flag.BoolVar(&c.tooltips, "tooltips", false, "add a <title> with the hex color to each color group")
+	flag.Float64Var(&c.maxAspect, "maxaspect", 0, "maximum aspect ratio of the rectangles, at least 1 (0 is unlimited)")
+	flag.IntVar(&c.maxRects, "maxrects", 0, "embed the image as a PNG instead, if more than N rectangles are needed (0 is unlimited)")
+	flag.BoolVar(&c.removeRedundant, "remove-redundant", false, "remove rectangles that are fully covered by other rectangles with the same color")
+	flag.IntVar(&c.maxRectsPerGroup, "max-rects-per-group", 0, "split color groups with more rectangles than this (0 is unlimited)")
+	flag.StringVar(&c.referenceFilename, "ref", "", "mark the pixels that differ from this reference PNG image with red rectangles")
+	flag.IntVar(&c.referenceTolerance, "ref-tol", 0, "largest difference per color channel for -ref")
+	flag.Float64Var(&c.gamma, "gamma", 0, "convert the pixels from this gamma to sRGB, like 1 for linear images (0 assumes sRGB)")
+	flag.StringVar(&bg, "bg", "", "place the image on top of this background color, like \"#ffffff\"")
+	flag.BoolVar(&c.pngBackground, "bkgd", false, "use the background color from the PNG image (bKGD chunk), if there is one")
+	flag.BoolVar(&c.clip, "clip", false, "cover the opaque region with one rectangle in the most used color, clipped with a <clipPath>")
+	flag.BoolVar(&c.evenOdd, "evenodd", false, "cover the opaque region with one path in the most used color, with the holes cut out with the even-odd fill rule")
+	flag.StringVar(&chroma, "chroma", "", "treat this color as transparent, like \"#ff00ff\"")
+	flag.IntVar(&c.chromaTolerance, "chroma-tolerance", 0, "largest difference per color channel for -chroma")
+	flag.IntVar(&c.mergeLeast, "mergeleast", 0, "keep only the N most used colors, by changing the other colors to the nearest kept color")
+	flag.BoolVar(&c.paths, "paths", false, "write the rectangles of each color as one <path>, with relative coordinates where shorter")
+	flag.StringVar(&c.swatchFilename, "swatch", "", "also write a PNG image with one labeled swatch per color, most used first, to this file")
+	flag.StringVar(&c.goFilename, "go", "", "also write the SVG image as a string variable in this Go source file")
+	flag.StringVar(&c.goPackage, "go-package", "main", "package name for the -go source file")
+	flag.StringVar(&c.goVariable, "go-var", "svgImage", "variable name for the -go source file")
+	flag.StringVar(&outline, "outline", "", "draw the outline of each rectangle with this color, like \"#ff00ff\", for debugging")
+	flag.Float64Var(&c.outlineWidth, "outline-width", 0.1, "stroke width of the outlines, for -outline")
+	flag.IntVar(&c.snapExtremes, "snap-extremes", 0, "snap colors within this difference per color channel of black or white to pure black or white")
+	flag.StringVar(&recolor, "recolor", "", "text file with one old and one new color per line, like \"#ff0000 #0000ff\", for replacing colors")
+	flag.BoolVar(&c.interlace, "interlace", false, "order the rectangles from coarse to fine, for a preview while large images are loading")
+	flag.BoolVar(&c.dedup, "dedup", false, "define rectangles with the same size and color once, and place them with <use>")
+	flag.BoolVar(&c.profile, "profile", false, "report the time and memory used by each phase of the conversion to stderr")
+	flag.BoolVar(&c.gradients, "gradients", false, "cover linear gradients with rectangles that are filled with a <linearGradient>")
+	flag.IntVar(&c.gradientTolerance, "gradient-tolerance", 2, "largest difference per color channel between a pixel and a gradient, for -gradients")
+	flag.Var(&c.views, "views", "add a named region that can be shown with an URL fragment, like home:0,0,16,16 (can be given several times)")
+	flag.BoolVar(&c.gridCSS, "grid-css", false, "set the size of 1x1 rectangles with CSS instead of attributes, for smaller output with -p")
+	flag.IntVar(&c.alphaLevels, "qalpha", 0, "snap the alpha of each pixel to this many evenly spaced levels, like 4 (useful with -colorformat rgba)")
+	flag.BoolVar(&c.separations, "separations", false, "write each color as a separate layer, from the lightest to the darkest, for print separations")
+	flag.StringVar(&spotColors, "spot-colors", "", "JSON file with spot color names for the separations, like {\"#e03c31\": \"PANTONE 485 C\"}")
+	flag.DurationVar(&c.deadline, "deadline", 0, "place expanding rectangles for at most this long (like 2s), then cover the rest quickly")
+	flag.IntVar(&c.fallback, "fallback", 0, "include a raster image of at most this width and height, for renderers that can not show the vector content")
+	flag.BoolVar(&c.components, "components", false, "group the rectangles by connected shapes of the same color, with an id per shape")
+	flag.BoolVar(&c.symmetry, "symmetry", false, "only convert one half of symmetric images, and mirror it with <use>")
+	flag.BoolVar(&c.groupByRow, "rows", false, "group the rectangles by row, in <g data-row=\"y\"> tags, for animations")
+	flag.StringVar(&c.rowTransform, "row-transform", "", "initial transform of each row, for -rows, like \"translate(-100,0)\"")
+	flag.StringVar(&c.rootID, "root-id", "", "id attribute for the root <svg> tag")
+	flag.StringVar(&c.rootClass, "root-class", "", "class attribute for the root <svg> tag")
+	flag.StringVar(&current, "current", "", "write this color as fill=\"currentColor\", like \"#000000\", for icons that use the text color")
+	flag.StringVar(&colorFormat, "colorformat", "hex", "color format for the fill colors: hex, rgb or rgba")
+	flag.StringVar(&fillAs, "fill-as", "attr", "write fill colors as attributes, styles or CSS classes: attr, style, class, class-and-fill or palette")
+	flag.BoolVar(&classFill, "class-and-fill", false, "same as -fill-as class-and-fill, for CSS classes that can be overridden by themes")
+	flag.StringVar(&hybrid, "hybrid", "off", "cover the semi-transparent edges with: off, pixels (1x1 rectangles) or runs (horizontal runs), and the rest with expanding rectangles")
+	flag.StringVar(&mono, "mono", "off", "convert to black rectangles only, for 1-bit targets, with this dithering: off, threshold, ordered or floyd")
+	flag.StringVar(&order, "order", "row", "order for placing rectangles: row (the fewest rectangles for most images), column, hilbert or largest (falls back to row if that is better)")
+	flag.StringVar(&qround, "qround", "floor", "rounding when limiting colors: floor, round or ceil")
+
+	flag.StringVar(&configFile, "config", "", "read options from a JSON file where the keys are flag names (flags on the command line take precedence)")
 
 	flag.Parse()
 
+	if configFile != "" {
+		if err := applyConfigFile(configFile); err != nil {
+			return nil, "", err
+		}
+	}
+
 	if c.version {
 		return nil, png2svg.VersionString, nil
 	}
 
 	c.limit = c.limit || c.quantize || c.colorOptimize
 
+	c.quantizeRounding, parseErr = png2svg.NewRounding(qround)
+	if parseErr != nil {
+		return nil, "", parseErr
+	}
+
+	c.colorFormat, parseErr = png2svg.NewColorFormat(colorFormat)
+	if parseErr != nil {
+		return nil, "", parseErr
+	}
+
+	if classFill {
+		fillAs = "class-and-fill"
+	}
+	c.fillMode, parseErr = png2svg.NewFillMode(fillAs)
+	if parseErr != nil {
+		return nil, "", parseErr
+	}
+
+	c.mono, parseErr = png2svg.NewDither(mono)
+	if parseErr != nil {
+		return nil, "", parseErr
+	}
+
+	c.hybrid, parseErr = png2svg.NewHybrid(hybrid)
+	if parseErr != nil {
+		return nil, "", parseErr
+	}
+
+	c.order, parseErr = png2svg.NewOrder(order)
+	if parseErr != nil {
+		return nil, "", parseErr
+	}
+
+	if c.maxAspect > 0 && c.maxAspect < 1 {
+		return nil, "", png2svg.ErrInvalidMaxAspect
+	}
+
+	if bg != "" {
+		background, err := png2svg.ParseHexColor(bg)
+		if err != nil {
+			return nil, "", err
+		}
+		c.background = &background
+	}
+
+	if current != "" {
+		currentColor, err := png2svg.ParseHexColor(current)
+		if err != nil {
+			return nil, "", err
+		}
+		c.currentColor = &currentColor
+	}
+
+	if chroma != "" {
+		chromaKey, err := png2svg.ParseHexColor(chroma)
+		if err != nil {
+			return nil, "", err
+		}
+		c.chromaKey = &chromaKey
+	}
+
+	if outline != "" {
+		outlineColor, err := png2svg.ParseHexColor(outline)
+		if err != nil {
+			return nil, "", err
+		}
+		c.outline = &outlineColor
+	}
+
+	if recolor != "" {
+		var err error
+		if c.recolor, err = png2svg.ReadRecolorFile(recolor); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if spotColors != "" {
+		var err error
+		if c.spotColors, err = png2svg.ReadSpotColors(spotColors); err != nil {
+			return nil, "", err
+		}
+	}
+
 	if c.colorPink {
 		c.singlePixelRectangles = false
 	}
 
 	args := flag.Args()
 	if len(args) == 0 {
-		return nil, "", errors.New("an input PNG filename is required")
+		return nil, "", errors.New("an input PNG filename or URL is required")
 
 	}
 	c.inputFilename = args[0]
+	if c.animate {
+		c.frameFilenames = args
+	}
 	return &c, "", nil
 }
 
+// optionsKey returns the effective options, together with the flags that only change
+// how the output is written and the files that are also written, for detecting changed
+// flags with -incremental. The color replacements and spot colors that are read from
+// files are part of the options, so changing the contents of those files is also detected.
+func (c *Config) optionsKey() string {
+	return fmt.Sprintf("%s bkgd=%t downsample=%d pretty=%t datauri=%t provenance=%t no-timestamp=%t animate=%t fps=%g pyramid=%d tilesize=%d band=%d ref=%s ref-tol=%d json=%q swatch=%q go=%q go-package=%q go-var=%q",
+		c.Options(), c.pngBackground, c.downsample, c.pretty, c.dataURI, c.provenance, c.noTimestamp,
+		c.animate, c.fps, c.pyramidLevels, c.tileSize, c.bandHeight, c.referenceFilename, c.referenceTolerance,
+		c.jsonFilename, c.swatchFilename, c.goFilename, c.goPackage, c.goVariable)
+}
+
+// inputFilenames returns the names of the files that are read, either the frames
+// for -animate or the input file
+func (c *Config) inputFilenames() []string {
+	if c.animate {
+		return c.frameFilenames
+	}
+	return []string{c.inputFilename}
+}
+
+// optionsHashFilename returns the name of the file where the hash of the options
+// that were used for creating the given output file is stored, for -incremental
+func optionsHashFilename(outputFilename string) string {
+	return outputFilename + ".options"
+}
+
+// optionsHash returns the SHA-256 hash of the given options key, as a hex string
+func optionsHash(key string) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(key)))
+}
+
+// writeOptionsHash stores the hash of the given options key next to the output file,
+// so that upToDate can detect if the flags are changed
+func writeOptionsHash(outputFilename, key string) error {
+	return ioutil.WriteFile(optionsHashFilename(outputFilename), []byte(optionsHash(key)+"\n"), 0644)
+}
+
+// upToDate returns true if the output file exists, is newer than all of the input files
+// (more than one for -animate) and was created with the same options, as stored by writeOptionsHash.
+func upToDate(inputFilenames []string, outputFilename, key string) bool {
+	if outputFilename == "-" {
+		return false
+	}
+	outputInfo, err := os.Stat(outputFilename)
+	if err != nil {
+		return false
+	}
+	for _, inputFilename := range inputFilenames {
+		inputInfo, err := os.Stat(inputFilename)
+		if err != nil || !outputInfo.ModTime().After(inputInfo.ModTime()) {
+			return false
+		}
+	}
+	data, err := ioutil.ReadFile(optionsHashFilename(outputFilename))
+	if err != nil {
+		return false
+	}
+	return strings.TrimSpace(string(data)) == optionsHash(key)
+}
+
+// benchmark converts the given image n times, without writing any output,
+// and reports the minimum, median and maximum time per conversion to w.
+// This is useful for profiling.
+func benchmark(w io.Writer, img image.Image, opts png2svg.Options, n int) {
+	durations := make([]time.Duration, n)
+	for i := range durations {
+		start := time.Now()
+		pi := png2svg.NewPixelImage(img, false)
+		pi.SetOptions(opts)
+		pi.Cover()
+		pi.Bytes()
+		durations[i] = time.Since(start)
+	}
+	sort.Slice(durations, func(i, j int) bool { return durations[i] < durations[j] })
+	fmt.Fprintf(w, "%d conversions: min %v, median %v, max %v\n", n, durations[0], durations[n/2], durations[n-1])
+}
+
 // Run performs the user-selected operations
 func Run() error {
-	var (
-		box          *png2svg.Box
-		x, y         int
-		expanded     bool
-		lastx, lasty int
-		lastLine     int // one message per line / y coordinate
-		done         bool
-	)
-
 	c, quitMessage, err := NewConfigFromFlags()
 	if err != nil {
 		return err
@@ -83,67 +475,209 @@ func Run() error {
 		return nil
 	}
 
-	img, err := png2svg.ReadPNG(c.inputFilename, c.verbose)
+	if !c.incremental {
+		return convert(c)
+	}
+	key := c.optionsKey()
+	if upToDate(c.inputFilenames(), c.outputFilename, key) {
+		if c.verbose {
+			fmt.Printf("Skipping %s, %s is up to date\n", c.inputFilename, c.outputFilename)
+		}
+		return nil
+	}
+	if err := convert(c); err != nil {
+		return err
+	}
+	if c.outputFilename == "-" {
+		return nil
+	}
+	return writeOptionsHash(c.outputFilename, key)
+}
+
+// convert performs the conversion, and writes the output
+func convert(c *Config) error {
+	if c.animate {
+		frames := make([]image.Image, len(c.frameFilenames))
+		for i, filename := range c.frameFilenames {
+			frame, _, err := readImage(filename, c.verbose)
+			if err != nil {
+				return err
+			}
+			frames[i] = png2svg.Downsample(frame, c.downsample)
+		}
+		return png2svg.WriteAnimatedSVG(c.outputFilename, frames, c.Options(), c.fps)
+	}
+
+	var prof *profiler
+	if c.profile {
+		prof = newProfiler()
+	}
+
+	// The input filename can also be an http:// or https:// URL
+	img, data, err := readImage(c.inputFilename, c.verbose)
 	if err != nil {
 		return err
 	}
 
-	height := img.Bounds().Max.Y - img.Bounds().Min.Y
+	if c.pngBackground {
+		// Use the background color from the PNG image, if there is one
+		var background *color.NRGBA
+		if data != nil {
+			background, err = png2svg.PNGBackground(bytes.NewReader(data))
+		} else {
+			background, err = png2svg.ReadPNGBackground(c.inputFilename)
+		}
+		if err != nil {
+			return err
+		}
+		if background != nil {
+			c.background = background
+		} else if c.verbose {
+			fmt.Println("No background color in " + c.inputFilename)
+		}
+	}
 
-	pi := png2svg.NewPixelImage(img, c.verbose)
-	pi.SetColorOptimize(c.limit)
+	if c.downsample > 1 {
+		img = png2svg.Downsample(img, c.downsample)
+	}
 
-	if c.verbose {
-		fmt.Print("Placing rectangles... 0%")
+	prof.phase("decode")
+
+	if c.benchmarkIterations > 0 {
+		benchmark(os.Stdout, img, c.Options(), c.benchmarkIterations)
+		return nil
 	}
 
-	percentage := 0
-	lastPercentage := 0
+	if c.pyramidLevels > 0 {
+		if c.outputFilename == "-" {
+			return errors.New("-pyramid needs an output directory, given with -o")
+		}
+		if c.verbose {
+			fmt.Printf("Writing a pyramid with %d levels to %s\n", c.pyramidLevels, c.outputFilename)
+		}
+		return png2svg.WritePyramid(img, c.Options(), c.outputFilename, c.pyramidLevels, c.tileSize)
+	}
 
-	// Cover pixels by creating expanding rectangles, as long as there are uncovered pixels
-	for !c.singlePixelRectangles && !done {
+	if c.bandHeight > 0 {
+		if c.verbose {
+			fmt.Printf("Converting the image in bands of %d rows\n", c.bandHeight)
+		}
+		err := png2svg.WriteBandedSVG(c.outputFilename, img, c.Options(), c.bandHeight)
+		if err == png2svg.ErrTooManyRects {
+			// Fall back to an embedded PNG, like for images that are not converted in bands
+			if c.verbose {
+				fmt.Printf("More than %d rectangles are needed for a band, embedding the image as a PNG instead.\n", c.maxRects)
+			}
+			return c.writeEmbeddedPNG(img)
+		}
+		return err
+	}
 
-		// Select the first uncovered pixel, searching from the given coordinate
-		x, y = pi.FirstUncovered(lastx, lasty)
+	pi := png2svg.NewPixelImage(img, c.verbose)
+	pi.SetOptions(c.Options())
 
-		if c.verbose && y != lastLine {
-			lastPercentage = percentage
-			percentage = int((float64(y) / float64(height)) * 100.0)
-			png2svg.Erase(len(fmt.Sprintf("%d%%", lastPercentage)))
-			fmt.Printf("%d%%", percentage)
-			lastLine = y
+	if c.referenceFilename != "" {
+		ref, err := c.readReference()
+		if err != nil {
+			return err
 		}
+		count := pi.SetReference(ref, c.referenceTolerance)
+		if c.verbose {
+			fmt.Printf("%d pixels differ from %s\n", count, c.referenceFilename)
+		}
+	}
 
-		// Create a box at that location
-		box = pi.CreateBox(x, y)
-		// Expand the box to the right and downwards, until it can not expand anymore
-		expanded = pi.Expand(box)
+	prof.phase("pixels")
 
-		// NOTE: Random boxes gave worse results, even though they are expanding in all directions
-		// Create a random box
-		//box := pi.CreateRandomBox(false)
-		// Expand the box in all directions, until it can not expand anymore
-		//expanded = pi.ExpandRandom(box)
+	// Cover all pixels with rectangles
+	pi.Cover()
 
-		// Use the expanded box. Color pink if it is > 1x1, and colorPink is true
-		pi.CoverBox(box, expanded && c.colorPink, c.limit)
+	prof.phase("cover")
 
-		// Check if we are done, searching from the current x,y
-		done = pi.Done(x, y)
+	if pi.TooManyRects() {
+		if c.verbose {
+			fmt.Printf("More than %d rectangles are needed, embedding the image as a PNG instead.\n", c.maxRects)
+		}
+		return c.writeEmbeddedPNG(img)
 	}
 
 	if c.verbose {
-		png2svg.Erase(len(fmt.Sprintf("%d%%", lastPercentage)))
-		fmt.Println("100%")
+		stats := pi.Stats()
+		fmt.Printf("Placed %d rectangles, using %d colors.\n", stats.Rects, stats.Colors)
+		if c.components {
+			fmt.Printf("Found %d connected components.\n", stats.Components)
+		}
+		if c.hybrid != png2svg.HybridOff {
+			fmt.Printf("Placed %d of the rectangles at the edges of the transparent regions.\n", stats.EdgeRects)
+		}
+		if c.order == png2svg.OrderLargest {
+			fmt.Printf("Covering the largest rectangles first places %d rectangles, the row order places %d.\n", stats.LargestRects, stats.RowRects)
+		}
 	}
 
-	if c.singlePixelRectangles {
-		// Cover all remaining pixels with rectangles of size 1x1
-		pi.CoverAllPixels()
+	if c.jsonFilename != "" {
+		// Write the rectangles as JSON to jsonFilename
+		if err := pi.WriteJSON(c.jsonFilename); err != nil {
+			return err
+		}
 	}
 
-	// Write the SVG image to outputFilename
-	return pi.WriteSVG(c.outputFilename)
+	if c.swatchFilename != "" {
+		// Write the colors as a PNG image with one swatch per color
+		if err := pi.WriteSwatch(c.swatchFilename); err != nil {
+			return err
+		}
+	}
+
+	if c.goFilename != "" {
+		// Write the SVG image as a string variable in a Go source file
+		if err := pi.WriteGoSource(c.goFilename, c.goPackage, c.goVariable); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case c.dataURI:
+		// Write the SVG image as a data URI to outputFilename
+		err = pi.WriteDataURI(c.outputFilename)
+	case c.pretty:
+		// Write the indented SVG image to outputFilename
+		err = pi.WriteSVGIndented(c.outputFilename, "  ")
+	default:
+		// Write the SVG image to outputFilename
+		err = pi.WriteSVG(c.outputFilename)
+	}
+
+	prof.phase("serialize")
+	prof.report(os.Stderr)
+
+	return err
+}
+
+// readReference reads the reference image, and downsamples it like the input image,
+// so that the pixels can be compared
+func (c *Config) readReference() (image.Image, error) {
+	ref, _, err := readImage(c.referenceFilename, c.verbose)
+	if err != nil {
+		return nil, err
+	}
+	if c.downsample > 1 {
+		ref = png2svg.Downsample(ref, c.downsample)
+	}
+	return ref, nil
+}
+
+// writeEmbeddedPNG writes the image as an SVG document with an embedded PNG image,
+// in the same output format as the converted SVG images
+func (c *Config) writeEmbeddedPNG(img image.Image) error {
+	switch {
+	case c.dataURI:
+		return png2svg.WriteEmbeddedPNGDataURI(c.outputFilename, img, c.Options())
+	case c.pretty:
+		return png2svg.WriteEmbeddedPNGSVGIndented(c.outputFilename, img, c.Options(), "  ")
+	default:
+		return png2svg.WriteEmbeddedPNGSVG(c.outputFilename, img, c.Options())
+	}
 }
 
 func main() {
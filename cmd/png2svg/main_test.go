@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xyproto/png2svg"
+)
+
+func TestUpToDate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "png2svg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	inputFilename := filepath.Join(dir, "input.png")
+	outputFilename := filepath.Join(dir, "output.svg")
+	for _, filename := range []string{inputFilename, outputFilename} {
+		if err := ioutil.WriteFile(filename, []byte{}, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Make the input file older than the output file
+	past := time.Now().Add(-time.Hour)
+	if err := os.Chtimes(inputFilename, past, past); err != nil {
+		t.Fatal(err)
+	}
+
+	c := &Config{downsample: 1}
+	key := c.optionsKey()
+	if upToDate([]string{inputFilename}, outputFilename, key) {
+		t.Error("expected the output to be converted again when there is no options hash")
+	}
+	if err := writeOptionsHash(outputFilename, key); err != nil {
+		t.Fatal(err)
+	}
+	if !upToDate([]string{inputFilename}, outputFilename, key) {
+		t.Error("expected the output to be up to date when the flags are the same")
+	}
+
+	// Changing a flag should cause the image to be converted again
+	for name, changed := range map[string]*Config{
+		"l":      {downsample: 1, limit: true},
+		"pretty": {downsample: 1, pretty: true},
+		"bkgd":   {downsample: 1, pngBackground: true},
+		"recolor": {downsample: 1, recolor: map[color.NRGBA]color.NRGBA{
+			{0, 0, 0, 255}: {255, 0, 0, 255},
+		}},
+		"spot-colors": {downsample: 1, spotColors: map[string]string{"#e03c31": "PANTONE 485 C"}},
+		"views":       {downsample: 1, views: viewFlags{{Name: "home", Width: 16, Height: 16}}},
+		"json":        {downsample: 1, jsonFilename: "output.json"},
+		"swatch":      {downsample: 1, swatchFilename: "swatch.png"},
+		"go":          {downsample: 1, goFilename: "image.go"},
+		"go-package":  {downsample: 1, goPackage: "assets"},
+		"go-var":      {downsample: 1, goVariable: "logo"},
+	} {
+		if upToDate([]string{inputFilename}, outputFilename, changed.optionsKey()) {
+			t.Errorf("expected the output to be converted again when -%s is changed", name)
+		}
+	}
+
+	// Changing the contents of a color mapping file, but not the number of colors,
+	// should also cause the image to be converted again
+	blackToRed := &Config{downsample: 1, recolor: map[color.NRGBA]color.NRGBA{{0, 0, 0, 255}: {255, 0, 0, 255}}}
+	whiteToGreen := &Config{downsample: 1, recolor: map[color.NRGBA]color.NRGBA{{255, 255, 255, 255}: {0, 255, 0, 255}}}
+	if err := writeOptionsHash(outputFilename, blackToRed.optionsKey()); err != nil {
+		t.Fatal(err)
+	}
+	if !upToDate([]string{inputFilename}, outputFilename, blackToRed.optionsKey()) {
+		t.Error("expected the output to be up to date when the color mapping is the same")
+	}
+	if upToDate([]string{inputFilename}, outputFilename, whiteToGreen.optionsKey()) {
+		t.Error("expected the output to be converted again when the color mapping is changed")
+	}
+
+	// With -animate, a newer frame should cause the image to be converted again
+	frameFilename := filepath.Join(dir, "frame2.png")
+	if err := ioutil.WriteFile(frameFilename, []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(frameFilename, past, past); err != nil {
+		t.Fatal(err)
+	}
+	animated := &Config{downsample: 1, animate: true, inputFilename: inputFilename, frameFilenames: []string{inputFilename, frameFilename}}
+	if err := writeOptionsHash(outputFilename, animated.optionsKey()); err != nil {
+		t.Fatal(err)
+	}
+	if !upToDate(animated.inputFilenames(), outputFilename, animated.optionsKey()) {
+		t.Error("expected the animation to be up to date when no frame is newer")
+	}
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(frameFilename, future, future); err != nil {
+		t.Fatal(err)
+	}
+	if upToDate(animated.inputFilenames(), outputFilename, animated.optionsKey()) {
+		t.Error("expected the animation to be converted again when the second frame is newer")
+	}
+
+	// A newer input file should cause the image to be converted again
+	if err := os.Chtimes(inputFilename, future, future); err != nil {
+		t.Fatal(err)
+	}
+	if upToDate([]string{inputFilename}, outputFilename, key) {
+		t.Error("expected the output to be converted again when the input file is newer")
+	}
+}
+
+func TestBenchmark(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(i * 37)
+	}
+	var buf bytes.Buffer
+	benchmark(&buf, img, png2svg.Options{}, 3)
+	fields := strings.Fields(buf.String())
+	// 3 conversions: min 1ms, median 2ms, max 3ms
+	if len(fields) != 8 || fields[0] != "3" || fields[1] != "conversions:" {
+		t.Fatalf("unexpected report: %q", buf.String())
+	}
+	var durations []time.Duration
+	for _, i := range []int{3, 5, 7} {
+		d, err := time.ParseDuration(strings.TrimSuffix(fields[i], ","))
+		if err != nil {
+			t.Fatal(err)
+		}
+		durations = append(durations, d)
+	}
+	if durations[0] > durations[1] || durations[1] > durations[2] {
+		t.Errorf("expected min <= median <= max, got %v", durations)
+	}
+}
+
+func TestViewFlags(t *testing.T) {
+	c, err := parseArgs(t, "-views", "home:0,0,16,16", "-views", "search:16,0,16,16", "input.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []png2svg.View{{Name: "home", Width: 16, Height: 16}, {Name: "search", X: 16, Width: 16, Height: 16}}
+	if views := c.Options().Views; len(views) != 2 || views[0] != want[0] || views[1] != want[1] {
+		t.Errorf("expected the views %v, got %v", want, views)
+	}
+	var views viewFlags
+	if err := views.Set("home:0,0,16"); err != png2svg.ErrInvalidView || len(views) != 0 {
+		t.Errorf("expected ErrInvalidView, got %v", err)
+	}
+}
+
+func TestMaxAspectFlag(t *testing.T) {
+	for _, tc := range []struct {
+		maxAspect string
+		err       error
+	}{
+		{"0", nil},
+		{"1", nil},
+		{"1.5", nil},
+		{"0.5", png2svg.ErrInvalidMaxAspect},
+	} {
+		if _, err := parseArgs(t, "-maxaspect", tc.maxAspect, "input.png"); err != tc.err {
+			t.Errorf("-maxaspect %s: expected %v, got %v", tc.maxAspect, tc.err, err)
+		}
+	}
+}
+
+func TestReadReference(t *testing.T) {
+	dir, err := ioutil.TempDir("", "png2svg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			img.SetNRGBA(x, y, color.NRGBA{uint8(x * 60), uint8(y * 60), 0, 255})
+		}
+	}
+	filename := filepath.Join(dir, "ref.png")
+	f, err := os.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	// The reference image is downsampled like the input image, so that it compares equal
+	c := &Config{referenceFilename: filename, downsample: 2}
+	ref, err := c.readReference()
+	if err != nil {
+		t.Fatal(err)
+	}
+	downsampled := png2svg.Downsample(img, 2)
+	if ref.Bounds() != downsampled.Bounds() {
+		t.Fatalf("expected the bounds %v, got %v", downsampled.Bounds(), ref.Bounds())
+	}
+	pi := png2svg.NewPixelImage(downsampled, false)
+	if count := pi.SetReference(ref, 0); count != 0 {
+		t.Errorf("expected no pixels to differ from the downsampled reference image, got %d", count)
+	}
+}
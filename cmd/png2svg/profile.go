@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"time"
+)
+
+// phaseTiming is the time spent in a phase of the conversion,
+// and the size of the heap at the end of the phase
+type phaseTiming struct {
+	name     string
+	duration time.Duration
+	heap     uint64
+}
+
+// profiler measures the time and memory that is used by each phase of the conversion.
+// All methods can be called on a nil profiler, which does nothing.
+type profiler struct {
+	start    time.Time
+	last     time.Time
+	phases   []phaseTiming
+	peakHeap uint64
+}
+
+// newProfiler returns a profiler that starts measuring the first phase now
+func newProfiler() *profiler {
+	now := time.Now()
+	return &profiler{start: now, last: now}
+}
+
+// phase ends the current phase, with the given name, and starts the next one
+func (p *profiler) phase(name string) {
+	if p == nil {
+		return
+	}
+	now := time.Now()
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	if m.HeapAlloc > p.peakHeap {
+		p.peakHeap = m.HeapAlloc
+	}
+	p.phases = append(p.phases, phaseTiming{name, now.Sub(p.last), m.HeapAlloc})
+	p.last = now
+}
+
+// megabytes formats the given number of bytes as megabytes
+func megabytes(n uint64) string {
+	return fmt.Sprintf("%.1f MiB", float64(n)/(1024*1024))
+}
+
+// report writes the time and heap size of each phase, and the total allocations, to w
+func (p *profiler) report(w io.Writer) {
+	if p == nil {
+		return
+	}
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	for _, phase := range p.phases {
+		fmt.Fprintf(w, "%-10s %12v  heap %s\n", phase.name, phase.duration, megabytes(phase.heap))
+	}
+	fmt.Fprintf(w, "%-10s %12v\n", "total", p.last.Sub(p.start))
+	fmt.Fprintf(w, "peak heap (at the end of a phase): %s, allocated in total: %s in %d allocations, %d garbage collections\n", megabytes(p.peakHeap), megabytes(m.TotalAlloc), m.Mallocs, m.NumGC)
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestProfiler(t *testing.T) {
+	// A nil profiler does nothing
+	var disabled *profiler
+	disabled.phase("decode")
+	var buf bytes.Buffer
+	disabled.report(&buf)
+	if buf.Len() != 0 {
+		t.Errorf("expected no report from a nil profiler, got %q", buf.String())
+	}
+
+	p := newProfiler()
+	phases := []string{"decode", "pixels", "cover", "serialize"}
+	for _, name := range phases {
+		p.phase(name)
+	}
+	p.report(&buf)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != len(phases)+2 {
+		t.Fatalf("expected one line per phase, a total and a memory summary, got:\n%s", buf.String())
+	}
+	for i, name := range append(phases, "total") {
+		if !strings.HasPrefix(lines[i], name+" ") {
+			t.Errorf("expected line %d to start with %q, got %q", i, name, lines[i])
+		}
+	}
+	if !strings.HasPrefix(lines[len(lines)-1], "peak heap") || !strings.Contains(lines[len(lines)-1], "MiB") {
+		t.Errorf("expected the memory summary last, got %q", lines[len(lines)-1])
+	}
+	var total int64
+	for _, phase := range p.phases {
+		total += int64(phase.duration)
+		if phase.heap == 0 || phase.heap > p.peakHeap {
+			t.Errorf("unexpected heap size %d for %s, with the peak %d", phase.heap, phase.name, p.peakHeap)
+		}
+	}
+	if total != int64(p.last.Sub(p.start)) {
+		t.Errorf("expected the phases to add up to the total time")
+	}
+}
+
+func TestMegabytes(t *testing.T) {
+	for n, want := range map[uint64]string{0: "0.0 MiB", 1024 * 1024: "1.0 MiB", 3 * 1024 * 1024 / 2: "1.5 MiB"} {
+		if got := megabytes(n); got != want {
+			t.Errorf("megabytes(%d) = %q, expected %q", n, got, want)
+		}
+	}
+}
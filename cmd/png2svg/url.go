@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/xyproto/png2svg"
+)
+
+const (
+	// downloadTimeout is the maximum time spent on fetching an image from an URL
+	downloadTimeout = 30 * time.Second
+	// maxDownloadSize is the maximum number of bytes that are read from an URL
+	maxDownloadSize = 64 * 1024 * 1024
+)
+
+// isURL checks if the given input filename is an http:// or https:// URL
+func isURL(filename string) bool {
+	return strings.HasPrefix(filename, "http://") || strings.HasPrefix(filename, "https://")
+}
+
+// download fetches the contents of the given URL. Responses that are not
+// "200 OK", that do not have an image content type or that are larger than
+// maxDownloadSize are reported as errors.
+func download(url string) ([]byte, error) {
+	client := &http.Client{Timeout: downloadTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("could not fetch %s: %s", url, resp.Status)
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if mediaType, _, err := mime.ParseMediaType(contentType); err != nil || !strings.HasPrefix(mediaType, "image/") {
+		return nil, fmt.Errorf("could not fetch %s: expected an image, got content type %q", url, contentType)
+	}
+	if resp.ContentLength > maxDownloadSize {
+		return nil, fmt.Errorf("could not fetch %s: the image is larger than %d bytes", url, maxDownloadSize)
+	}
+	data, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxDownloadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxDownloadSize {
+		return nil, fmt.Errorf("could not fetch %s: the image is larger than %d bytes", url, maxDownloadSize)
+	}
+	return data, nil
+}
+
+// readImage reads a PNG image from the given filename or http(s) URL.
+// For URLs, the downloaded data is also returned, so that it can be
+// read again without fetching it twice.
+func readImage(filename string, verbose bool) (image.Image, []byte, error) {
+	if !isURL(filename) {
+		img, err := png2svg.ReadPNG(filename, verbose)
+		return img, nil, err
+	}
+	if verbose {
+		fmt.Printf("Fetching %s", filename)
+		defer fmt.Println()
+	}
+	data, err := download(filename)
+	if err != nil {
+		return nil, nil, err
+	}
+	img, err := png2svg.ReadPNGFromReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, nil, err
+	}
+	if verbose {
+		fmt.Printf(" (%dx%d)", img.Bounds().Dx(), img.Bounds().Dy())
+	}
+	return img, data, nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestIsURL(t *testing.T) {
+	for filename, want := range map[string]bool{
+		"http://example.com/a.png":  true,
+		"https://example.com/a.png": true,
+		"a.png":                     false,
+		"ftp://example.com/a.png":   false,
+		"-":                         false,
+	} {
+		if got := isURL(filename); got != want {
+			t.Errorf("isURL(%q) = %v, expected %v", filename, got, want)
+		}
+	}
+}
+
+func TestReadImage(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 2))
+	img.SetNRGBA(1, 1, color.NRGBA{255, 0, 0, 255})
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, img); err != nil {
+		t.Fatal(err)
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/image.png":
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(pngData.Bytes())
+		case "/page.html":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<html></html>"))
+		case "/large.png":
+			// The declared size is checked before the body is read
+			w.Header().Set("Content-Type", "image/png")
+			w.Header().Set("Content-Length", strconv.Itoa(maxDownloadSize+1))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	got, data, err := readImage(server.URL+"/image.png", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Bounds() != img.Bounds() || color.NRGBAModel.Convert(got.At(1, 1)) != img.At(1, 1) {
+		t.Error("expected the downloaded image to be the same as the original image")
+	}
+	if !bytes.Equal(data, pngData.Bytes()) {
+		t.Error("expected the downloaded data to be returned")
+	}
+
+	for path, want := range map[string]string{
+		"/missing.png": "404",
+		"/page.html":   "expected an image",
+		"/large.png":   "larger than",
+	} {
+		if _, _, err := readImage(server.URL+path, false); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected an error that contains %q, got %v", path, want, err)
+		}
+	}
+}
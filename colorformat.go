@@ -0,0 +1,178 @@
+package png2svg
+
+import (
+	"errors"
+	"image/color"
+	"strconv"
+	"strings"
+)
+
+// ColorFormat decides how fill colors are written to the SVG document
+type ColorFormat int
+
+const (
+	// ColorHex writes colors on the form #aabbcc or #abc (the default)
+	ColorHex ColorFormat = iota
+	// ColorRGB writes colors on the form rgb(170,187,204)
+	ColorRGB
+	// ColorRGBA writes colors on the form rgba(170,187,204,0.5)
+	ColorRGBA
+)
+
+// String returns the name of the color format, as used by NewColorFormat
+func (colorFormat ColorFormat) String() string {
+	switch colorFormat {
+	case ColorRGB:
+		return "rgb"
+	case ColorRGBA:
+		return "rgba"
+	default:
+		return "hex"
+	}
+}
+
+// ErrUnknownColorFormat is returned by NewColorFormat if the color format is not recognized
+var ErrUnknownColorFormat = errors.New("unknown color format, use hex, rgb or rgba")
+
+// NewColorFormat returns a ColorFormat, given "hex", "rgb" or "rgba"
+func NewColorFormat(format string) (ColorFormat, error) {
+	switch format {
+	case "hex", "":
+		return ColorHex, nil
+	case "rgb":
+		return ColorRGB, nil
+	case "rgba":
+		return ColorRGBA, nil
+	}
+	return ColorHex, ErrUnknownColorFormat
+}
+
+// formatAlpha formats an alpha value (0..255) as an opacity between 0 and 1,
+// with at most 3 decimals and without trailing zeros
+func formatAlpha(a int) string {
+	return formatOpacity(float64(a)/255.0, 3, true)
+}
+
+// colorBytes returns the fill color for the given color, in the selected color format.
+// If optimizeColors is true, the color is quantized to one of 4096 colors.
+// If the color is the one given to SetCurrentColor, "currentColor" is returned.
+func (pi *PixelImage) colorBytes(r, g, b, a int, optimizeColors bool) []byte {
+	if pi.isCurrentColor(r, g, b, a) {
+		return []byte("currentColor")
+	}
+	if optimizeColors {
+		qr, qg, qb := pi.quantize(r, g, b)
+		if pi.colorFormat == ColorHex {
+			return []byte{'#', hexDigits[qr], hexDigits[qg], hexDigits[qb]}
+		}
+		// Use the same levels as #abc would represent: #00, #11 ... #ff
+		r, g, b = qr*17, qg*17, qb*17
+	} else if pi.colorFormat == ColorHex {
+		return hexColor(r, g, b)
+	}
+	rgb := strconv.Itoa(r) + "," + strconv.Itoa(g) + "," + strconv.Itoa(b)
+	if pi.colorFormat == ColorRGBA {
+		return []byte("rgba(" + rgb + "," + formatAlpha(a) + ")")
+	}
+	return []byte("rgb(" + rgb + ")")
+}
+
+// FillMode decides if fill colors are written as fill attributes, style attributes or CSS classes
+type FillMode int
+
+const (
+	// FillAttribute writes fill colors as presentation attributes, like fill="#abc" (the default)
+	FillAttribute FillMode = iota
+	// FillStyle writes fill colors as style attributes, like style="fill:#abc"
+	FillStyle
+	// FillClass writes fill colors as CSS classes, like class="c0", with a <style> block
+	FillClass
+	// FillClassAndFill writes both a CSS class and a fill attribute, like class="c0" fill="#abc",
+	// with a <style> block. The document renders without CSS support, but the colors can still
+	// be changed by overriding the class rules, since CSS rules take precedence over fill attributes.
+	FillClassAndFill
+	// FillPalette writes each fill color once, in a <style> block, and refers to the colors
+	// with the shortest possible class names, like class="a", class="b" ... class="aa".
+	// This is more compact than FillClass, and also more compact than FillAttribute when the
+	// colors are used by several groups each, for instance when the groups are split with
+	// SetMaxRectsPerGroup. Each reference saves 5 bytes compared to fill="#aabbcc", while the
+	// <style> block costs about 16 bytes per color.
+	FillPalette
+)
+
+// ErrUnknownFillMode is returned by NewFillMode if the fill mode is not recognized
+var ErrUnknownFillMode = errors.New("unknown fill mode, use attr, style, class, class-and-fill or palette")
+
+// NewFillMode returns a FillMode, given "attr", "style", "class", "class-and-fill" or "palette"
+func NewFillMode(mode string) (FillMode, error) {
+	switch mode {
+	case "attr", "":
+		return FillAttribute, nil
+	case "style":
+		return FillStyle, nil
+	case "class":
+		return FillClass, nil
+	case "class-and-fill":
+		return FillClassAndFill, nil
+	case "palette":
+		return FillPalette, nil
+	}
+	return FillAttribute, ErrUnknownFillMode
+}
+
+// String returns the name of the fill mode, as used by NewFillMode
+func (fillMode FillMode) String() string {
+	switch fillMode {
+	case FillStyle:
+		return "style"
+	case FillClass:
+		return "class"
+	case FillClassAndFill:
+		return "class-and-fill"
+	case FillPalette:
+		return "palette"
+	}
+	return "attr"
+}
+
+// ErrInvalidHexColor is returned by ParseHexColor if the given string is not a hex color
+var ErrInvalidHexColor = errors.New("invalid hex color, use #rgb or #rrggbb")
+
+// ParseHexColor parses a color on the form "#rrggbb" or "#rgb".
+// The returned color is opaque.
+func ParseHexColor(s string) (color.NRGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) == 3 {
+		s = string([]byte{s[0], s[0], s[1], s[1], s[2], s[2]})
+	}
+	if len(s) != 6 {
+		return color.NRGBA{}, ErrInvalidHexColor
+	}
+	x, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return color.NRGBA{}, ErrInvalidHexColor
+	}
+	return color.NRGBA{uint8(x >> 16), uint8(x >> 8), uint8(x), 0xff}, nil
+}
+
+// SetCurrentColor can be used for writing the given color as fill="currentColor",
+// so that it is replaced by the CSS color of the element that contains the SVG image.
+// This is useful for icons that should have the same color as the surrounding text.
+// The color is compared with the colors of the pixels before they are quantized.
+func (pi *PixelImage) SetCurrentColor(c color.NRGBA) {
+	pi.currentColor = &c
+}
+
+// isCurrentColor checks if the given color should be written as "currentColor".
+// The alpha is only compared when the alpha is a part of the color format,
+// since currentColor can not be combined with another alpha.
+func (pi *PixelImage) isCurrentColor(r, g, b, a int) bool {
+	if pi.currentColor == nil {
+		return false
+	}
+	c := pi.currentColor
+	if pi.colorFormat == ColorRGBA && a != 255 {
+		return false
+	}
+	return r == int(c.R) && g == int(c.G) && b == int(c.B)
+}
@@ -0,0 +1,256 @@
+package png2svg
+
+import (
+	"encoding/xml"
+	"image"
+	"image/color"
+	"regexp"
+	"testing"
+)
+
+func TestNewColorFormat(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		want ColorFormat
+		err  error
+	}{
+		{"", ColorHex, nil},
+		{"hex", ColorHex, nil},
+		{"rgb", ColorRGB, nil},
+		{"rgba", ColorRGBA, nil},
+		{"hsl", ColorHex, ErrUnknownColorFormat},
+	} {
+		got, err := NewColorFormat(tc.name)
+		if got != tc.want || err != tc.err {
+			t.Errorf("NewColorFormat(%q) = %v, %v, expected %v, %v", tc.name, got, err, tc.want, tc.err)
+		}
+		if err == nil && tc.name != "" && got.String() != tc.name {
+			t.Errorf("expected %v.String() to be %q", got, tc.name)
+		}
+	}
+}
+
+func TestColorBytes(t *testing.T) {
+	pi := NewPixelImage(image.NewNRGBA(image.Rect(0, 0, 1, 1)), false)
+	for _, tc := range []struct {
+		format   ColorFormat
+		optimize bool
+		want     string
+	}{
+		{ColorHex, false, "#1f89ee"},
+		{ColorHex, true, "#18e"},
+		{ColorRGB, false, "rgb(31,137,238)"},
+		{ColorRGB, true, "rgb(17,136,238)"},
+		{ColorRGBA, false, "rgba(31,137,238,0.502)"},
+	} {
+		pi.SetColorFormat(tc.format)
+		if got := string(pi.colorBytes(0x1f, 0x89, 0xee, 128, tc.optimize)); got != tc.want {
+			t.Errorf("%v (optimize %v): expected %s, got %s", tc.format, tc.optimize, tc.want, got)
+		}
+	}
+}
+
+func TestTranslucentRectsDoNotOverlap(t *testing.T) {
+	for _, rows := range [][]string{
+		{
+			"bhh",
+			"hhh",
+		},
+		{
+			"bhhh",
+			"hhhh",
+			"hhhh",
+		},
+		{
+			"hbh",
+			"hhh",
+			"bhb",
+		},
+	} {
+		img := newTestImage(testPalette, rows...)
+		r := checkRendersAs(t, convertBytes(t, img, Options{ColorFormat: ColorRGBA}), img, 1)
+		for y := 0; y < r.h; y++ {
+			for x := 0; x < r.w; x++ {
+				if got := r.Painted(x, y); got != 1 {
+					t.Errorf("pixel (%d, %d) in %v is painted %d times, expected once", x, y, rows, got)
+				}
+				if want, got := img.NRGBAAt(x, y).A, r.At(x, y).A; want != got {
+					t.Errorf("pixel (%d, %d) in %v has the alpha %d, expected %d", x, y, rows, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestOpaqueRectsMayOverlap(t *testing.T) {
+	// Opaque rectangles may still be expanded over covered pixels, for fewer rectangles
+	img := newTestImage(testPalette,
+		"brr",
+		"rrr",
+	)
+	pi := NewPixelImage(img, false)
+	pi.Cover()
+	if got := len(pi.rects); got != 3 {
+		t.Errorf("expected 3 rectangles, got %d", got)
+	}
+	checkRendersAs(t, pi.Bytes(), img, 0)
+}
+
+func TestParseHexColor(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		want color.NRGBA
+		err  error
+	}{
+		{"#ff00ff", color.NRGBA{255, 0, 255, 255}, nil},
+		{"1f89ee", color.NRGBA{0x1f, 0x89, 0xee, 255}, nil},
+		{"#abc", color.NRGBA{0xaa, 0xbb, 0xcc, 255}, nil},
+		{"#ABC", color.NRGBA{0xaa, 0xbb, 0xcc, 255}, nil},
+		{"#abcd", color.NRGBA{}, ErrInvalidHexColor},
+		{"#gg0000", color.NRGBA{}, ErrInvalidHexColor},
+		{"", color.NRGBA{}, ErrInvalidHexColor},
+	} {
+		got, err := ParseHexColor(tc.s)
+		if got != tc.want || err != tc.err {
+			t.Errorf("ParseHexColor(%q) = %v, %v, expected %v, %v", tc.s, got, err, tc.want, tc.err)
+		}
+	}
+}
+
+func TestNewFillMode(t *testing.T) {
+	for _, mode := range []FillMode{FillAttribute, FillStyle, FillClass, FillClassAndFill, FillPalette} {
+		got, err := NewFillMode(mode.String())
+		if err != nil || got != mode {
+			t.Errorf("NewFillMode(%q) = %v, %v, expected %v", mode.String(), got, err, mode)
+		}
+	}
+	if _, err := NewFillMode("css"); err != ErrUnknownFillMode {
+		t.Errorf("expected ErrUnknownFillMode, got %v", err)
+	}
+}
+
+func TestFillStyle(t *testing.T) {
+	img := newTestImage(testPalette,
+		"rrb",
+		"rgg",
+	)
+	svgDocument := convertBytes(t, img, Options{FillMode: FillStyle})
+	checkRendersAs(t, svgDocument, img, 0)
+	var root svgNode
+	if err := xml.Unmarshal(svgDocument, &root); err != nil {
+		t.Fatal(err)
+	}
+	styles := make(map[string]bool)
+	for _, child := range root.Children {
+		if _, ok := child.attr("fill"); ok {
+			t.Errorf("expected no fill attribute on <%s>", child.XMLName.Local)
+		}
+		style, _ := child.attr("style")
+		styles[style] = true
+	}
+	for _, style := range []string{"fill:red", "fill:#00f", "fill:green"} {
+		if !styles[style] {
+			t.Errorf("expected a tag with style=%q in %s", style, svgDocument)
+		}
+	}
+}
+
+func TestFillClasses(t *testing.T) {
+	img := newTestImage(testPalette,
+		"rrb",
+		"rgg",
+		"b.r",
+	)
+	styleTag := regexp.MustCompile(`<style>.*</style>`)
+	for _, mode := range []FillMode{FillClass, FillClassAndFill} {
+		svgDocument := convertBytes(t, img, Options{FillMode: mode})
+		checkRendersAs(t, svgDocument, img, 0)
+
+		var root svgNode
+		if err := xml.Unmarshal(svgDocument, &root); err != nil {
+			t.Fatal(err)
+		}
+		classes := make(map[string]bool)
+		for _, child := range root.Children {
+			if child.XMLName.Local == "style" {
+				continue
+			}
+			class, ok := child.attr("class")
+			if !ok {
+				t.Errorf("%v: expected a class attribute on <%s>", mode, child.XMLName.Local)
+			}
+			if classes[class] {
+				t.Errorf("%v: the class %s is used by more than one group", mode, class)
+			}
+			classes[class] = true
+			if _, ok := child.attr("fill"); ok != (mode == FillClassAndFill) {
+				t.Errorf("%v: unexpected fill attribute: %v", mode, ok)
+			}
+		}
+		if len(classes) != 3 {
+			t.Errorf("%v: expected 3 classes, got %d", mode, len(classes))
+		}
+
+		// The colors can be changed by changing the CSS rules
+		themed := styleTag.ReplaceAll(svgDocument, []byte("<style>"+cssRules(classes, "#ff0")+"</style>"))
+		r := renderSVG(t, themed)
+		if got := r.At(0, 0); got != testPalette['y'] {
+			t.Errorf("%v: expected the CSS rules to change the colors, got %v", mode, got)
+		}
+
+		// Without CSS, only the fill attributes are used
+		if mode == FillClassAndFill {
+			checkRendersAs(t, styleTag.ReplaceAll(svgDocument, nil), img, 0)
+		}
+	}
+}
+
+// cssRules returns one CSS rule per class, which sets the fill color
+func cssRules(classes map[string]bool, fill string) string {
+	var rules string
+	for class := range classes {
+		rules += "." + class + "{fill:" + fill + "}"
+	}
+	return rules
+}
+
+func TestCurrentColor(t *testing.T) {
+	palette := map[byte]color.NRGBA{
+		'k': {0, 0, 0, 255},
+		't': {0, 0, 0, 128}, // translucent black
+		'r': {255, 0, 0, 255},
+		'.': {},
+	}
+	img := newTestImage(palette,
+		"kkr.",
+		"kkrt",
+	)
+	for _, tc := range []struct {
+		opts    Options
+		current int // the number of pixels that are written as currentColor
+	}{
+		{Options{}, 5},
+		{Options{ColorOptimize: true}, 5},
+		{Options{ColorFormat: ColorRGBA}, 4},
+	} {
+		tc.opts.CurrentColor = &color.NRGBA{0, 0, 0, 255}
+		pi := NewPixelImage(img, false)
+		pi.SetOptions(tc.opts)
+		pi.Cover()
+		svgDocument := pi.Bytes()
+		svgContains(t, svgDocument, `fill="currentColor"`)
+		// currentColor is black, unless the CSS color is set
+		if tc.opts.ColorFormat == ColorRGBA {
+			checkRendersAs(t, svgDocument, img, 1)
+		}
+		current := 0
+		for _, rect := range pi.rects {
+			if rect.Fill == "currentColor" {
+				current += rect.Width * rect.Height
+			}
+		}
+		if current != tc.current {
+			t.Errorf("%s: expected %d pixels with currentColor, got %d", tc.opts, tc.current, current)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package png2svg
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// SetComponents can be used for grouping the rectangles by connected component,
+// which is a region of horizontally or vertically connected pixels with the same
+// color, instead of by fill color. Each component is placed in a <g> tag (or a
+// <path>, if SetPaths is used) with an id like "s0", so that each contiguous shape
+// can be treated as an object by other tools.
+func (pi *PixelImage) SetComponents(enabled bool) {
+	pi.components = enabled
+}
+
+// labelComponents returns the component number of each pixel, and the number of
+// components. Transparent pixels get -1.
+func (pi *PixelImage) labelComponents() ([]int, int) {
+	labels := make([]int, len(pi.pixels))
+	for i := range labels {
+		labels[i] = -1
+	}
+	count := 0
+	var stack []int
+	for start, p := range pi.pixels {
+		if labels[start] != -1 || p.a == 0 {
+			continue
+		}
+		// Flood fill the pixels that are connected to this one and have the same color
+		labels[start] = count
+		stack = append(stack[:0], start)
+		for len(stack) > 0 {
+			i := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			x, y := i%pi.w, i/pi.w
+			for _, n := range [4][2]int{{x - 1, y}, {x + 1, y}, {x, y - 1}, {x, y + 1}} {
+				if n[0] < 0 || n[1] < 0 || n[0] >= pi.w || n[1] >= pi.h {
+					continue
+				}
+				j := n[1]*pi.w + n[0]
+				if labels[j] == -1 && pi.pixels[j].a != 0 && pi.samePixel(i, j) {
+					labels[j] = count
+					stack = append(stack, j)
+				}
+			}
+		}
+		count++
+	}
+	return labels, count
+}
+
+// componentRects returns the rectangles grouped by connected component,
+// in the order the components were first used by a rectangle
+func (pi *PixelImage) componentRects() [][]Rect {
+	labels, count := pi.labelComponents()
+	var (
+		order  []int
+		byComp = make([][]Rect, count)
+	)
+	for _, rect := range pi.rects {
+		// All pixels within a rectangle belong to the same component
+		c := labels[rect.Y*pi.w+rect.X]
+		if c == -1 {
+			continue
+		}
+		if len(byComp[c]) == 0 {
+			order = append(order, c)
+		}
+		byComp[c] = append(byComp[c], rect)
+	}
+	components := make([][]Rect, 0, len(order))
+	for _, c := range order {
+		components = append(components, byComp[c])
+	}
+	return components
+}
+
+// writeComponents writes the rectangles grouped by connected component
+func (pi *PixelImage) writeComponents(buf *bytes.Buffer) {
+	groupIndex := make(map[string]int, len(pi.groupOrder))
+	for i, group := range pi.groupOrder {
+		groupIndex[string(group.fill)] = i
+	}
+	for n, rects := range pi.componentRects() {
+		fill := []byte(rects[0].Fill)
+		if pi.paths {
+			buf.WriteString("<path")
+		} else {
+			buf.WriteString("<g")
+		}
+		buf.WriteString(" id=\"")
+		buf.WriteString(pi.classPrefix)
+		buf.WriteString("s")
+		buf.WriteString(strconv.Itoa(n))
+		buf.WriteByte('"')
+		pi.writeFill(buf, groupIndex[rects[0].Fill], fill)
+		if pi.paths {
+			buf.WriteString("\" d=\"")
+			buf.Write(pathData(rects))
+			buf.WriteString("\"/>")
+			continue
+		}
+		buf.WriteString("\">")
+		for _, rect := range rects {
+			pi.writeRect(buf, rect.X, rect.Y, rect.Width, rect.Height)
+		}
+		buf.WriteString("</g>")
+	}
+}
@@ -0,0 +1,72 @@
+package png2svg
+
+import (
+	"encoding/xml"
+	"strconv"
+	"testing"
+)
+
+func TestLabelComponents(t *testing.T) {
+	img := newTestImage(testPalette,
+		"rr.r",
+		"b..r",
+		"brrr",
+	)
+	pi := NewPixelImage(img, false)
+	labels, count := pi.labelComponents()
+	if count != 3 {
+		t.Fatalf("expected 3 components, got %d", count)
+	}
+	want := []int{
+		0, 0, -1, 1,
+		2, -1, -1, 1,
+		2, 1, 1, 1,
+	}
+	for i, label := range labels {
+		if label != want[i] {
+			t.Errorf("expected the labels %v, got %v", want, labels)
+			break
+		}
+	}
+}
+
+func TestComponents(t *testing.T) {
+	// Two separate red shapes, a blue shape and a green shape that touches one of the red shapes
+	img := newTestImage(testPalette,
+		"rr.rr",
+		"r..rg",
+		"bb.gg",
+	)
+	for _, paths := range []bool{false, true} {
+		pi := NewPixelImage(img, false)
+		pi.SetOptions(Options{Components: true, Paths: paths})
+		pi.Cover()
+		svgDocument := pi.Bytes()
+		checkRendersAs(t, svgDocument, img, 0)
+		if stats := pi.Stats(); stats.Components != 4 {
+			t.Errorf("paths %v: expected 4 components, got %d", paths, stats.Components)
+		}
+
+		var root svgNode
+		if err := xml.Unmarshal(svgDocument, &root); err != nil {
+			t.Fatal(err)
+		}
+		if len(root.Children) != 4 {
+			t.Fatalf("paths %v: expected 4 shapes, got %d", paths, len(root.Children))
+		}
+		fills := make(map[string]int)
+		for i, child := range root.Children {
+			if id, _ := child.attr("id"); id != "s"+strconv.Itoa(i) {
+				t.Errorf("paths %v: expected the id s%d, got %q", paths, i, id)
+			}
+			fill, _ := child.attr("fill")
+			fills[fill]++
+			if tag := child.XMLName.Local; (tag == "path") != paths {
+				t.Errorf("paths %v: unexpected <%s> tag", paths, tag)
+			}
+		}
+		if fills["red"] != 2 || fills["#00f"] != 1 || fills["green"] != 1 {
+			t.Errorf("paths %v: expected two red shapes, one blue and one green, got %v", paths, fills)
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package png2svg
+
+import (
+	"strings"
+)
+
+// dataURIReplacer URL-encodes the characters in an SVG document that needs to be encoded
+// when the document is used as a data URI, also within url("...") in CSS
+var dataURIReplacer = strings.NewReplacer(
+	"%", "%25",
+	"#", "%23",
+	"<", "%3C",
+	">", "%3E",
+	"\"", "%22",
+	"\n", "",
+	"\r", "",
+)
+
+// DataURI returns the given SVG document as a single line "data:image/svg+xml," URI,
+// where only the characters that needs to be are URL-encoded.
+// The result can be used directly in CSS, as background-image: url("...").
+func DataURI(svgDocument []byte) string {
+	return "data:image/svg+xml," + dataURIReplacer.Replace(string(svgDocument))
+}
+
+// WriteDataURI will save the current SVG document as a data URI to a file,
+// or write it to stdout if filename is "-"
+func (pi *PixelImage) WriteDataURI(filename string) error {
+	if !pi.Done(0, 0) {
+		return ErrNotCovered
+	}
+	if filename == "-" {
+		// Turn off verbose messages, so that they don't end up in the output
+		pi.verbose = false
+	}
+	return writeFile(filename, []byte(DataURI(pi.Bytes())))
+}
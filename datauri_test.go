@@ -0,0 +1,39 @@
+package png2svg
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestDataURI(t *testing.T) {
+	for _, tc := range []struct {
+		svgDocument string
+		want        string
+	}{
+		{`<rect fill="#f00"/>`, `data:image/svg+xml,%3Crect fill=%22%23f00%22/%3E`},
+		{"<g>\r\n<rect/>\n</g>", `data:image/svg+xml,%3Cg%3E%3Crect/%3E%3C/g%3E`},
+		{`<title>100%</title>`, `data:image/svg+xml,%3Ctitle%3E100%25%3C/title%3E`},
+	} {
+		if got := DataURI([]byte(tc.svgDocument)); got != tc.want {
+			t.Errorf("DataURI(%q) = %q, expected %q", tc.svgDocument, got, tc.want)
+		}
+	}
+
+	// The data URI can be decoded to the same SVG document, without the newlines
+	img := newTestImage(testPalette, "rrb", ".gg")
+	svgDocument := convertBytes(t, img, Options{Tooltips: true})
+	uri := DataURI(svgDocument)
+	if strings.ContainsAny(uri, "<>\"#\n") {
+		t.Errorf("expected the data URI to have no characters that need to be encoded: %s", uri)
+	}
+	decoded, err := url.PathUnescape(strings.TrimPrefix(uri, "data:image/svg+xml,"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := bytes.Replace(svgDocument, []byte("\n"), nil, -1); decoded != string(want) {
+		t.Errorf("expected the decoded data URI to be\n%s\ngot\n%s", want, decoded)
+	}
+	checkRendersAs(t, []byte(decoded), img, 0)
+}
@@ -0,0 +1,54 @@
+package png2svg
+
+import (
+	"time"
+)
+
+// SetDeadline can be used for limiting the time spent on covering the image with
+// expanding rectangles. When the time is up, the remaining pixels are covered with
+// one rectangle per horizontal run of pixels with the same color, which is fast,
+// but places more rectangles. The result is a complete, but less optimized, SVG image.
+// The time is measured from when Cover is called. 0 means no deadline (the default).
+func (pi *PixelImage) SetDeadline(d time.Duration) {
+	pi.deadline = d
+}
+
+// pastDeadline returns true if a deadline has been set and has been reached
+func (pi *PixelImage) pastDeadline() bool {
+	return pi.deadline > 0 && !pi.stopAt.IsZero() && time.Now().After(pi.stopAt)
+}
+
+// stopCovering returns true if no more expanding rectangles should be placed
+func (pi *PixelImage) stopCovering() bool {
+	return pi.TooManyRects() || pi.pastDeadline()
+}
+
+// coverRuns covers the remaining pixels with one rectangle per horizontal run of
+// uncovered pixels that have the same color. Returns the number of pixels that were covered.
+func (pi *PixelImage) coverRuns() int {
+	coverCount := 0
+	for y := 0; y < pi.h && !pi.TooManyRects(); y++ {
+		for x := 0; x < pi.w; x++ {
+			p := pi.pixels[y*pi.w+x]
+			if p.covered {
+				continue
+			}
+			// Find the length of the run
+			n := 1
+			for x+n < pi.w {
+				q := pi.pixels[y*pi.w+x+n]
+				if q.covered || q.r != p.r || q.g != p.g || q.b != p.b || q.a != p.a {
+					break
+				}
+				n++
+			}
+			pi.addRect(x, y, n, 1, pi.colorBytes(p.r, p.g, p.b, p.a, pi.colorOptimize))
+			for i := 0; i < n; i++ {
+				pi.pixels[y*pi.w+x+i].covered = true
+			}
+			coverCount += n
+			x += n - 1
+		}
+	}
+	return coverCount
+}
@@ -0,0 +1,64 @@
+package png2svg
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCoverRuns(t *testing.T) {
+	img := newTestImage(testPalette,
+		"rrrbb",
+		"r.rrr",
+		"ggggg",
+	)
+	pi := NewPixelImage(img, false)
+	if count := pi.coverRuns(); count != 14 {
+		t.Errorf("expected 14 covered pixels, got %d", count)
+	}
+	want := []Rect{
+		{X: 0, Y: 0, Width: 3, Height: 1},
+		{X: 3, Y: 0, Width: 2, Height: 1},
+		{X: 0, Y: 1, Width: 1, Height: 1},
+		{X: 2, Y: 1, Width: 3, Height: 1},
+		{X: 0, Y: 2, Width: 5, Height: 1},
+	}
+	if len(pi.rects) != len(want) {
+		t.Fatalf("expected %d runs, got %d", len(want), len(pi.rects))
+	}
+	for i, rect := range pi.rects {
+		rect.Fill = ""
+		if rect != want[i] {
+			t.Errorf("expected the run %+v, got %+v", want[i], rect)
+		}
+	}
+	checkRendersAs(t, pi.Bytes(), img, 0)
+}
+
+func TestDeadline(t *testing.T) {
+	img := noiseImage(48, 32, 3)
+	optimized := NewPixelImage(img, false)
+	optimized.Cover()
+
+	// The deadline is reached right away, so the pixels are covered with runs
+	pi := NewPixelImage(img, false)
+	pi.SetOptions(Options{Deadline: time.Nanosecond})
+	pi.Cover()
+	if !pi.pastDeadline() {
+		t.Fatal("expected the deadline to be reached")
+	}
+	if uncovered := pi.Uncovered(); len(uncovered) != 0 {
+		t.Fatalf("expected all pixels to be covered, got %d uncovered pixels", len(uncovered))
+	}
+	checkRendersAs(t, pi.Bytes(), img, 0)
+	if len(pi.rects) < len(optimized.rects) {
+		t.Errorf("expected the runs to need at least as many rectangles, got %d < %d", len(pi.rects), len(optimized.rects))
+	}
+
+	// A deadline that is not reached gives the same result as no deadline
+	pi = NewPixelImage(img, false)
+	pi.SetOptions(Options{Deadline: time.Hour})
+	pi.Cover()
+	if string(pi.Bytes()) != string(optimized.Bytes()) {
+		t.Error("expected the same document when the deadline is not reached")
+	}
+}
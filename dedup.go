@@ -0,0 +1,135 @@
+package png2svg
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// shape is the appearance of a rectangle, without its position
+type shape struct {
+	w, h int
+	fill string
+}
+
+// SetDedup can be used for defining rectangles that have the same width, height and
+// fill color once, within a <defs> tag, and placing them with <use x="..." y="...">.
+// Only shapes that are used often enough for this to save space are defined.
+// This is used when the rectangles are grouped by color, and is ignored if they
+// are grouped in other ways or written as paths.
+func (pi *PixelImage) SetDedup(enabled bool) {
+	pi.dedup = enabled
+}
+
+// dedupShapes returns the id number of each shape that should be defined once and
+// placed with <use>, numbered in the order the shapes were first placed
+func (pi *PixelImage) dedupShapes() map[shape]int {
+	var (
+		counts = make(map[shape]int)
+		order  []shape
+	)
+	for _, rect := range pi.rects {
+		s := shape{rect.Width, rect.Height, rect.Fill}
+		if counts[s] == 0 {
+			order = append(order, s)
+		}
+		counts[s]++
+	}
+	ids := make(map[shape]int)
+	for _, s := range order {
+		id := pi.classPrefix + "d" + strconv.Itoa(len(ids))
+		// The bytes that are saved per <use>, compared to a <rect>, and the size of the definition
+		sizeAttrs := len(` width="` + strconv.Itoa(s.w) + `" height="` + strconv.Itoa(s.h) + `"`)
+		saved := len("<rect") + sizeAttrs - len(`<use xlink:href="#`+id+`"`)
+		definition := len(`<rect id="`+id+`" fill=""/>`) + sizeAttrs + len(s.fill)
+		if counts[s]*saved > definition {
+			ids[s] = len(ids)
+		}
+	}
+	return ids
+}
+
+// writeDedup writes the rectangles grouped by color, like writeShapes, but places the
+// shapes that are used often with <use> tags that refer to a single definition
+func (pi *PixelImage) writeDedup(buf *bytes.Buffer) {
+	ids := pi.dedupShapes()
+	groupIndex := make(map[string]int, len(pi.groupOrder))
+	for i, group := range pi.groupOrder {
+		groupIndex[string(group.fill)] = i
+	}
+	byFill := make(map[string][]Rect, len(pi.groupOrder))
+	for _, rect := range pi.rects {
+		byFill[rect.Fill] = append(byFill[rect.Fill], rect)
+	}
+	writeID := func(id int) {
+		buf.WriteString(pi.classPrefix)
+		buf.WriteString("d")
+		buf.WriteString(strconv.Itoa(id))
+	}
+
+	// Write the definitions, in the order of the id numbers
+	if len(ids) > 0 {
+		defined := make([]shape, len(ids))
+		for s, id := range ids {
+			defined[id] = s
+		}
+		buf.WriteString("<defs>")
+		for id, s := range defined {
+			buf.WriteString("<rect id=\"")
+			writeID(id)
+			buf.WriteString("\" width=\"")
+			buf.WriteString(strconv.Itoa(s.w))
+			buf.WriteString("\" height=\"")
+			buf.WriteString(strconv.Itoa(s.h))
+			buf.WriteByte('"')
+			pi.writeFill(buf, groupIndex[s.fill], []byte(s.fill))
+			buf.WriteString("\"/>")
+		}
+		buf.WriteString("</defs>")
+	}
+
+	for i, group := range pi.groupOrder {
+		rects := byFill[string(group.fill)]
+		if _, ok := ids[shape{rects[0].Width, rects[0].Height, rects[0].Fill}]; len(rects) == 1 && !ok {
+			// Write the fill attribute on the rectangle, like writeShapes does
+			pi.writeRect(buf, rects[0].X, rects[0].Y, rects[0].Width, rects[0].Height)
+			buf.Truncate(buf.Len() - 2)
+			pi.writeFill(buf, i, group.fill)
+			if pi.tooltips {
+				buf.WriteString("\">")
+				writeTooltip(buf, group.hex)
+				buf.WriteString("</rect>")
+			} else {
+				buf.WriteString("\"/>")
+			}
+			continue
+		}
+		for len(rects) > 0 {
+			chunk := rects
+			if pi.maxRectsPerGroup > 0 && len(chunk) > pi.maxRectsPerGroup {
+				chunk = chunk[:pi.maxRectsPerGroup]
+			}
+			buf.WriteString("<g")
+			pi.writeFill(buf, i, group.fill)
+			buf.WriteString("\">")
+			if pi.tooltips {
+				writeTooltip(buf, group.hex)
+			}
+			for _, rect := range chunk {
+				id, ok := ids[shape{rect.Width, rect.Height, rect.Fill}]
+				if !ok {
+					pi.writeRect(buf, rect.X, rect.Y, rect.Width, rect.Height)
+					continue
+				}
+				buf.WriteString("<use xlink:href=\"#")
+				writeID(id)
+				buf.WriteString("\" x=\"")
+				buf.WriteString(strconv.Itoa(rect.X))
+				buf.WriteString("\" y=\"")
+				buf.WriteString(strconv.Itoa(rect.Y))
+				buf.WriteString("\"/>")
+			}
+			buf.WriteString("</g>")
+			rects = rects[len(chunk):]
+		}
+	}
+}
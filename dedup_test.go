@@ -0,0 +1,72 @@
+package png2svg
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func TestDedup(t *testing.T) {
+	img := noiseImage(24, 16, 4)
+	plain := convertBytes(t, img, Options{})
+	for _, opts := range []Options{
+		{Dedup: true},
+		{Dedup: true, MaxRectsPerGroup: 5, Tooltips: true},
+		{Dedup: true, FillMode: FillClass},
+	} {
+		svgDocument := convertBytes(t, img, opts)
+		checkRendersAs(t, svgDocument, img, 0)
+		svgContains(t, svgDocument, xlinkNamespace)
+
+		var root svgNode
+		if err := xml.Unmarshal(svgDocument, &root); err != nil {
+			t.Fatal(err)
+		}
+		defined := make(map[string]bool)
+		used := make(map[string]int)
+		var walk func(n *svgNode)
+		walk = func(n *svgNode) {
+			if n.XMLName.Local == "defs" {
+				for _, def := range n.Children {
+					id, _ := def.attr("id")
+					defined[id] = true
+				}
+				return
+			}
+			if n.XMLName.Local == "use" {
+				href, _ := n.attr("href")
+				used[strings.TrimPrefix(href, "#")]++
+			}
+			for _, child := range n.Children {
+				walk(child)
+			}
+		}
+		walk(&root)
+		if len(defined) == 0 {
+			t.Fatalf("%+v: expected some shapes to be defined once", opts)
+		}
+		for id := range defined {
+			// Each shape is only defined if it is used often enough to save space
+			if used[id] < 2 {
+				t.Errorf("%+v: the shape %s is used %d times", opts, id, used[id])
+			}
+		}
+		for id := range used {
+			if !defined[id] {
+				t.Errorf("%+v: <use> refers to the undefined shape %s", opts, id)
+			}
+		}
+		if opts.MaxRectsPerGroup == 0 && opts.FillMode == FillAttribute && len(svgDocument) >= len(plain) {
+			t.Errorf("%+v: expected the document to be smaller, %d >= %d bytes", opts, len(svgDocument), len(plain))
+		}
+	}
+
+	// Shapes that are only used a few times are not defined
+	img = newTestImage(testPalette, "rrb", "..b")
+	svgDocument := convertBytes(t, img, Options{Dedup: true})
+	if bytes.Contains(svgDocument, []byte("<use")) || bytes.Contains(svgDocument, []byte(xlinkNamespace)) {
+		t.Errorf("expected no <use> tags in %s", svgDocument)
+	}
+	checkRendersAs(t, svgDocument, img, 0)
+}
@@ -0,0 +1,58 @@
+package png2svg
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+)
+
+// SetReference compares the pixels with the given reference image, and marks
+// the pixels that differ with red semi-transparent rectangles, that are placed
+// in a separate <g id="diff"> layer on top of the converted image. A pixel differs
+// if one of the color channels or the alpha differs by more than the tolerance.
+// Pixels that are outside of the reference image also differ.
+// Pixels that are fully transparent in both images do not differ, whatever their color channels are.
+// Returns the number of pixels that differ.
+func (pi *PixelImage) SetReference(ref image.Image, tolerance int) int {
+	var (
+		bounds = ref.Bounds()
+		differ = make([]bool, len(pi.pixels))
+		count  int
+	)
+	within := func(a int, b uint8) bool {
+		d := a - int(b)
+		return d <= tolerance && d >= -tolerance
+	}
+	for i, p := range pi.pixels {
+		x, y := bounds.Min.X+i%pi.w, bounds.Min.Y+i/pi.w
+		if x < bounds.Max.X && y < bounds.Max.Y {
+			c := color.NRGBAModel.Convert(ref.At(x, y)).(color.NRGBA)
+			if p.a == 0 && c.A == 0 {
+				continue
+			}
+			if within(p.r, c.R) && within(p.g, c.G) && within(p.b, c.B) && within(p.a, c.A) {
+				continue
+			}
+		}
+		differ[i] = true
+		count++
+	}
+	pi.diffRects.Reset()
+	coverMask(differ, pi.w, pi.h, func(x, y, w, h int) {
+		pi.writeRect(&pi.diffRects, x, y, w, h)
+	})
+	return count
+}
+
+// writeDiff writes the layer with the pixels that differ from the reference image,
+// if SetReference has been used and there are pixels that differ
+func (pi *PixelImage) writeDiff(buf *bytes.Buffer) {
+	if pi.diffRects.Len() == 0 {
+		return
+	}
+	buf.WriteString("<g id=\"")
+	buf.WriteString(pi.classPrefix)
+	buf.WriteString("diff\" fill=\"red\" fill-opacity=\"0.5\">")
+	buf.Write(pi.diffRects.Bytes())
+	buf.WriteString("</g>")
+}
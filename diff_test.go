@@ -0,0 +1,81 @@
+package png2svg
+
+import (
+	"encoding/xml"
+	"fmt"
+	"image/color"
+	"testing"
+)
+
+func TestSetReference(t *testing.T) {
+	img := newTestImage(testPalette,
+		"rrrr",
+		"rbbr",
+		"rrrr",
+	)
+	ref := newTestImage(testPalette,
+		"rrrr",
+		"rbb.",
+		"rrgg",
+	)
+	ref.SetNRGBA(1, 0, color.NRGBA{250, 3, 0, 255}) // within the tolerance
+	for _, tc := range []struct {
+		tolerance int
+		differ    []string
+	}{
+		{0, []string{"1,0", "3,1", "2,2", "3,2"}},
+		{5, []string{"3,1", "2,2", "3,2"}},
+	} {
+		pi := NewPixelImage(img, false)
+		if count := pi.SetReference(ref, tc.tolerance); count != len(tc.differ) {
+			t.Errorf("tolerance %d: expected %d pixels to differ, got %d", tc.tolerance, len(tc.differ), count)
+		}
+		pi.Cover()
+		svgDocument := pi.Bytes()
+
+		// The diff layer is drawn last, and covers exactly the pixels that differ
+		var root svgNode
+		if err := xml.Unmarshal(svgDocument, &root); err != nil {
+			t.Fatal(err)
+		}
+		layer := root.Children[len(root.Children)-1]
+		if id, _ := layer.attr("id"); id != "diff" {
+			t.Fatalf("expected the diff layer last in %s", svgDocument)
+		}
+		r := renderSVG(t, svgDocument)
+		layerOnly := &raster{w: r.w, h: r.h, pixels: make([]premultiplied, r.w*r.h), painted: make([]int, r.w*r.h)}
+		layerOnly.paint(t, layer, paintStyle{"#000", 1}, identity)
+		marked := make(map[string]bool)
+		for y := 0; y < r.h; y++ {
+			for x := 0; x < r.w; x++ {
+				if layerOnly.Painted(x, y) > 0 {
+					marked[fmt.Sprintf("%d,%d", x, y)] = true
+				}
+			}
+		}
+		for _, p := range tc.differ {
+			if !marked[p] {
+				t.Errorf("tolerance %d: expected (%s) to be marked", tc.tolerance, p)
+			}
+		}
+		if len(marked) != len(tc.differ) {
+			t.Errorf("tolerance %d: expected %d marked pixels, got %v", tc.tolerance, len(tc.differ), marked)
+		}
+		// The pixels that are not marked look like the image
+		if got, want := r.At(0, 0), img.NRGBAAt(0, 0); got != want {
+			t.Errorf("tolerance %d: expected %v, got %v", tc.tolerance, want, got)
+		}
+	}
+}
+
+func TestSetReferenceTransparent(t *testing.T) {
+	img := newTestImage(testPalette, "r.", "..")
+	ref := newTestImage(testPalette, "r.", ".r")
+	// Fully transparent pixels with other color channels look the same
+	ref.SetNRGBA(1, 0, color.NRGBA{255, 255, 255, 0})
+	img.SetNRGBA(0, 1, color.NRGBA{0, 0, 255, 0})
+	pi := NewPixelImage(img, false)
+	if count := pi.SetReference(ref, 0); count != 1 {
+		t.Errorf("expected only the pixel that is transparent in one of the images to differ, got %d", count)
+	}
+}
@@ -0,0 +1,28 @@
+package png2svg
+
+import (
+	"image"
+	"image/color"
+)
+
+// Downsample returns a smaller image that consists of every nth pixel of the
+// given image, in both directions, starting with the upper left pixel.
+// The width and height of the returned image is the original width and height
+// divided by n, rounded upwards. This is useful for quickly creating previews.
+// If n is 1 or smaller, the given image is returned as it is.
+func Downsample(img image.Image, n int) image.Image {
+	if n <= 1 {
+		return img
+	}
+	bounds := img.Bounds()
+	width := (bounds.Dx() + n - 1) / n
+	height := (bounds.Dy() + n - 1) / n
+	smallImage := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			c := color.NRGBAModel.Convert(img.At(bounds.Min.X+x*n, bounds.Min.Y+y*n)).(color.NRGBA)
+			smallImage.SetNRGBA(x, y, c)
+		}
+	}
+	return smallImage
+}
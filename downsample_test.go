@@ -0,0 +1,46 @@
+package png2svg
+
+import (
+	"image"
+	"testing"
+)
+
+func TestDownsample(t *testing.T) {
+	img := newTestImage(testPalette,
+		"rgbwk",
+		"kkkkk",
+		"bwkry",
+		"kkkkk",
+		"y.r.g",
+	)
+	for _, tc := range []struct {
+		n    int
+		want *image.NRGBA
+	}{
+		{2, newTestImage(testPalette, "rbk", "bky", "yrg")},
+		{3, newTestImage(testPalette, "rw", "kk")},
+		{5, newTestImage(testPalette, "r")},
+	} {
+		small := Downsample(img, tc.n)
+		if small.Bounds() != tc.want.Bounds() {
+			t.Fatalf("n=%d: expected the size %v, got %v", tc.n, tc.want.Bounds().Size(), small.Bounds().Size())
+		}
+		for y := 0; y < tc.want.Bounds().Dy(); y++ {
+			for x := 0; x < tc.want.Bounds().Dx(); x++ {
+				if got, want := small.At(x, y), tc.want.NRGBAAt(x, y); got != want {
+					t.Errorf("n=%d: pixel (%d, %d) is %v, expected %v", tc.n, x, y, got, want)
+				}
+			}
+		}
+		checkRendersAs(t, convertBytes(t, small, Options{}), small, 0)
+	}
+	if Downsample(img, 1) != image.Image(img) {
+		t.Error("expected the image to be returned as it is for n=1")
+	}
+
+	// Images that do not start at (0, 0)
+	sub := img.SubImage(image.Rect(1, 1, 5, 5))
+	if got, want := Downsample(sub, 2).At(0, 0), img.NRGBAAt(1, 1); got != want {
+		t.Errorf("expected the first pixel of the sub image, %v, got %v", want, got)
+	}
+}
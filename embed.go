@@ -0,0 +1,90 @@
+package png2svg
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"image"
+	"image/png"
+	"strconv"
+)
+
+// ErrTooManyRects is returned by Convert if more rectangles than the maximum
+// set with SetMaxRects are needed for covering the image
+var ErrTooManyRects = errors.New("too many rectangles are needed for covering the image")
+
+// SetMaxRects can be used to stop covering the image when more than n rectangles
+// have been placed, which indicates that the image is not a good fit for being
+// converted to rectangles, like a photo. Use TooManyRects to check if this
+// happened. 0 means no limit (the default).
+func (pi *PixelImage) SetMaxRects(n int) {
+	pi.maxRects = n
+}
+
+// TooManyRects returns true if more rectangles than the maximum set with
+// SetMaxRects have been placed. The image is then only partially covered.
+func (pi *PixelImage) TooManyRects() bool {
+	return pi.maxRects > 0 && len(pi.rects) > pi.maxRects
+}
+
+// EmbeddedPNGSVG returns an SVG document that contains the given image as a
+// base64 encoded PNG image, within an <image> tag. This can be used instead of
+// converting images that would need too many rectangles.
+// If opts.Provenance is set, a <metadata> tag with the png2svg version and the options is added.
+func EmbeddedPNGSVG(img image.Image, opts Options) ([]byte, error) {
+	dataURI, err := pngDataURI(img)
+	if err != nil {
+		return nil, err
+	}
+	w, h := strconv.Itoa(img.Bounds().Dx()), strconv.Itoa(img.Bounds().Dy())
+	var buf bytes.Buffer
+	buf.WriteString(xmlHeader)
+	buf.WriteString("<svg xmlns=\"http://www.w3.org/2000/svg\" xmlns:xlink=\"http://www.w3.org/1999/xlink\" version=\"1.2\" baseProfile=\"tiny\" viewBox=\"0 0 ")
+	buf.WriteString(w + " " + h + "\" width=\"" + w + "px\" height=\"" + h + "px\">")
+	if opts.Provenance {
+		writeProvenance(&buf, opts, opts.Timestamp)
+	}
+	buf.WriteString("<image width=\"" + w + "\" height=\"" + h + "\" xlink:href=\"")
+	buf.WriteString(dataURI)
+	buf.WriteString("\"/></svg>")
+	return buf.Bytes(), nil
+}
+
+// pngDataURI encodes the given image as a PNG image, in a base64 data URI
+func pngDataURI(img image.Image) (string, error) {
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, img); err != nil {
+		return "", err
+	}
+	return "data:image/png;base64," + base64.StdEncoding.EncodeToString(pngData.Bytes()), nil
+}
+
+// WriteEmbeddedPNGSVG will save the given image as an SVG document that contains
+// a base64 encoded PNG image, or write it to stdout if filename is "-".
+// The line endings and byte order mark are given by opts.CRLF and opts.BOM.
+func WriteEmbeddedPNGSVG(filename string, img image.Image, opts Options) error {
+	svgDocument, err := EmbeddedPNGSVG(img, opts)
+	if err != nil {
+		return err
+	}
+	return writeFile(filename, encode(svgDocument, opts.CRLF, opts.BOM))
+}
+
+// WriteEmbeddedPNGSVGIndented is like WriteEmbeddedPNGSVG, but writes one tag per line,
+// indented with the given indentation string
+func WriteEmbeddedPNGSVGIndented(filename string, img image.Image, opts Options, indent string) error {
+	svgDocument, err := EmbeddedPNGSVG(img, opts)
+	if err != nil {
+		return err
+	}
+	return writeFile(filename, encode(indentSVG(svgDocument, indent), opts.CRLF, opts.BOM))
+}
+
+// WriteEmbeddedPNGDataURI is like WriteEmbeddedPNGSVG, but writes the SVG document as a data URI
+func WriteEmbeddedPNGDataURI(filename string, img image.Image, opts Options) error {
+	svgDocument, err := EmbeddedPNGSVG(img, opts)
+	if err != nil {
+		return err
+	}
+	return writeFile(filename, []byte(DataURI(svgDocument)))
+}
@@ -0,0 +1,131 @@
+package png2svg
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMaxRects(t *testing.T) {
+	img := noiseImage(16, 16, 4)
+	for _, tc := range []struct {
+		maxRects int
+		tooMany  bool
+	}{
+		{0, false},
+		{10, true},
+		{100000, false},
+	} {
+		pi := NewPixelImage(img, false)
+		pi.SetMaxRects(tc.maxRects)
+		pi.Cover()
+		if got := pi.TooManyRects(); got != tc.tooMany {
+			t.Errorf("maxRects %d: TooManyRects() = %v, expected %v", tc.maxRects, got, tc.tooMany)
+		}
+		if tc.tooMany {
+			// Covering stops soon after the maximum is reached
+			if len(pi.rects) > tc.maxRects+1 {
+				t.Errorf("maxRects %d: expected covering to stop, but %d rectangles were placed", tc.maxRects, len(pi.rects))
+			}
+		} else if !pi.Done(0, 0) {
+			t.Errorf("maxRects %d: expected all pixels to be covered", tc.maxRects)
+		}
+	}
+}
+
+func TestEmbeddedPNGSVG(t *testing.T) {
+	img := noiseImage(5, 3, 4)
+	svgDocument, err := EmbeddedPNGSVG(img, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var root svgNode
+	if err := xml.Unmarshal(svgDocument, &root); err != nil {
+		t.Fatal(err)
+	}
+	if viewBox, _ := root.attr("viewBox"); viewBox != "0 0 5 3" {
+		t.Errorf("expected the viewBox 0 0 5 3, got %q", viewBox)
+	}
+	if len(root.Children) != 1 || root.Children[0].XMLName.Local != "image" {
+		t.Fatalf("expected one <image> tag in %s", svgDocument)
+	}
+	href, _ := root.Children[0].attr("href")
+	const prefix = "data:image/png;base64,"
+	if !strings.HasPrefix(href, prefix) {
+		t.Fatalf("expected a PNG data URI, got %.40s", href)
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(href, prefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	embedded, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 5; x++ {
+			if got, want := color.NRGBAModel.Convert(embedded.At(x, y)), img.NRGBAAt(x, y); got != want {
+				t.Errorf("pixel (%d, %d) is %v, expected %v", x, y, got, want)
+			}
+		}
+	}
+}
+
+func TestWriteEmbeddedPNGSVG(t *testing.T) {
+	dir, err := ioutil.TempDir("", "png2svg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	img := noiseImage(5, 3, 4)
+	opts := Options{CRLF: true, BOM: true, Provenance: true}
+	metadata := "<metadata>" + VersionString + "; " + opts.String() + "</metadata>"
+	filename := filepath.Join(dir, "embedded.svg")
+
+	if err := WriteEmbeddedPNGSVG(filename, img, opts); err != nil {
+		t.Fatal(err)
+	}
+	svgDocument, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(svgDocument, utf8BOM) {
+		t.Errorf("expected a byte order mark first in %.60q", svgDocument)
+	}
+	svgContains(t, svgDocument, metadata)
+
+	if err := WriteEmbeddedPNGSVGIndented(filename, img, opts, "  "); err != nil {
+		t.Fatal(err)
+	}
+	if svgDocument, err = ioutil.ReadFile(filename); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(svgDocument, utf8BOM) {
+		t.Errorf("expected a byte order mark first in %.60q", svgDocument)
+	}
+	if lines, crlfs := bytes.Count(svgDocument, []byte("\n")), bytes.Count(svgDocument, []byte("\r\n")); lines < 4 || lines != crlfs {
+		t.Errorf("expected one tag per line with CRLF line endings, got %d CRLF of %d line endings", crlfs, lines)
+	}
+	svgContains(t, svgDocument, "\r\n  "+metadata+"\r\n  <image ")
+
+	if err := WriteEmbeddedPNGDataURI(filename, img, opts); err != nil {
+		t.Fatal(err)
+	}
+	if svgDocument, err = ioutil.ReadFile(filename); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(svgDocument, []byte("data:image/svg+xml,")) {
+		t.Errorf("expected an SVG data URI, got %.60q", svgDocument)
+	}
+	if !bytes.Contains(svgDocument, []byte("%3Cmetadata%3E")) || !bytes.Contains(svgDocument, []byte("data:image/png;base64,")) {
+		t.Errorf("expected the metadata and the embedded PNG image in the data URI, got %.200q", svgDocument)
+	}
+}
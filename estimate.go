@@ -0,0 +1,65 @@
+package png2svg
+
+import (
+	"image"
+	"image/color"
+	"strconv"
+)
+
+// Rough sizes, in bytes, of the parts of a converted SVG document
+const (
+	estimatedHeaderSize  = 180 // XML declaration, <svg> tag and </svg>
+	estimatedGroupSize   = 15  // <g fill=""></g>, excluding the color
+	estimatedTooltipSize = 22  // <title>#rrggbb</title>
+	estimatedRectSize    = 34  // <rect x="" y="" width="" height=""/>, excluding the numbers
+)
+
+// EstimateSVGSize returns a rough estimate of how many bytes the SVG document
+// will be, if the given image is converted with the given options.
+// The image is not converted. Instead, the estimate is based on the number of
+// distinct colors and on the number of horizontal runs of the same color,
+// which is an upper bound for the number of rectangles that are placed.
+// The estimate is approximate and may be quite far off.
+func EstimateSVGSize(img image.Image, opts Options) int {
+	var (
+		pi      = &PixelImage{colorFormat: opts.ColorFormat, rounding: opts.Rounding, distance: opts.DistanceFunc}
+		bounds  = img.Bounds()
+		colors  = make(map[string]bool)
+		size    = estimatedHeaderSize
+		numSize = func(x int) int { return len(strconv.Itoa(x)) }
+	)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		var (
+			last, current color.NRGBA
+			runStart      = bounds.Min.X
+		)
+		for x := bounds.Min.X; x <= bounds.Max.X; x++ {
+			// The boxes are expanded while the pixels have exactly the same color,
+			// also when the colors are quantized afterwards.
+			current = color.NRGBA{}
+			if x < bounds.Max.X {
+				current = color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			}
+			if x > bounds.Min.X && (current != last || opts.SinglePixelRectangles) && last.A > 0 {
+				// The run of pixels from runStart to x is one rectangle
+				size += estimatedRectSize + numSize(runStart) + numSize(y) + numSize(x-runStart) + 1
+			}
+			if current != last || opts.SinglePixelRectangles {
+				runStart = x
+				last = current
+			}
+			if current.A == 0 {
+				continue
+			}
+			fill := string(shortenColor(pi.colorBytes(int(current.R), int(current.G), int(current.B), int(current.A), opts.ColorOptimize), opts.ColorOptimize))
+			if !colors[fill] {
+				colors[fill] = true
+				size += estimatedGroupSize + len(fill)
+				if opts.Tooltips {
+					size += estimatedTooltipSize
+				}
+			}
+		}
+	}
+	return size
+}
@@ -0,0 +1,153 @@
+package png2svg
+
+import (
+	"bytes"
+)
+
+// evenOddRegion is the opaque region of the image, expressed as the d attribute of a
+// <path> that traces the outer and inner boundaries of the region, together with the
+// fill color of the path
+type evenOddRegion struct {
+	d    []byte
+	fill []byte
+}
+
+// SetEvenOdd can be used for covering the opaque region of the image with a single
+// <path> in the most used opaque color, that traces both the outer boundaries of the
+// region and the boundaries of the transparent holes within it. The path is filled
+// with the even-odd fill rule, so that the holes are cut out. The pixels with the most
+// used color are then left out of the regular rectangles. This is an alternative to
+// SetClip, which is used instead if both are enabled. Must be called before the pixels are covered.
+func (pi *PixelImage) SetEvenOdd(enabled bool) {
+	pi.evenOdd = enabled
+}
+
+// coverEvenOdd traces the boundaries of the opaque region, and marks all pixels with
+// the most used opaque color as covered, since they are covered by the traced path.
+// Semi-transparent pixels are left out of the opaque region, like for coverClip.
+func (pi *PixelImage) coverEvenOdd() {
+	pi.evenOddRegion = nil
+
+	dominant, ok := pi.dominantOpaqueColor()
+	if !ok {
+		// No opaque pixels
+		return
+	}
+
+	// If the image is symmetric, only the first half is traced, like for coverClip
+	opaque := make([]bool, len(pi.pixels))
+	for i := range pi.pixels {
+		opaque[i] = pi.opaque(i) && !pi.mirroredHalf(i)
+	}
+	pi.evenOddRegion = &evenOddRegion{
+		d:    boundaryPathData(opaque, pi.w, pi.h),
+		fill: shortestColor(pi.colorBytes(dominant[0], dominant[1], dominant[2], 255, pi.colorOptimize), pi.colorOptimize),
+	}
+	pi.coverOpaqueColor(dominant)
+}
+
+// boundaryPathData returns the d attribute of a path that traces the boundaries between
+// the true and false values in the given mask, as one closed subpath per boundary.
+// Each edge between a true pixel and a false pixel, or the edge of the image, is used
+// exactly once, so when the path is filled with the even-odd fill rule, exactly the
+// true pixels are filled, regardless of how the subpaths are oriented.
+func boundaryPathData(mask []bool, width, height int) []byte {
+	// The corners of the pixels are the vertices, and out holds the vertices
+	// that can be reached from each vertex by following the boundary clockwise
+	stride := width + 1
+	out := make([][]int, stride*(height+1))
+	inside := func(x, y int) bool {
+		return x >= 0 && y >= 0 && x < width && y < height && mask[y*width+x]
+	}
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			if !mask[y*width+x] {
+				continue
+			}
+			topLeft, topRight := y*stride+x, y*stride+x+1
+			bottomLeft, bottomRight := topLeft+stride, topRight+stride
+			if !inside(x, y-1) {
+				out[topLeft] = append(out[topLeft], topRight)
+			}
+			if !inside(x+1, y) {
+				out[topRight] = append(out[topRight], bottomRight)
+			}
+			if !inside(x, y+1) {
+				out[bottomRight] = append(out[bottomRight], bottomLeft)
+			}
+			if !inside(x-1, y) {
+				out[bottomLeft] = append(out[bottomLeft], topLeft)
+			}
+		}
+	}
+
+	var (
+		buf    bytes.Buffer
+		cx, cy int // the current point
+	)
+	for start := range out {
+		for len(out[start]) > 0 {
+			// Follow the boundary until it returns to the start
+			corners := []int{start}
+			for v := start; ; {
+				next := out[v][len(out[v])-1]
+				out[v] = out[v][:len(out[v])-1]
+				if next == start {
+					break
+				}
+				// Only keep the vertices where the direction changes
+				if n := len(corners); n >= 2 && collinear(corners[n-2], corners[n-1], next, stride) {
+					corners[n-1] = next
+				} else {
+					corners = append(corners, next)
+				}
+				v = next
+			}
+			if n := len(corners); n >= 3 && collinear(corners[n-2], corners[n-1], start, stride) {
+				corners = corners[:n-1]
+			}
+
+			x, y := start%stride, start/stride
+			if buf.Len() > 0 && numbersLength(x-cx, y-cy) < numbersLength(x, y) {
+				buf.WriteByte('m')
+				writeNumbers(&buf, x-cx, y-cy)
+			} else {
+				buf.WriteByte('M')
+				writeNumbers(&buf, x, y)
+			}
+			// The last side is drawn by "z"
+			px, py := x, y
+			for _, corner := range corners[1:] {
+				nx, ny := corner%stride, corner/stride
+				if ny == py {
+					buf.WriteByte('h')
+					writeNumbers(&buf, nx-px)
+				} else {
+					buf.WriteByte('v')
+					writeNumbers(&buf, ny-py)
+				}
+				px, py = nx, ny
+			}
+			buf.WriteByte('z')
+			cx, cy = x, y
+		}
+	}
+	return buf.Bytes()
+}
+
+// collinear returns true if the three given vertices are on the same horizontal or vertical line
+func collinear(a, b, c, stride int) bool {
+	return (a/stride == b/stride && b/stride == c/stride) || (a%stride == b%stride && b%stride == c%stride)
+}
+
+// writeEvenOdd writes the <path> that covers the opaque region, if SetEvenOdd has been used
+func (pi *PixelImage) writeEvenOdd(buf *bytes.Buffer) {
+	if pi.evenOddRegion == nil {
+		return
+	}
+	buf.WriteString("<path fill=\"")
+	buf.Write(pi.evenOddRegion.fill)
+	buf.WriteString("\" fill-rule=\"evenodd\" d=\"")
+	buf.Write(pi.evenOddRegion.d)
+	buf.WriteString("\"/>")
+}
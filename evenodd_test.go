@@ -0,0 +1,103 @@
+package png2svg
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+)
+
+func TestBoundaryPathData(t *testing.T) {
+	for _, tc := range []struct {
+		rows []string
+		want string
+	}{
+		{[]string{"x"}, "M0 0h1v1h-1z"},
+		{[]string{"xx", "xx"}, "M0 0h2v2h-2z"},
+		{[]string{"..", ".x"}, "M1 1h1v1h-1z"},
+	} {
+		w, h := len(tc.rows[0]), len(tc.rows)
+		mask := make([]bool, w*h)
+		for y, row := range tc.rows {
+			for x := range row {
+				mask[y*w+x] = row[x] == 'x'
+			}
+		}
+		if got := string(boundaryPathData(mask, w, h)); got != tc.want {
+			t.Errorf("%v: expected %q, got %q", tc.rows, tc.want, got)
+		}
+	}
+}
+
+func TestEvenOdd(t *testing.T) {
+	for _, rows := range [][]string{
+		{
+			"rrrrr",
+			"r...r",
+			"r.b.r",
+			"r...r",
+			"rrrrr",
+		},
+		{
+			"rr.rr",
+			"r.r.r",
+			"rrhrr",
+			".gr..",
+		},
+		{
+			"....",
+			".bb.",
+		},
+	} {
+		img := newTestImage(testPalette, rows...)
+		svgDocument := convertBytes(t, img, Options{EvenOdd: true, ColorFormat: ColorRGBA})
+		r := checkRendersAs(t, svgDocument, img, 1)
+		for y := 0; y < r.h; y++ {
+			for x := 0; x < r.w; x++ {
+				if img.NRGBAAt(x, y).A == 0 && r.Painted(x, y) > 0 {
+					t.Errorf("%v: the hole at (%d, %d) is painted", rows, x, y)
+				}
+			}
+		}
+
+		var root svgNode
+		if err := xml.Unmarshal(svgDocument, &root); err != nil {
+			t.Fatal(err)
+		}
+		path := root.Children[0]
+		if path.XMLName.Local != "path" {
+			t.Fatalf("%v: expected the even-odd path to come first, got %s", rows, svgDocument)
+		}
+		if rule, _ := path.attr("fill-rule"); rule != "evenodd" {
+			t.Errorf("%v: expected the even-odd fill rule, got %q", rows, rule)
+		}
+		// The pixels in the most used color are only covered by the path
+		fill, _ := path.attr("fill")
+		if bytes.Count(svgDocument, []byte(`fill="`+fill+`"`)) != 1 {
+			t.Errorf("%v: expected only the path to have the fill %s, got %s", rows, fill, svgDocument)
+		}
+	}
+
+	// Fully transparent images have no path
+	img := newTestImage(testPalette, "..", "..")
+	if svgDocument := convertBytes(t, img, Options{EvenOdd: true}); bytes.Contains(svgDocument, []byte("<path")) {
+		t.Errorf("expected no path for a transparent image, got %s", svgDocument)
+	}
+}
+
+func TestEvenOddWithSymmetry(t *testing.T) {
+	for _, rows := range [][]string{
+		{"rwwr", "rbbr", "wbbw"},
+		{"rrrrr", "r.b.r", "rrrrr"},
+		{"rw", "r.", "rw"},
+	} {
+		img := newTestImage(testPalette, rows...)
+		pi := NewPixelImage(img, false)
+		pi.SetOptions(Options{EvenOdd: true, Symmetry: true})
+		pi.Cover()
+		if pi.Symmetry() == MirrorNone {
+			t.Errorf("%v: expected the image to be mirrored", rows)
+		}
+		// The even-odd path is mirrored along with the first half, without covering it
+		checkRendersAs(t, pi.Bytes(), img, 0)
+	}
+}
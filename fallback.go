@@ -0,0 +1,59 @@
+package png2svg
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"strconv"
+)
+
+// requiredFeature is a feature string that all SVG renderers with support for
+// <switch> and vector shapes recognize. Modern renderers treat any
+// requiredFeatures attribute as supported.
+const requiredFeature = "http://www.w3.org/TR/SVG11/feature#Shape"
+
+// SetFallback can be used for including a small raster version of the image,
+// as a base64 encoded PNG image, for renderers that can not show the vector
+// content. The vector content and the raster image are placed in a <switch> tag,
+// and only renderers that do not support the vector content show the raster image.
+// The width and height of the raster image is at most the given size, in pixels.
+// 0 disables the fallback (the default).
+func (pi *PixelImage) SetFallback(size int) {
+	pi.fallback = size
+}
+
+// thumbnail returns the pixels as an image, downsampled so that the width
+// and height is at most pi.fallback
+func (pi *PixelImage) thumbnail() image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, pi.w, pi.h))
+	for i, p := range pi.pixels {
+		img.SetNRGBA(i%pi.w, i/pi.w, color.NRGBA{uint8(p.r), uint8(p.g), uint8(p.b), uint8(p.a)})
+	}
+	longest := pi.w
+	if pi.h > longest {
+		longest = pi.h
+	}
+	return Downsample(img, (longest+pi.fallback-1)/pi.fallback)
+}
+
+// writeFallback writes a <switch> tag with the given vector content, followed by
+// an <image> tag with a thumbnail of the image, stretched to cover the whole image
+func (pi *PixelImage) writeFallback(buf *bytes.Buffer, content []byte) {
+	dataURI, err := pngDataURI(pi.thumbnail())
+	if err != nil {
+		// Write the vector content only
+		buf.Write(content)
+		return
+	}
+	buf.WriteString("<switch><g requiredFeatures=\"")
+	buf.WriteString(requiredFeature)
+	buf.WriteString("\">")
+	buf.Write(content)
+	buf.WriteString("</g><image width=\"")
+	buf.WriteString(strconv.Itoa(pi.w))
+	buf.WriteString("\" height=\"")
+	buf.WriteString(strconv.Itoa(pi.h))
+	buf.WriteString("\" preserveAspectRatio=\"none\" xlink:href=\"")
+	buf.WriteString(dataURI)
+	buf.WriteString("\"/></switch>")
+}
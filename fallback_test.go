@@ -0,0 +1,77 @@
+package png2svg
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/xml"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+)
+
+func TestFallback(t *testing.T) {
+	img := newTestImage(testPalette,
+		"rrbbggkk",
+		"rrbbggkk",
+		"kkggbbrr",
+		"kkggbbrr",
+	)
+	pi := NewPixelImage(img, false)
+	pi.SetOptions(Options{Fallback: 4})
+	pi.Cover()
+	svgDocument := pi.Bytes()
+	svgContains(t, svgDocument, xlinkNamespace)
+	// Renderers that support <switch> show the vector content
+	checkRendersAs(t, svgDocument, img, 0)
+
+	var root svgNode
+	if err := xml.Unmarshal(svgDocument, &root); err != nil {
+		t.Fatal(err)
+	}
+	if len(root.Children) != 1 || root.Children[0].XMLName.Local != "switch" {
+		t.Fatalf("expected one <switch> tag in %s", svgDocument)
+	}
+	sw := root.Children[0]
+	if len(sw.Children) != 2 || sw.Children[1].XMLName.Local != "image" {
+		t.Fatalf("expected the vector content and an <image> within the <switch>")
+	}
+	if feature, _ := sw.Children[0].attr("requiredFeatures"); feature != requiredFeature {
+		t.Errorf("expected the required feature %q, got %q", requiredFeature, feature)
+	}
+	fallback := sw.Children[1]
+	if fallback.number(t, "width", 0) != 8 || fallback.number(t, "height", 0) != 4 {
+		t.Errorf("expected the fallback image to cover the whole image")
+	}
+
+	// The thumbnail is downsampled to at most 4 pixels wide, one pixel per 2x2 block
+	href, _ := fallback.attr("href")
+	const prefix = "data:image/png;base64,"
+	if !strings.HasPrefix(href, prefix) {
+		t.Fatalf("expected a PNG data URI, got %.40s", href)
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(href, prefix))
+	if err != nil {
+		t.Fatal(err)
+	}
+	thumbnail, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if size := thumbnail.Bounds().Size(); size.X != 4 || size.Y != 2 {
+		t.Fatalf("expected a 4x2 thumbnail, got %v", size)
+	}
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 4; x++ {
+			if got, want := color.NRGBAModel.Convert(thumbnail.At(x, y)), img.NRGBAAt(x*2, y*2); got != want {
+				t.Errorf("thumbnail pixel (%d, %d) is %v, expected %v", x, y, got, want)
+			}
+		}
+	}
+
+	// Without a fallback, there is no <switch> and no xlink namespace
+	plain := convertBytes(t, img, Options{})
+	if bytes.Contains(plain, []byte("<switch")) || bytes.Contains(plain, []byte(xlinkNamespace)) {
+		t.Errorf("expected no fallback in %s", plain)
+	}
+}
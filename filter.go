@@ -0,0 +1,25 @@
+package png2svg
+
+// PixelFilter is a function that decides if a pixel should be converted.
+// The coordinate starts at (0, 0) and the color channels and alpha are 0..255.
+type PixelFilter func(x, y, r, g, b, a int) bool
+
+// SetPixelFilter makes all pixels where the given function returns false
+// transparent and covered, so that no rectangles are placed for them.
+// This can be used for chroma keying, alpha thresholds, masks and similar.
+// Returns the number of pixels that were filtered out.
+func (pi *PixelImage) SetPixelFilter(filter PixelFilter) int {
+	pi.filter = filter
+	if filter == nil {
+		return 0
+	}
+	count := 0
+	for i, p := range pi.pixels {
+		if p.a > 0 && !filter(i%pi.w, i/pi.w, p.r, p.g, p.b, p.a) {
+			p.a = 0
+			p.covered = true
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,80 @@
+package png2svg
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// Only convert the pixels that are not reddish
+func ExamplePixelFilter() {
+	img := image.NewNRGBA(image.Rect(0, 0, 3, 1))
+	img.SetNRGBA(0, 0, color.NRGBA{255, 0, 0, 255})
+	img.SetNRGBA(1, 0, color.NRGBA{200, 40, 30, 255})
+	img.SetNRGBA(2, 0, color.NRGBA{0, 0, 255, 255})
+
+	pi := NewPixelImage(img, false)
+	pi.SetOptions(Options{PixelFilter: func(x, y, r, g, b, a int) bool {
+		return !(r > 150 && g < 100 && b < 100)
+	}})
+	pi.Cover()
+	fmt.Println(pi.Stats().Rects)
+	fmt.Println(strings.Contains(string(pi.Bytes()), `fill="#00f"`))
+	// Output:
+	// 1
+	// true
+}
+
+func TestPixelFilter(t *testing.T) {
+	img := newTestImage(testPalette,
+		"rrbb",
+		"rgg.",
+		"kkww",
+	)
+	// Filter out the left half
+	leftHalf := func(x, y, r, g, b, a int) bool {
+		return x >= 2
+	}
+	pi := NewPixelImage(img, false)
+	if count := pi.SetPixelFilter(leftHalf); count != 6 {
+		t.Errorf("expected 6 filtered pixels, got %d", count)
+	}
+	pi.Cover()
+	checkRendersAs(t, pi.Bytes(), newTestImage(testPalette,
+		"..bb",
+		"..g.",
+		"..ww",
+	), 0)
+}
+
+func TestPixelFilterWithBackground(t *testing.T) {
+	img := newTestImage(testPalette,
+		"rr.b",
+		"r..b",
+	)
+	yellow := testPalette['y']
+	for _, tc := range []struct {
+		name string
+		opts Options
+		want *image.NRGBA
+	}{
+		{"background", Options{Background: &yellow}, newTestImage(testPalette, "rryb", "ryyb")},
+		// The filtered pixels stay transparent, while the other transparent pixels get the background color
+		{"filter", Options{Background: &yellow, PixelFilter: func(x, y, r, g, b, a int) bool {
+			return b < 128
+		}}, newTestImage(testPalette, "rry.", "ryy.")},
+		{"chroma key", Options{Background: &yellow, ChromaKey: &color.NRGBA{255, 0, 0, 255}}, newTestImage(testPalette, "..yb", ".yyb")},
+	} {
+		svgDocument := convertBytes(t, img, tc.opts)
+		r := checkRendersAs(t, svgDocument, tc.want, 0)
+		for y := 0; y < r.h; y++ {
+			for x := 0; x < r.w; x++ {
+				if tc.want.NRGBAAt(x, y).A == 0 && r.Painted(x, y) > 0 {
+					t.Errorf("%s: the removed pixel (%d, %d) is painted", tc.name, x, y)
+				}
+			}
+		}
+	}
+}
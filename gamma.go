@@ -0,0 +1,41 @@
+package png2svg
+
+import (
+	"math"
+)
+
+// linearToSRGB encodes a linear light value (0..1) with the sRGB transfer function
+func linearToSRGB(v float64) float64 {
+	if v <= 0.0031308 {
+		return 12.92 * v
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+// gammaTable returns a lookup table for converting color channel values that
+// are encoded with the given gamma to sRGB. A gamma of 1 is linear light,
+// while for instance 2.2 is close to, but not quite, sRGB.
+func gammaTable(gamma float64) [256]int {
+	var table [256]int
+	for i := range table {
+		linear := math.Pow(float64(i)/255, gamma)
+		table[i] = int(math.Round(linearToSRGB(linear) * 255))
+	}
+	return table
+}
+
+// SetGamma converts the colors of all pixels from the given input gamma to sRGB.
+// This is useful for images where the pixels are stored as linear light (gamma 1),
+// or with a gamma that differs from sRGB. The alpha is left as it is.
+// By default, the pixels are assumed to already be sRGB, and are not converted.
+// A gamma of 0 or less does nothing.
+func (pi *PixelImage) SetGamma(gamma float64) {
+	if gamma <= 0 {
+		return
+	}
+	pi.gamma = gamma
+	table := gammaTable(gamma)
+	for _, p := range pi.pixels {
+		p.r, p.g, p.b = table[p.r], table[p.g], table[p.b]
+	}
+}
@@ -0,0 +1,49 @@
+package png2svg
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestGammaTable(t *testing.T) {
+	for _, tc := range []struct {
+		gamma float64
+		in    []int
+		want  []int
+	}{
+		{1, []int{0, 1, 64, 128, 255}, []int{0, 13, 137, 188, 255}},
+		{2.2, []int{0, 1, 64, 128, 255}, []int{0, 0, 62, 129, 255}},
+	} {
+		table := gammaTable(tc.gamma)
+		for i, in := range tc.in {
+			if got := table[in]; got != tc.want[i] {
+				t.Errorf("gamma %v: %d became %d, expected %d", tc.gamma, in, got, tc.want[i])
+			}
+		}
+		for i := 1; i < len(table); i++ {
+			if table[i] < table[i-1] {
+				t.Errorf("gamma %v: the table is not increasing at %d", tc.gamma, i)
+			}
+		}
+	}
+}
+
+func TestSetGamma(t *testing.T) {
+	palette := map[byte]color.NRGBA{
+		'g': {128, 64, 255, 100},
+		'.': {},
+	}
+	img := newTestImage(palette, "g.")
+	for _, tc := range []struct {
+		gamma float64
+		want  color.NRGBA
+	}{
+		{0, color.NRGBA{128, 64, 255, 100}},
+		{1, color.NRGBA{188, 137, 255, 100}}, // the alpha is kept
+	} {
+		svgDocument := convertBytes(t, img, Options{Gamma: tc.gamma, ColorFormat: ColorRGBA})
+		if got := renderSVG(t, svgDocument).At(0, 0); got != tc.want {
+			t.Errorf("gamma %v: expected %v, got %v", tc.gamma, tc.want, got)
+		}
+	}
+}
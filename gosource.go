@@ -0,0 +1,63 @@
+package png2svg
+
+import (
+	"bytes"
+	"errors"
+	"go/format"
+	"go/token"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidGoSource is returned by GoSource if the package name or
+// the variable name is not a valid Go identifier
+var ErrInvalidGoSource = errors.New("invalid Go package or variable name")
+
+// isIdentifier checks if the given name is a valid Go identifier, and not a keyword
+func isIdentifier(name string) bool {
+	if name == "" || token.Lookup(name).IsKeyword() {
+		return false
+	}
+	for i, r := range name {
+		if !unicode.IsLetter(r) && r != '_' && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// GoSource returns a Go source file with the given package name, where the
+// SVG document is a string in a package-level variable with the given name.
+// This can be used for compiling images into Go programs without go:embed.
+func (pi *PixelImage) GoSource(packageName, variableName string) ([]byte, error) {
+	if !isIdentifier(packageName) || !isIdentifier(variableName) {
+		return nil, ErrInvalidGoSource
+	}
+	svgDocument := string(pi.Bytes())
+	literal := strconv.Quote(svgDocument)
+	if !strings.ContainsAny(svgDocument, "`\r") {
+		// A raw string literal is more readable and needs no escaping,
+		// but can not contain backquotes or carriage returns
+		literal = "`" + svgDocument + "`"
+	}
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by png2svg. DO NOT EDIT.\n\n")
+	buf.WriteString("package " + packageName + "\n\n")
+	buf.WriteString("// " + variableName + " is an SVG image\n")
+	buf.WriteString("var " + variableName + " = " + literal + "\n")
+	return format.Source(buf.Bytes())
+}
+
+// WriteGoSource will save the SVG document as a string variable in a Go source file,
+// or write it to stdout if filename is "-"
+func (pi *PixelImage) WriteGoSource(filename, packageName, variableName string) error {
+	if !pi.Done(0, 0) {
+		return ErrNotCovered
+	}
+	source, err := pi.GoSource(packageName, variableName)
+	if err != nil {
+		return err
+	}
+	return writeFile(filename, source)
+}
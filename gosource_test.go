@@ -0,0 +1,107 @@
+package png2svg
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// compileGoSource parses and type checks the given Go source file, and returns
+// the package name and the value of the given string variable
+func compileGoSource(t *testing.T, source []byte, variableName string) (string, string) {
+	t.Helper()
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "image.go", source, parser.ParseComments)
+	if err != nil {
+		t.Fatalf("the generated source does not parse: %v\n%s", err, source)
+	}
+	info := &types.Info{Types: make(map[ast.Expr]types.TypeAndValue)}
+	conf := types.Config{Importer: importer.Default()}
+	pkg, err := conf.Check(file.Name.Name, fset, []*ast.File{file}, info)
+	if err != nil {
+		t.Fatalf("the generated source does not compile: %v\n%s", err, source)
+	}
+	obj := pkg.Scope().Lookup(variableName)
+	if obj == nil {
+		t.Fatalf("no variable named %s in\n%s", variableName, source)
+	}
+	if _, ok := obj.(*types.Var); !ok || obj.Type() != types.Typ[types.String] {
+		t.Fatalf("expected %s to be a string variable, got %v", variableName, obj)
+	}
+	spec := file.Decls[0].(*ast.GenDecl).Specs[0].(*ast.ValueSpec)
+	value := info.Types[spec.Values[0]].Value
+	if value == nil || value.Kind() != constant.String {
+		t.Fatalf("expected %s to be a string literal", variableName)
+	}
+	return pkg.Name(), constant.StringVal(value)
+}
+
+func TestGoSource(t *testing.T) {
+	img := newTestImage(testPalette, "rrb", ".gg")
+	for _, opts := range []Options{
+		{},
+		// A backquote can not be in a raw string literal
+		{RootID: "a`b"},
+	} {
+		pi := NewPixelImage(img, false)
+		pi.SetOptions(opts)
+		pi.Cover()
+		source, err := pi.GoSource("assets", "Logo")
+		if err != nil {
+			t.Fatal(err)
+		}
+		packageName, svgDocument := compileGoSource(t, source, "Logo")
+		if packageName != "assets" {
+			t.Errorf("expected the package assets, got %s", packageName)
+		}
+		if svgDocument != string(pi.Bytes()) {
+			t.Errorf("expected the variable to contain the SVG document, got %q", svgDocument)
+		}
+		checkRendersAs(t, []byte(svgDocument), img, 0)
+	}
+
+	pi := NewPixelImage(img, false)
+	pi.Cover()
+	for _, names := range [][2]string{{"", "x"}, {"main", "func"}, {"my-pkg", "x"}, {"main", "1x"}, {"main", "x y"}} {
+		if _, err := pi.GoSource(names[0], names[1]); err != ErrInvalidGoSource {
+			t.Errorf("GoSource(%q, %q): expected ErrInvalidGoSource, got %v", names[0], names[1], err)
+		}
+	}
+	for _, name := range []string{"x", "_x", "x1", "ÆØÅ", "svgImage"} {
+		if !isIdentifier(name) {
+			t.Errorf("expected %q to be a valid identifier", name)
+		}
+	}
+}
+
+func TestWriteGoSource(t *testing.T) {
+	img := newTestImage(testPalette, "rb")
+	dir, err := ioutil.TempDir("", "png2svg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "image.go")
+	pi := NewPixelImage(img, false)
+	if err := pi.WriteGoSource(filename, "main", "svgImage"); err != ErrNotCovered {
+		t.Errorf("expected ErrNotCovered before covering, got %v", err)
+	}
+	pi.Cover()
+	if err := pi.WriteGoSource(filename, "main", "svgImage"); err != nil {
+		t.Fatal(err)
+	}
+	source, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, svgDocument := compileGoSource(t, source, "svgImage"); svgDocument != string(pi.Bytes()) {
+		t.Errorf("expected the written variable to contain the SVG document, got %q", svgDocument)
+	}
+}
@@ -0,0 +1,239 @@
+package png2svg
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+const (
+	// minGradientColors is the smallest number of distinct colors along a gradient.
+	// A <linearGradient> and its rectangle take about as much space as six regular rectangles.
+	minGradientColors = 8
+	// maxGradientPlateau is the largest number of pixels with the same color
+	// that a gradient can start with, which keeps uniform regions from being
+	// examined pixel by pixel
+	maxGradientPlateau = 32
+)
+
+// gradient is a rectangle that is filled with a linear gradient,
+// either from left to right or from top to bottom
+type gradient struct {
+	x, y, w, h int
+	vertical   bool
+	from, to   rgb
+}
+
+// SetGradients can be used for finding rectangular regions where the color changes
+// linearly, either from left to right or from top to bottom, and covering each of
+// them with a single rectangle that is filled with a <linearGradient>. This can make
+// the output much smaller for images with smooth gradients. The tolerance is the
+// largest allowed difference per color channel, between a pixel and the gradient.
+// Only opaque pixels are used for gradients. Must be called before the pixels are covered.
+func (pi *PixelImage) SetGradients(enabled bool, tolerance int) {
+	pi.gradients = enabled
+	pi.gradientTolerance = tolerance
+}
+
+// gradientPixel returns the pixel at the given position along (i) and across (j)
+// a gradient that starts at (x, y)
+func (pi *PixelImage) gradientPixel(x, y, i, j int, vertical bool) *Pixel {
+	if vertical {
+		return pi.pixels[(y+i)*pi.w+x+j]
+	}
+	return pi.pixels[(y+j)*pi.w+x+i]
+}
+
+// interpolate returns the color at position i of n, between the two given colors
+func interpolate(from, to rgb, i, n int) rgb {
+	var c rgb
+	for k := range c {
+		c[k] = from[k] + int(math.Round(float64((to[k]-from[k])*i)/float64(n-1)))
+	}
+	return c
+}
+
+// withinTolerance checks if the given pixel is opaque, uncovered and has a color that
+// is within the gradient tolerance of the given color
+func (pi *PixelImage) withinTolerance(p *Pixel, c rgb) bool {
+	if p.covered || p.a != 255 {
+		return false
+	}
+	for k, v := range [3]int{p.r, p.g, p.b} {
+		if d := v - c[k]; d > pi.gradientTolerance || d < -pi.gradientTolerance {
+			return false
+		}
+	}
+	return true
+}
+
+// findGradient finds the longest linear gradient that starts at (x, y), in the given
+// direction, and how far it extends across that direction. Returns false if there
+// is no gradient with at least minGradientColors colors.
+func (pi *PixelImage) findGradient(x, y int, vertical bool) (gradient, bool) {
+	maxLength, maxBreadth := pi.w-x, pi.h-y
+	if vertical {
+		maxLength, maxBreadth = maxBreadth, maxLength
+	}
+	first := pi.gradientPixel(x, y, 0, 0, vertical)
+	from := rgb{first.r, first.g, first.b}
+
+	// Find the length, by checking the pixels between the first and the last pixel
+	length := 1
+	plateau := true
+	for length < maxLength {
+		p := pi.gradientPixel(x, y, length, 0, vertical)
+		if p.covered || p.a != 255 {
+			break
+		}
+		to := rgb{p.r, p.g, p.b}
+		if plateau && to != from {
+			plateau = false
+		} else if plateau && length >= maxGradientPlateau {
+			return gradient{}, false
+		}
+		fits := true
+		for i := 1; i < length && fits; i++ {
+			fits = pi.withinTolerance(pi.gradientPixel(x, y, i, 0, vertical), interpolate(from, to, i, length+1))
+		}
+		if !fits {
+			break
+		}
+		length++
+	}
+	if length < minGradientColors {
+		return gradient{}, false
+	}
+	last := pi.gradientPixel(x, y, length-1, 0, vertical)
+	to := rgb{last.r, last.g, last.b}
+
+	// A gradient with only a few colors is better covered by regular rectangles
+	colors := make(map[rgb]bool)
+	for i := 0; i < length && len(colors) < minGradientColors; i++ {
+		p := pi.gradientPixel(x, y, i, 0, vertical)
+		colors[rgb{p.r, p.g, p.b}] = true
+	}
+	if len(colors) < minGradientColors {
+		return gradient{}, false
+	}
+
+	// Find how far the gradient extends across its direction
+	breadth := 1
+	for ; breadth < maxBreadth; breadth++ {
+		fits := true
+		for i := 0; i < length && fits; i++ {
+			fits = pi.withinTolerance(pi.gradientPixel(x, y, i, breadth, vertical), interpolate(from, to, i, length))
+		}
+		if !fits {
+			break
+		}
+	}
+	g := gradient{x: x, y: y, w: length, h: breadth, vertical: vertical, from: from, to: to}
+	if vertical {
+		g.w, g.h = breadth, length
+	}
+	return g, true
+}
+
+// gradientStart checks if a gradient in the given direction can start at (x, y).
+// Pixels that have the same color as the uncovered pixel before them are skipped,
+// since the gradient would then have started at the previous pixel.
+func (pi *PixelImage) gradientStart(x, y int, vertical bool) bool {
+	i := y*pi.w + x
+	switch {
+	case vertical && y > 0:
+		return pi.pixels[i-pi.w].covered || !pi.samePixel(i, i-pi.w)
+	case !vertical && x > 0:
+		return pi.pixels[i-1].covered || !pi.samePixel(i, i-1)
+	}
+	return true
+}
+
+// coverGradients finds linear gradients, in row order, and marks their pixels as covered
+func (pi *PixelImage) coverGradients() {
+	pi.gradientRects = nil
+	covered := 0
+	for i, p := range pi.pixels {
+		if p.covered || p.a != 255 {
+			continue
+		}
+		x, y := i%pi.w, i/pi.w
+		var (
+			best  gradient
+			found bool
+		)
+		for _, vertical := range []bool{false, true} {
+			if !pi.gradientStart(x, y, vertical) {
+				continue
+			}
+			if g, ok := pi.findGradient(x, y, vertical); ok && (!found || g.w*g.h > best.w*best.h) {
+				best, found = g, true
+			}
+		}
+		if !found {
+			continue
+		}
+		for gy := best.y; gy < best.y+best.h; gy++ {
+			for gx := best.x; gx < best.x+best.w; gx++ {
+				pi.pixels[gy*pi.w+gx].covered = true
+			}
+		}
+		covered += best.w * best.h
+		pi.gradientRects = append(pi.gradientRects, best)
+	}
+	if pi.verbose {
+		fmt.Printf("Covered %d pixels with %d gradients.\n", covered, len(pi.gradientRects))
+	}
+}
+
+// writeHalf writes a pixel coordinate with .5 added, for the center of the pixel
+func writeHalf(buf *bytes.Buffer, n int) {
+	buf.WriteString(strconv.Itoa(n))
+	buf.WriteString(".5")
+}
+
+// writeGradients writes a <linearGradient> for each gradient, within a <defs> tag,
+// followed by the rectangles that are filled with the gradients. The gradients go
+// from the center of the first pixel to the center of the last pixel.
+func (pi *PixelImage) writeGradients(buf *bytes.Buffer) {
+	if len(pi.gradientRects) == 0 {
+		return
+	}
+	buf.WriteString("<defs>")
+	for n, g := range pi.gradientRects {
+		x2, y2 := g.x, g.y
+		if g.vertical {
+			y2 += g.h - 1
+		} else {
+			x2 += g.w - 1
+		}
+		buf.WriteString("<linearGradient id=\"")
+		buf.WriteString(pi.classPrefix)
+		buf.WriteString("g")
+		buf.WriteString(strconv.Itoa(n))
+		buf.WriteString("\" gradientUnits=\"userSpaceOnUse\" x1=\"")
+		writeHalf(buf, g.x)
+		buf.WriteString("\" y1=\"")
+		writeHalf(buf, g.y)
+		buf.WriteString("\" x2=\"")
+		writeHalf(buf, x2)
+		buf.WriteString("\" y2=\"")
+		writeHalf(buf, y2)
+		buf.WriteString("\"><stop offset=\"0\" stop-color=\"")
+		buf.Write(shortenColor(hexColor(g.from[0], g.from[1], g.from[2]), false))
+		buf.WriteString("\"/><stop offset=\"1\" stop-color=\"")
+		buf.Write(shortenColor(hexColor(g.to[0], g.to[1], g.to[2]), false))
+		buf.WriteString("\"/></linearGradient>")
+	}
+	buf.WriteString("</defs>")
+	for n, g := range pi.gradientRects {
+		pi.writeRect(buf, g.x, g.y, g.w, g.h)
+		buf.Truncate(buf.Len() - 2)
+		buf.WriteString(" fill=\"url(#")
+		buf.WriteString(pi.classPrefix)
+		buf.WriteString("g")
+		buf.WriteString(strconv.Itoa(n))
+		buf.WriteString(")\"/>")
+	}
+}
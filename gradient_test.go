@@ -0,0 +1,74 @@
+package png2svg
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestInterpolate(t *testing.T) {
+	from, to := rgb{0, 100, 255}, rgb{255, 0, 255}
+	for _, tc := range []struct {
+		i    int
+		want rgb
+	}{
+		{0, from},
+		{1, rgb{51, 80, 255}},
+		{2, rgb{102, 60, 255}},
+		{5, to},
+	} {
+		if got := interpolate(from, to, tc.i, 6); got != tc.want {
+			t.Errorf("interpolate(%v, %v, %d, 6) = %v, expected %v", from, to, tc.i, got, tc.want)
+		}
+	}
+}
+
+// gradientsImage returns an image with a horizontal gradient at the top,
+// a vertical gradient at the bottom left and a uniform region at the bottom right
+func gradientsImage() *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, 24, 20))
+	for y := 0; y < 20; y++ {
+		for x := 0; x < 24; x++ {
+			switch {
+			case y < 4:
+				img.SetNRGBA(x, y, color.NRGBA{uint8(x * 10), 40, 200, 255})
+			case x < 12:
+				img.SetNRGBA(x, y, color.NRGBA{0, uint8(y * 12), uint8(255 - y*12), 255})
+			default:
+				img.SetNRGBA(x, y, color.NRGBA{255, 255, 0, 255})
+			}
+		}
+	}
+	return img
+}
+
+func TestGradients(t *testing.T) {
+	img := gradientsImage()
+	plain := convertBytes(t, img, Options{})
+	for _, tolerance := range []int{0, 2} {
+		pi := NewPixelImage(img, false)
+		pi.SetOptions(Options{Gradients: true, GradientTolerance: tolerance})
+		pi.Cover()
+		svgDocument := pi.Bytes()
+		checkRendersAs(t, svgDocument, img, 0)
+		if n := bytes.Count(svgDocument, []byte("<linearGradient")); n != 2 {
+			t.Errorf("tolerance %d: expected 2 gradients, got %d", tolerance, n)
+		}
+		// The two gradients and the uniform region
+		if stats := pi.Stats(); stats.Rects != 3 {
+			t.Errorf("tolerance %d: expected 3 rectangles, got %d", tolerance, stats.Rects)
+		}
+		if len(svgDocument) >= len(plain) {
+			t.Errorf("tolerance %d: expected the gradients to be smaller, %d >= %d bytes", tolerance, len(svgDocument), len(plain))
+		}
+	}
+
+	// Gradients with too few colors are covered with regular rectangles
+	short := newTestImage(testPalette, "rgbkw", "rgbkw")
+	svgDocument := convertBytes(t, short, Options{Gradients: true, GradientTolerance: 255})
+	if bytes.Contains(svgDocument, []byte("<linearGradient")) {
+		t.Errorf("expected no gradients for 5 colors, got %s", svgDocument)
+	}
+	checkRendersAs(t, svgDocument, short, 0)
+}
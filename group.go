@@ -0,0 +1,279 @@
+package png2svg
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// colorGroup contains the rectangles that share the same fill color.
+// The rectangles are rendered without a fill attribute, since the
+// fill color is set on the surrounding <g> tag instead.
+type colorGroup struct {
+	fill  []byte // the shortest representation of the color, like "red"
+	hex   []byte // the color as a hex color, like "#f00", for the tooltips
+	rects bytes.Buffer
+	count int
+}
+
+// group returns the group for the given fill color, and creates it if needed
+func (pi *PixelImage) group(fill []byte) *colorGroup {
+	hex := shortenColor(fill, pi.colorOptimize)
+	fill = shortestColor(hex, pi.colorOptimize)
+	key := string(fill)
+	group, ok := pi.groups[key]
+	if !ok {
+		group = &colorGroup{fill: fill, hex: hex}
+		pi.groups[key] = group
+		pi.groupOrder = append(pi.groupOrder, group)
+	}
+	return group
+}
+
+// addRect adds a rectangle with the given position, size and fill color.
+// The rectangle is placed in the group for that color right away, so that
+// the rendered SVG document does not have to be parsed and grouped afterwards.
+func (pi *PixelImage) addRect(x, y, w, h int, fill []byte) {
+	group := pi.group(fill)
+	pi.writeRect(&group.rects, x, y, w, h)
+	group.count++
+	pi.rects = append(pi.rects, Rect{x, y, w, h, string(group.fill)})
+}
+
+// writeRect writes a <rect> tag without a fill attribute to the given buffer
+func writeRect(buf *bytes.Buffer, x, y, w, h int) {
+	buf.WriteString("<rect x=\"")
+	buf.WriteString(strconv.Itoa(x))
+	buf.WriteString("\" y=\"")
+	buf.WriteString(strconv.Itoa(y))
+	buf.WriteString("\" width=\"")
+	buf.WriteString(strconv.Itoa(w))
+	buf.WriteString("\" height=\"")
+	buf.WriteString(strconv.Itoa(h))
+	buf.WriteString("\"/>")
+}
+
+// gridRule is the CSS rule that gives <rect> tags without a width the size 1x1
+const gridRule = "rect:not([width]){width:1px;height:1px}"
+
+// SetGridCSS can be used for leaving out the width and height of 1x1 rectangles,
+// and instead set their size with a single CSS rule in a <style> tag. This makes
+// the output considerably smaller when most rectangles are 1x1, like when only single
+// pixel rectangles are used. Note that this needs a renderer that supports setting
+// the size of a rectangle with CSS, like a web browser.
+func (pi *PixelImage) SetGridCSS(enabled bool) {
+	pi.gridCSS = enabled
+}
+
+// writeRect writes a <rect> tag without a fill attribute to the given buffer.
+// If SetGridCSS is used, the width and height are left out for 1x1 rectangles.
+func (pi *PixelImage) writeRect(buf *bytes.Buffer, x, y, w, h int) {
+	if !pi.gridCSS || w != 1 || h != 1 {
+		writeRect(buf, x, y, w, h)
+		return
+	}
+	buf.WriteString("<rect x=\"")
+	buf.WriteString(strconv.Itoa(x))
+	buf.WriteString("\" y=\"")
+	buf.WriteString(strconv.Itoa(y))
+	buf.WriteString("\"/>")
+}
+
+// longColor expands a color on the short form "#abc" to "#aabbcc"
+func longColor(hexColorBytes []byte) []byte {
+	if len(hexColorBytes) != 4 {
+		return hexColorBytes
+	}
+	return []byte{'#', hexColorBytes[1], hexColorBytes[1], hexColorBytes[2], hexColorBytes[2], hexColorBytes[3], hexColorBytes[3]}
+}
+
+// writeTooltip writes a <title> tag with the given color, on the form #rrggbb
+func writeTooltip(buf *bytes.Buffer, fill []byte) {
+	buf.WriteString("<title>")
+	buf.Write(longColor(fill))
+	buf.WriteString("</title>")
+}
+
+// paletteLetters are the letters used for the class names in the palette fill mode
+const paletteLetters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// paletteName returns the i-th of the shortest class names: "a" ... "Z", "aa" ... "ZZ", "aaa" ...
+func paletteName(i int) string {
+	name := []byte{paletteLetters[i%len(paletteLetters)]}
+	for i /= len(paletteLetters); i > 0; i /= len(paletteLetters) {
+		i--
+		name = append([]byte{paletteLetters[i%len(paletteLetters)]}, name...)
+	}
+	return string(name)
+}
+
+// className returns the CSS class name for the i-th color group, like "c0",
+// or like "a" in the palette fill mode
+func (pi *PixelImage) className(i int) string {
+	if pi.fillMode == FillPalette {
+		return pi.classPrefix + paletteName(i)
+	}
+	return pi.classPrefix + "c" + strconv.Itoa(i)
+}
+
+// usesClasses returns true if the fill colors are written as CSS classes
+func (pi *PixelImage) usesClasses() bool {
+	return pi.fillMode == FillClass || pi.fillMode == FillClassAndFill || pi.fillMode == FillPalette
+}
+
+// writeFill writes the fill color of the i-th color group as either a fill attribute,
+// a style attribute or a class attribute, like ` fill="#abc`, ` style="fill:#abc`,
+// ` class="c0` or ` class="c0" fill="#abc`, without the closing quote
+func (pi *PixelImage) writeFill(buf *bytes.Buffer, i int, fill []byte) {
+	switch pi.fillMode {
+	case FillStyle:
+		buf.WriteString(" style=\"fill:")
+	case FillClass, FillPalette:
+		buf.WriteString(" class=\"")
+		buf.WriteString(pi.className(i))
+		return
+	case FillClassAndFill:
+		buf.WriteString(" class=\"")
+		buf.WriteString(pi.className(i))
+		buf.WriteString("\" fill=\"")
+	default:
+		buf.WriteString(" fill=\"")
+	}
+	buf.Write(fill)
+}
+
+// writeStyle writes a <style> tag with one CSS class per fill color, like .c0{fill:#abc},
+// if the fill colors are written as CSS classes, and the size of 1x1 rectangles, if SetGridCSS is used
+func (pi *PixelImage) writeStyle(buf *bytes.Buffer) {
+	buf.WriteString("<style>")
+	if pi.gridCSS {
+		buf.WriteString(gridRule)
+	}
+	if !pi.usesClasses() {
+		buf.WriteString("</style>")
+		return
+	}
+	for i, group := range pi.groupOrder {
+		buf.WriteByte('.')
+		buf.WriteString(pi.className(i))
+		buf.WriteString("{fill:")
+		buf.Write(group.fill)
+		buf.WriteByte('}')
+	}
+	buf.WriteString("</style>")
+}
+
+// writeGroups writes all rectangles to the given buffer, grouped by fill color,
+// in the order the colors were first used. Colors that are only used by
+// a single rectangle get a fill attribute instead of a surrounding <g> tag.
+// If tooltips are enabled, each group (or single rectangle) gets a <title>.
+// If the fill colors are written as CSS classes, or SetGridCSS is used, a <style> tag is written first.
+// If the opaque region is clipped or covered with an even-odd path, the background shape comes before the groups.
+// If there are pixels that differ from a reference image, they are marked in a layer after the groups.
+// If SetGroupByRow has been used, the rectangles are grouped by row instead.
+// If SetPaths has been used, the rectangles of each color are written as one <path>.
+// If the image is symmetric and SetSymmetry has been used, only one half is written,
+// together with a mirrored <use> tag for the other half.
+// If SetFallback has been used, the shapes are placed in a <switch> tag, together with a raster image.
+func (pi *PixelImage) writeGroups(buf *bytes.Buffer) {
+	if pi.usesClasses() || pi.gridCSS {
+		pi.writeStyle(buf)
+	}
+	if pi.fallback > 0 {
+		var content bytes.Buffer
+		pi.writeContent(&content)
+		pi.writeFallback(buf, content.Bytes())
+		return
+	}
+	pi.writeContent(buf)
+}
+
+// writeContent writes the shapes, either mirrored or not, the differences from a reference image
+// and the outlines of the rectangles
+func (pi *PixelImage) writeContent(buf *bytes.Buffer) {
+	if pi.mirror != MirrorNone {
+		pi.writeMirrored(buf)
+	} else {
+		pi.writeShapes(buf)
+	}
+	pi.writeDiff(buf)
+	pi.writeOutline(buf)
+}
+
+// writeShapes writes the clipped background or the even-odd path, and the gradients, if any, and all rectangles,
+// either grouped by color, by separation, by connected component, by row or by interlace pass, or as paths
+func (pi *PixelImage) writeShapes(buf *bytes.Buffer) {
+	pi.writeClip(buf)
+	pi.writeEvenOdd(buf)
+	pi.writeGradients(buf)
+	if pi.separations {
+		pi.writeSeparations(buf)
+		return
+	}
+	if pi.components {
+		pi.writeComponents(buf)
+		return
+	}
+	if pi.groupByRow {
+		pi.writeRows(buf)
+		return
+	}
+	if pi.paths {
+		pi.writePaths(buf)
+		return
+	}
+	if pi.interlace {
+		pi.writeInterlaced(buf)
+		return
+	}
+	if pi.dedup {
+		pi.writeDedup(buf)
+		return
+	}
+	for i, group := range pi.groupOrder {
+		if group.count == 1 {
+			// Insert the fill attribute before the closing "/>"
+			rect := group.rects.Bytes()
+			buf.Write(rect[:len(rect)-2])
+			pi.writeFill(buf, i, group.fill)
+			if pi.tooltips {
+				buf.WriteString("\">")
+				writeTooltip(buf, group.hex)
+				buf.WriteString("</rect>")
+			} else {
+				buf.WriteString("\"/>")
+			}
+			continue
+		}
+		// Split the group into several <g> tags, if there is a limit
+		rects := group.rects.Bytes()
+		for len(rects) > 0 {
+			chunk := rects
+			if pi.maxRectsPerGroup > 0 {
+				chunk = firstRects(rects, pi.maxRectsPerGroup)
+			}
+			buf.WriteString("<g")
+			pi.writeFill(buf, i, group.fill)
+			buf.WriteString("\">")
+			if pi.tooltips {
+				writeTooltip(buf, group.hex)
+			}
+			buf.Write(chunk)
+			buf.WriteString("</g>")
+			rects = rects[len(chunk):]
+		}
+	}
+}
+
+// firstRects returns the first n <rect> tags of the given rectangles,
+// as written by writeRect
+func firstRects(rects []byte, n int) []byte {
+	end := 0
+	for i := 0; i < n; i++ {
+		pos := bytes.Index(rects[end:], []byte("/>"))
+		if pos == -1 {
+			break
+		}
+		end += pos + 2
+	}
+	return rects[:end]
+}
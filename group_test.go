@@ -0,0 +1,242 @@
+package png2svg
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image"
+	"image/color"
+	"strings"
+	"testing"
+)
+
+// noiseImage returns an image where most neighbouring pixels differ, which needs many rectangles
+func noiseImage(w, h, colors int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := (x*7 + y*13 + x*y) % colors
+			img.SetNRGBA(x, y, color.NRGBA{uint8(c * 40), uint8(255 - c*30), uint8(c * 17), 255})
+		}
+	}
+	return img
+}
+
+func TestGroupsByColor(t *testing.T) {
+	img := noiseImage(24, 16, 5)
+	svgDocument := convertBytes(t, img, Options{})
+	checkRendersAs(t, svgDocument, img, 0)
+
+	// Each color is used by one <g> tag, or by one rectangle with a fill attribute
+	var root svgNode
+	if err := xml.Unmarshal(svgDocument, &root); err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[string]bool)
+	for _, child := range root.Children {
+		fill, ok := child.attr("fill")
+		if !ok {
+			t.Fatalf("expected a fill attribute on <%s>", child.XMLName.Local)
+		}
+		if seen[fill] {
+			t.Errorf("the fill color %s is used by more than one group", fill)
+		}
+		seen[fill] = true
+		for _, rect := range child.Children {
+			if _, ok := rect.attr("fill"); ok {
+				t.Errorf("a rectangle within a group has a fill attribute")
+			}
+		}
+	}
+	if len(seen) != 5 {
+		t.Errorf("expected 5 colors, got %d", len(seen))
+	}
+}
+
+func TestFirstRects(t *testing.T) {
+	var buf bytes.Buffer
+	for i := 0; i < 5; i++ {
+		writeRect(&buf, i, 0, 1, 1)
+	}
+	rects := buf.Bytes()
+	for _, n := range []int{0, 1, 3, 5, 10} {
+		want := n
+		if want > 5 {
+			want = 5
+		}
+		if got := strings.Count(string(firstRects(rects, n)), "<rect"); got != want {
+			t.Errorf("firstRects(rects, %d) returned %d rectangles, expected %d", n, got, want)
+		}
+	}
+}
+
+// BenchmarkBytes measures rendering an SVG document with many rectangles, which are
+// grouped by color while they are placed, instead of when the document is rendered
+func BenchmarkBytes(b *testing.B) {
+	pi := NewPixelImage(noiseImage(256, 256, 16), false)
+	pi.Cover()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pi.Bytes()
+	}
+}
+
+func TestTooltips(t *testing.T) {
+	img := newTestImage(testPalette,
+		"rrb",
+		"rrg",
+	)
+	svgDocument := convertBytes(t, img, Options{Tooltips: true})
+	checkRendersAs(t, svgDocument, img, 0)
+	var root svgNode
+	if err := xml.Unmarshal(svgDocument, &root); err != nil {
+		t.Fatal(err)
+	}
+	titles := make(map[string]string)
+	for _, child := range root.Children {
+		fill, _ := child.attr("fill")
+		if len(child.Children) == 0 || child.Children[0].XMLName.Local != "title" {
+			t.Fatalf("expected a <title> as the first child of <%s fill=%q>", child.XMLName.Local, fill)
+		}
+		titles[fill] = child.Children[0].Text
+	}
+	for fill, title := range map[string]string{"red": "#ff0000", "#00f": "#0000ff", "green": "#008000"} {
+		if titles[fill] != title {
+			t.Errorf("expected the title %q for %s, got %q", title, fill, titles[fill])
+		}
+	}
+}
+
+func TestMaxRectsPerGroup(t *testing.T) {
+	img := noiseImage(24, 16, 3)
+	for _, limit := range []int{1, 4, 10} {
+		pi := NewPixelImage(img, false)
+		pi.SetOptions(Options{MaxRectsPerGroup: limit})
+		pi.Cover()
+		svgDocument := pi.Bytes()
+		checkRendersAs(t, svgDocument, img, 0)
+
+		var root svgNode
+		if err := xml.Unmarshal(svgDocument, &root); err != nil {
+			t.Fatal(err)
+		}
+		total, groups := 0, make(map[string]int)
+		for _, child := range root.Children {
+			fill, _ := child.attr("fill")
+			groups[fill]++
+			if child.XMLName.Local == "rect" {
+				total++
+				continue
+			}
+			if len(child.Children) > limit {
+				t.Errorf("limit %d: a group has %d rectangles", limit, len(child.Children))
+			}
+			total += len(child.Children)
+		}
+		if total != len(pi.rects) {
+			t.Errorf("limit %d: expected %d rectangles, got %d", limit, len(pi.rects), total)
+		}
+		if limit == 4 && len(groups) != 3 {
+			t.Errorf("expected the groups to use 3 colors, got %d", len(groups))
+		}
+		if limit == 1 && len(root.Children) != total {
+			t.Errorf("expected one group per rectangle, got %d groups for %d rectangles", len(root.Children), total)
+		}
+	}
+}
+
+func TestPaletteName(t *testing.T) {
+	for i, want := range map[int]string{0: "a", 25: "z", 26: "A", 51: "Z", 52: "aa", 53: "ab", 103: "aZ", 104: "ba", 52 + 52*52: "aaa"} {
+		if got := paletteName(i); got != want {
+			t.Errorf("paletteName(%d) = %q, expected %q", i, got, want)
+		}
+	}
+	seen := make(map[string]bool)
+	for i := 0; i < 3000; i++ {
+		name := paletteName(i)
+		if seen[name] {
+			t.Fatalf("paletteName(%d) = %q is used twice", i, name)
+		}
+		seen[name] = true
+	}
+}
+
+func TestFillPalette(t *testing.T) {
+	img := noiseImage(24, 16, 5)
+	opts := Options{FillMode: FillPalette, MaxRectsPerGroup: 4}
+	svgDocument := convertBytes(t, img, opts)
+	checkRendersAs(t, svgDocument, img, 0)
+
+	var root svgNode
+	if err := xml.Unmarshal(svgDocument, &root); err != nil {
+		t.Fatal(err)
+	}
+	if len(root.Children) == 0 || root.Children[0].XMLName.Local != "style" {
+		t.Fatalf("expected a <style> tag first in %s", svgDocument)
+	}
+	classes := make(map[string]bool)
+	for _, child := range root.Children[1:] {
+		if _, ok := child.attr("fill"); ok {
+			t.Errorf("unexpected fill attribute on <%s>", child.XMLName.Local)
+		}
+		class, _ := child.attr("class")
+		if len(class) != 1 {
+			t.Errorf("expected a one letter class name, got %q", class)
+		}
+		classes[class] = true
+	}
+	if len(classes) != 5 {
+		t.Errorf("expected 5 classes, got %d", len(classes))
+	}
+
+	// Each color is written once, so the palette is smaller than the other modes when the groups are split
+	for _, mode := range []FillMode{FillAttribute, FillClass} {
+		opts.FillMode = mode
+		if other := convertBytes(t, img, opts); len(svgDocument) >= len(other) {
+			t.Errorf("expected the palette to be smaller than %v: %d >= %d bytes", mode, len(svgDocument), len(other))
+		}
+	}
+}
+
+func TestGridCSS(t *testing.T) {
+	img := noiseImage(12, 8, 4)
+	for _, opts := range []Options{
+		{GridCSS: true, SinglePixelRectangles: true},
+		{GridCSS: true},
+		{GridCSS: true, FillMode: FillClass},
+	} {
+		svgDocument := convertBytes(t, img, opts)
+		checkRendersAs(t, svgDocument, img, 0)
+		svgContains(t, svgDocument, "<style>"+gridRule)
+		if strings.Count(string(svgDocument), "<style>") != 1 {
+			t.Errorf("%+v: expected one <style> tag", opts)
+		}
+
+		// Only rectangles that are not 1x1 have a width and a height
+		var root svgNode
+		if err := xml.Unmarshal(svgDocument, &root); err != nil {
+			t.Fatal(err)
+		}
+		var check func(n *svgNode)
+		check = func(n *svgNode) {
+			if n.XMLName.Local == "rect" {
+				_, hasWidth := n.attr("width")
+				_, hasHeight := n.attr("height")
+				if hasWidth != hasHeight {
+					t.Errorf("%+v: expected both or neither of width and height", opts)
+				}
+				if hasWidth && n.number(t, "width", 0) == 1 && n.number(t, "height", 0) == 1 {
+					t.Errorf("%+v: a 1x1 rectangle has a width and a height", opts)
+				}
+			}
+			for _, child := range n.Children {
+				check(child)
+			}
+		}
+		check(&root)
+
+		opts.GridCSS = false
+		if without := convertBytes(t, img, opts); len(svgDocument) >= len(without) {
+			t.Errorf("%+v: expected the grid CSS to make the document smaller, %d >= %d bytes", opts, len(svgDocument), len(without))
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package png2svg
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+)
+
+// rgb is a color without alpha, used as a key when counting colors
+type rgb [3]int
+
+// colorFrequency is a color and the number of pixels that has this color
+type colorFrequency struct {
+	c     rgb
+	count int
+}
+
+// histogram returns the colors of all pixels that are not transparent,
+// sorted by how many pixels that has that color, most used first.
+// Colors that are used equally often are sorted by their value.
+func (pi *PixelImage) histogram() []colorFrequency {
+	counts := make(map[rgb]int)
+	for _, p := range pi.pixels {
+		if p.a > 0 {
+			counts[rgb{p.r, p.g, p.b}]++
+		}
+	}
+	frequencies := make([]colorFrequency, 0, len(counts))
+	for c, count := range counts {
+		frequencies = append(frequencies, colorFrequency{c, count})
+	}
+	sort.Slice(frequencies, func(i, j int) bool {
+		a, b := frequencies[i], frequencies[j]
+		if a.count != b.count {
+			return a.count > b.count
+		}
+		if a.c[0] != b.c[0] {
+			return a.c[0] < b.c[0]
+		}
+		if a.c[1] != b.c[1] {
+			return a.c[1] < b.c[1]
+		}
+		return a.c[2] < b.c[2]
+	})
+	return frequencies
+}
+
+// ColorCount is a color and the number of pixels that has this color
+type ColorCount struct {
+	Color color.NRGBA
+	Count int
+}
+
+// DominantColors returns the n most used colors of the pixels that are not
+// transparent, most used first, together with the number of pixels per color.
+// The alpha is not taken into account, and the returned colors are opaque.
+// If n is 0 or less, or larger than the number of colors, all colors are returned.
+func (pi *PixelImage) DominantColors(n int) []ColorCount {
+	frequencies := pi.histogram()
+	if n <= 0 || n > len(frequencies) {
+		n = len(frequencies)
+	}
+	colors := make([]ColorCount, n)
+	for i, f := range frequencies[:n] {
+		colors[i] = ColorCount{color.NRGBA{uint8(f.c[0]), uint8(f.c[1]), uint8(f.c[2]), 0xff}, f.count}
+	}
+	return colors
+}
+
+// MergeLeastUsedColors keeps the n most used colors, and changes the color of
+// all other pixels to the nearest of the kept colors. The distance function
+// that is set with SetDistanceFunc is used, or SquaredEuclidean if none is set.
+// The alpha of each pixel is kept as it is. Returns the number of colors that
+// were merged away.
+func (pi *PixelImage) MergeLeastUsedColors(n int) int {
+	pi.mergeLeast = n
+	frequencies := pi.histogram()
+	if n <= 0 || len(frequencies) <= n {
+		return 0
+	}
+	distance := pi.distance
+	if distance == nil {
+		distance = SquaredEuclidean
+	}
+	kept := frequencies[:n]
+	replacements := make(map[rgb]rgb, len(frequencies)-n)
+	for _, f := range frequencies[n:] {
+		var (
+			best     rgb
+			bestDist = -1.0
+		)
+		for _, k := range kept {
+			if d := distance(f.c[0], f.c[1], f.c[2], k.c[0], k.c[1], k.c[2]); bestDist < 0 || d < bestDist {
+				best, bestDist = k.c, d
+			}
+		}
+		replacements[f.c] = best
+	}
+	for _, p := range pi.pixels {
+		if p.a == 0 {
+			continue
+		}
+		if replacement, ok := replacements[rgb{p.r, p.g, p.b}]; ok {
+			p.r, p.g, p.b = replacement[0], replacement[1], replacement[2]
+		}
+	}
+	if pi.verbose {
+		fmt.Printf("Merged %d colors into the %d most used colors.\n", len(replacements), n)
+	}
+	return len(replacements)
+}
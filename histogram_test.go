@@ -0,0 +1,101 @@
+package png2svg
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestDominantColors(t *testing.T) {
+	img := newTestImage(testPalette,
+		"rrrrb",
+		"rrbbg",
+		"..h.w",
+	)
+	pi := NewPixelImage(img, false)
+	want := []ColorCount{
+		{color.NRGBA{255, 0, 0, 255}, 7}, // includes the half transparent red pixel
+		{color.NRGBA{0, 0, 255, 255}, 3},
+		{color.NRGBA{0, 128, 0, 255}, 1},
+		{color.NRGBA{255, 255, 255, 255}, 1},
+	}
+	for _, n := range []int{0, 2, 4, 10} {
+		got := pi.DominantColors(n)
+		expected := want
+		if n > 0 && n < len(want) {
+			expected = want[:n]
+		}
+		if len(got) != len(expected) {
+			t.Fatalf("DominantColors(%d) returned %d colors, expected %d", n, len(got), len(expected))
+		}
+		for i := range got {
+			if got[i] != expected[i] {
+				t.Errorf("DominantColors(%d)[%d] = %v, expected %v", n, i, got[i], expected[i])
+			}
+		}
+	}
+}
+
+func TestDominantColorsCounts(t *testing.T) {
+	img := noiseImage(40, 30, 7)
+	pi := NewPixelImage(img, false)
+	colors := pi.DominantColors(0)
+	if len(colors) != 7 {
+		t.Fatalf("expected 7 colors, got %d", len(colors))
+	}
+	total := 0
+	for i, c := range colors {
+		total += c.Count
+		if i > 0 && c.Count > colors[i-1].Count {
+			t.Errorf("expected the colors to be sorted by count, got %d after %d", c.Count, colors[i-1].Count)
+		}
+	}
+	if total != 40*30 {
+		t.Errorf("expected the counts to add up to %d pixels, got %d", 40*30, total)
+	}
+	// Colors that are used equally often are returned in the same order every time
+	for i := 0; i < 5; i++ {
+		again := pi.DominantColors(3)
+		for j := range again {
+			if again[j] != colors[j] {
+				t.Fatalf("expected the same colors every time, got %v and %v", again, colors[:3])
+			}
+		}
+	}
+}
+
+func TestMergeLeastUsedColors(t *testing.T) {
+	palette := map[byte]color.NRGBA{
+		'r': {255, 0, 0, 255},
+		'o': {240, 20, 10, 255}, // close to red
+		'b': {0, 0, 255, 255},
+		'n': {10, 10, 200, 255}, // close to blue
+		't': {10, 10, 200, 100}, // close to blue, translucent
+		'.': {0, 0, 0, 0},
+	}
+	img := newTestImage(palette,
+		"rrrbbb",
+		"rrobbn",
+		"..rbt.",
+	)
+	pi := NewPixelImage(img, false)
+	if merged := pi.MergeLeastUsedColors(2); merged != 2 {
+		t.Errorf("expected 2 colors to be merged, got %d", merged)
+	}
+	pi.SetColorFormat(ColorRGBA)
+	pi.Cover()
+	want := newTestImage(palette,
+		"rrrbbb",
+		"rrrbbb",
+		"..rb..",
+	)
+	want.SetNRGBA(4, 2, color.NRGBA{0, 0, 255, 100}) // the alpha is kept
+	checkRendersAs(t, pi.Bytes(), want, 1)
+	if colors := pi.Stats().Colors; colors != 3 {
+		t.Errorf("expected red, blue and translucent blue, got %d colors", colors)
+	}
+
+	// Nothing is merged if there are only n colors
+	if merged := NewPixelImage(want, false).MergeLeastUsedColors(2); merged != 0 {
+		t.Errorf("expected no colors to be merged, got %d", merged)
+	}
+}
@@ -0,0 +1,108 @@
+package png2svg
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Hybrid decides how the pixels at the edges of the transparent regions are covered,
+// when the other pixels are covered with expanding rectangles
+type Hybrid int
+
+const (
+	// HybridOff covers all pixels the same way (the default)
+	HybridOff Hybrid = iota
+	// HybridPixels covers the edges with 1x1 rectangles
+	HybridPixels
+	// HybridRuns covers the edges with one rectangle per horizontal run of pixels with the same color
+	HybridRuns
+)
+
+// String returns the name of the hybrid mode, as used by NewHybrid
+func (hybrid Hybrid) String() string {
+	switch hybrid {
+	case HybridPixels:
+		return "pixels"
+	case HybridRuns:
+		return "runs"
+	default:
+		return "off"
+	}
+}
+
+// ErrUnknownHybrid is returned by NewHybrid if the hybrid mode is not recognized
+var ErrUnknownHybrid = errors.New("unknown hybrid mode, use off, pixels or runs")
+
+// NewHybrid returns a Hybrid, given "off", "pixels" or "runs"
+func NewHybrid(hybrid string) (Hybrid, error) {
+	switch hybrid {
+	case "off", "":
+		return HybridOff, nil
+	case "pixels":
+		return HybridPixels, nil
+	case "runs":
+		return HybridRuns, nil
+	}
+	return HybridOff, ErrUnknownHybrid
+}
+
+// SetHybrid can be used for covering the pixels at the edges of the transparent regions
+// in another way than the rest of the pixels. The edges are the semi-transparent pixels,
+// like the anti-aliased edges of a shape, which seldom have large regions of the same color.
+// The expanding rectangles are then only started at the opaque pixels, and the edges are
+// covered with 1x1 rectangles or horizontal runs afterwards, which keeps them exact.
+// The number of rectangles that are placed at the edges is returned by Stats, for comparing
+// with the other ways of covering the image. Note that the expanding rectangles can already
+// cover most edges with small rectangles, so this seldom places fewer rectangles in total.
+func (pi *PixelImage) SetHybrid(hybrid Hybrid) {
+	pi.hybrid = hybrid
+}
+
+// isEdge returns true if the pixel at the given index is at the edge of a transparent region
+func (pi *PixelImage) isEdge(i int) bool {
+	a := pi.pixels[i].a
+	return a > 0 && a < 255
+}
+
+// hideEdges marks the uncovered edge pixels as covered, so that no expanding rectangles
+// are started there, and returns their indices, for coverEdges
+func (pi *PixelImage) hideEdges() []int {
+	var edges []int
+	for i, p := range pi.pixels {
+		if !p.covered && pi.isEdge(i) {
+			edges = append(edges, i)
+		}
+	}
+	for _, i := range edges {
+		pi.pixels[i].covered = true
+	}
+	return edges
+}
+
+// coverEdges covers the given edge pixels that are not within any of the given rectangles,
+// that were placed after hideEdges was called, with the selected hybrid mode
+func (pi *PixelImage) coverEdges(edges []int, placed []Rect) {
+	within := make([]bool, len(pi.pixels))
+	for _, rect := range placed {
+		for y := rect.Y; y < rect.Y+rect.Height; y++ {
+			for x := rect.X; x < rect.X+rect.Width; x++ {
+				within[y*pi.w+x] = true
+			}
+		}
+	}
+	for _, i := range edges {
+		if !within[i] {
+			pi.pixels[i].covered = false
+		}
+	}
+	before := len(pi.rects)
+	if pi.hybrid == HybridRuns {
+		count := pi.coverRuns()
+		if pi.verbose {
+			fmt.Printf("Covered %d edge pixels with horizontal runs.\n", count)
+		}
+	} else {
+		pi.CoverAllPixels()
+	}
+	pi.edgeRects = len(pi.rects) - before
+}
@@ -0,0 +1,52 @@
+package png2svg
+
+import (
+	"testing"
+)
+
+func TestNewHybrid(t *testing.T) {
+	for _, hybrid := range []Hybrid{HybridOff, HybridPixels, HybridRuns} {
+		got, err := NewHybrid(hybrid.String())
+		if err != nil || got != hybrid {
+			t.Errorf("NewHybrid(%q) = %v, %v, expected %v", hybrid.String(), got, err, hybrid)
+		}
+	}
+	if got, err := NewHybrid(""); got != HybridOff || err != nil {
+		t.Errorf("expected an empty string to give HybridOff, got %v, %v", got, err)
+	}
+	if _, err := NewHybrid("edges"); err != ErrUnknownHybrid {
+		t.Errorf("expected ErrUnknownHybrid, got %v", err)
+	}
+}
+
+func TestHybrid(t *testing.T) {
+	img := newTestImage(testPalette,
+		"rrrrb",
+		"hhhrb",
+		"hh.rb",
+	)
+	for _, tc := range []struct {
+		hybrid    Hybrid
+		edgeRects int
+	}{
+		{HybridOff, 0},
+		{HybridPixels, 5},
+		{HybridRuns, 2},
+	} {
+		pi := NewPixelImage(img, false)
+		pi.SetOptions(Options{Hybrid: tc.hybrid, ColorFormat: ColorRGBA})
+		pi.Cover()
+		r := checkRendersAs(t, pi.Bytes(), img, 1)
+		if got := pi.Stats().EdgeRects; got != tc.edgeRects {
+			t.Errorf("%v: expected %d rectangles at the edges, got %d", tc.hybrid, tc.edgeRects, got)
+		}
+		// The translucent edges are painted exactly once
+		for y := 1; y < 3; y++ {
+			for x := 0; x < 3; x++ {
+				if img.NRGBAAt(x, y).A > 0 && r.Painted(x, y) != 1 {
+					t.Errorf("%v: the edge pixel (%d, %d) is painted %d times", tc.hybrid, x, y, r.Painted(x, y))
+				}
+			}
+		}
+	}
+}
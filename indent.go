@@ -0,0 +1,93 @@
+package png2svg
+
+import (
+	"bytes"
+	"io"
+)
+
+// indentSVG returns the given SVG document with one tag per line, where each
+// tag is indented with the given indentation string, once per nesting level.
+// Tags that only contain text, like <title>, are kept on one line.
+func indentSVG(svgDocument []byte, indent string) []byte {
+	var (
+		out      bytes.Buffer
+		depth    int
+		pos      int
+		lastOpen bool // if the previous token was an opening tag, with no text or tags after it
+		hadText  bool // if there was text after the previous tag
+	)
+	newline := func() {
+		if out.Len() > 0 {
+			out.WriteByte('\n')
+		}
+		for i := 0; i < depth; i++ {
+			out.WriteString(indent)
+		}
+	}
+	for pos < len(svgDocument) {
+		tagStart := bytes.IndexByte(svgDocument[pos:], '<')
+		if tagStart == -1 {
+			out.Write(bytes.TrimSpace(svgDocument[pos:]))
+			break
+		}
+		if text := bytes.TrimSpace(svgDocument[pos : pos+tagStart]); len(text) > 0 {
+			out.Write(text)
+			hadText = true
+		}
+		tagStart += pos
+		tagEnd := bytes.IndexByte(svgDocument[tagStart:], '>')
+		if tagEnd == -1 {
+			out.Write(svgDocument[tagStart:])
+			break
+		}
+		tagEnd += tagStart + 1
+		tag := svgDocument[tagStart:tagEnd]
+		pos = tagEnd
+		switch {
+		case bytes.HasPrefix(tag, []byte("</")):
+			depth--
+			if !(lastOpen || hadText) {
+				newline()
+			}
+			out.Write(tag)
+			lastOpen = false
+		case bytes.HasPrefix(tag, []byte("<?")), bytes.HasPrefix(tag, []byte("<!")), bytes.HasSuffix(tag, []byte("/>")):
+			newline()
+			out.Write(tag)
+			lastOpen = false
+		default:
+			newline()
+			out.Write(tag)
+			depth++
+			lastOpen = true
+		}
+		hadText = false
+	}
+	out.WriteByte('\n')
+	return out.Bytes()
+}
+
+// WriteSVGIndentedTo will write the current SVG document to the given io.Writer,
+// with one tag per line, indented with the given indentation string.
+// The line endings are CRLF if SetCRLF has been used.
+func (pi *PixelImage) WriteSVGIndentedTo(w io.Writer, indent string) error {
+	if !pi.Done(0, 0) {
+		return ErrNotCovered
+	}
+	_, err := w.Write(pi.encode(indentSVG(pi.Bytes(), indent)))
+	return err
+}
+
+// WriteSVGIndented will save the current SVG document to a file, or write it
+// to stdout if filename is "-", with one tag per line, indented with the given
+// indentation string
+func (pi *PixelImage) WriteSVGIndented(filename, indent string) error {
+	if !pi.Done(0, 0) {
+		return ErrNotCovered
+	}
+	if filename == "-" {
+		// Turn off verbose messages, so that they don't end up in the SVG output
+		pi.verbose = false
+	}
+	return writeFile(filename, pi.encode(indentSVG(pi.Bytes(), indent)))
+}
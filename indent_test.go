@@ -0,0 +1,49 @@
+package png2svg
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestIndentSVG(t *testing.T) {
+	for _, tc := range []struct {
+		svgDocument string
+		want        string
+	}{
+		{
+			`<?xml version="1.0"?><svg><g fill="red"><rect/><rect x="1"/></g></svg>`,
+			"<?xml version=\"1.0\"?>\n<svg>\n  <g fill=\"red\">\n    <rect/>\n    <rect x=\"1\"/>\n  </g>\n</svg>\n",
+		},
+		{
+			`<svg><g><title>#ff0000</title><rect/></g><metadata>v1</metadata></svg>`,
+			"<svg>\n  <g>\n    <title>#ff0000</title>\n    <rect/>\n  </g>\n  <metadata>v1</metadata>\n</svg>\n",
+		},
+		{
+			`<svg><g></g></svg>`,
+			"<svg>\n  <g></g>\n</svg>\n",
+		},
+	} {
+		if got := string(indentSVG([]byte(tc.svgDocument), "  ")); got != tc.want {
+			t.Errorf("indentSVG(%q) =\n%s\nexpected\n%s", tc.svgDocument, got, tc.want)
+		}
+	}
+}
+
+func TestWriteSVGIndentedTo(t *testing.T) {
+	img := newTestImage(testPalette, "rrb", "gg.")
+	pi := NewPixelImage(img, false)
+	pi.SetTooltips(true)
+	pi.Cover()
+	var buf bytes.Buffer
+	if err := pi.WriteSVGIndentedTo(&buf, "\t"); err != nil {
+		t.Fatal(err)
+	}
+	checkRendersAs(t, buf.Bytes(), img, 0)
+	if !bytes.Contains(buf.Bytes(), []byte("\n\t<")) {
+		t.Errorf("expected the tags to be indented with tabs:\n%s", buf.Bytes())
+	}
+	// The indented document has the same tags as the compact document
+	if compact := pi.Bytes(); !bytes.Equal(bytes.TrimSpace(trimSpaceBetweenTags(buf.Bytes())), bytes.TrimSpace(compact)) {
+		t.Errorf("expected the same tags as in\n%s\ngot\n%s", compact, buf.Bytes())
+	}
+}
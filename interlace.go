@@ -0,0 +1,76 @@
+package png2svg
+
+import (
+	"bytes"
+)
+
+// adam7 is the pass number (1 to 7) of each pixel within an 8x8 block, as used by interlaced PNG images
+var adam7 = [8][8]int{
+	{1, 6, 4, 6, 2, 6, 4, 6},
+	{7, 7, 7, 7, 7, 7, 7, 7},
+	{5, 6, 5, 6, 5, 6, 5, 6},
+	{7, 7, 7, 7, 7, 7, 7, 7},
+	{3, 6, 4, 6, 3, 6, 4, 6},
+	{7, 7, 7, 7, 7, 7, 7, 7},
+	{5, 6, 5, 6, 5, 6, 5, 6},
+	{7, 7, 7, 7, 7, 7, 7, 7},
+}
+
+// SetInterlace can be used for ordering the rectangles like the passes of an interlaced
+// PNG image (Adam7), so that a browser that renders a large SVG image while it is being
+// loaded shows a coarse preview first, which is then refined. Each rectangle belongs to
+// the earliest pass of the pixels it covers, so large rectangles come early.
+// Within each pass, the rectangles are grouped by color. This only changes how the
+// image looks while it is partially loaded, not how the complete image looks.
+func (pi *PixelImage) SetInterlace(enabled bool) {
+	pi.interlace = enabled
+}
+
+// interlacePass returns the earliest Adam7 pass of the pixels that are covered by the given rectangle
+func interlacePass(rect Rect) int {
+	pass := 7
+	for y := rect.Y; y < rect.Y+rect.Height && y < rect.Y+8; y++ {
+		for x := rect.X; x < rect.X+rect.Width && x < rect.X+8; x++ {
+			if p := adam7[y%8][x%8]; p < pass {
+				pass = p
+			}
+		}
+	}
+	return pass
+}
+
+// writeInterlaced writes the rectangles in the order of the Adam7 passes,
+// grouped by color within each pass
+func (pi *PixelImage) writeInterlaced(buf *bytes.Buffer) {
+	var passes [8]map[string][]Rect
+	for _, rect := range pi.rects {
+		pass := interlacePass(rect)
+		if passes[pass] == nil {
+			passes[pass] = make(map[string][]Rect)
+		}
+		passes[pass][rect.Fill] = append(passes[pass][rect.Fill], rect)
+	}
+	for _, byFill := range passes {
+		for i, group := range pi.groupOrder {
+			rects := byFill[string(group.fill)]
+			switch len(rects) {
+			case 0:
+				continue
+			case 1:
+				// Insert the fill attribute before the closing "/>"
+				pi.writeRect(buf, rects[0].X, rects[0].Y, rects[0].Width, rects[0].Height)
+				buf.Truncate(buf.Len() - 2)
+				pi.writeFill(buf, i, group.fill)
+				buf.WriteString("\"/>")
+				continue
+			}
+			buf.WriteString("<g")
+			pi.writeFill(buf, i, group.fill)
+			buf.WriteString("\">")
+			for _, rect := range rects {
+				pi.writeRect(buf, rect.X, rect.Y, rect.Width, rect.Height)
+			}
+			buf.WriteString("</g>")
+		}
+	}
+}
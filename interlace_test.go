@@ -0,0 +1,68 @@
+package png2svg
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+)
+
+func TestInterlacePass(t *testing.T) {
+	for _, tc := range []struct {
+		rect Rect
+		want int
+	}{
+		{Rect{X: 0, Y: 0, Width: 1, Height: 1}, 1},
+		{Rect{X: 8, Y: 8, Width: 1, Height: 1}, 1},
+		{Rect{X: 4, Y: 0, Width: 1, Height: 1}, 2},
+		{Rect{X: 1, Y: 1, Width: 1, Height: 1}, 7},
+		{Rect{X: 1, Y: 0, Width: 1, Height: 1}, 6},
+		{Rect{X: 1, Y: 1, Width: 2, Height: 2}, 5},
+		{Rect{X: 1, Y: 1, Width: 4, Height: 4}, 3},
+		{Rect{X: 1, Y: 1, Width: 40, Height: 1}, 7},
+		{Rect{X: 5, Y: 3, Width: 8, Height: 8}, 1},
+	} {
+		if got := interlacePass(tc.rect); got != tc.want {
+			t.Errorf("interlacePass(%v) = %d, expected %d", tc.rect, got, tc.want)
+		}
+	}
+}
+
+func TestInterlace(t *testing.T) {
+	img := noiseImage(24, 16, 3)
+	flat := convertBytes(t, img, Options{})
+	svgDocument := convertBytes(t, img, Options{Interlace: true})
+	checkRendersAs(t, svgDocument, img, 0)
+
+	var root svgNode
+	if err := xml.Unmarshal(svgDocument, &root); err != nil {
+		t.Fatal(err)
+	}
+	var rects []*svgNode
+	for _, child := range root.Children {
+		if child.XMLName.Local == "g" {
+			rects = append(rects, child.Children...)
+		} else {
+			rects = append(rects, child)
+		}
+	}
+	if want := bytes.Count(flat, []byte("<rect")); len(rects) != want {
+		t.Errorf("expected the same %d rectangles as without interlacing, got %d", want, len(rects))
+	}
+	// The passes are written from coarse to fine
+	lastPass := 1
+	for _, rect := range rects {
+		pass := interlacePass(Rect{
+			X:      int(rect.number(t, "x", 0)),
+			Y:      int(rect.number(t, "y", 0)),
+			Width:  int(rect.number(t, "width", 1)),
+			Height: int(rect.number(t, "height", 1)),
+		})
+		if pass < lastPass {
+			t.Fatalf("a rectangle in pass %d comes after pass %d", pass, lastPass)
+		}
+		lastPass = pass
+	}
+	if lastPass == 1 {
+		t.Error("expected the rectangles to be spread over several passes")
+	}
+}
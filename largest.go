@@ -0,0 +1,225 @@
+package png2svg
+
+import (
+	"container/heap"
+	"fmt"
+)
+
+// candidate is a rectangle of uncovered pixels that all have the same color
+type candidate struct {
+	x, y, w, h int
+}
+
+// candidateHeap is a max-heap of candidates, with the largest area first.
+// Candidates with the same area are ordered by position, row by row, and then
+// by width, so that the order is deterministic.
+type candidateHeap []candidate
+
+func (ch candidateHeap) Len() int { return len(ch) }
+
+func (ch candidateHeap) Less(i, j int) bool {
+	a, b := ch[i], ch[j]
+	if a.w*a.h != b.w*b.h {
+		return a.w*a.h > b.w*b.h
+	}
+	if a.y != b.y {
+		return a.y < b.y
+	}
+	if a.x != b.x {
+		return a.x < b.x
+	}
+	return a.w > b.w
+}
+
+func (ch candidateHeap) Swap(i, j int) { ch[i], ch[j] = ch[j], ch[i] }
+
+func (ch *candidateHeap) Push(x interface{}) { *ch = append(*ch, x.(candidate)) }
+
+func (ch *candidateHeap) Pop() interface{} {
+	old := *ch
+	c := old[len(old)-1]
+	*ch = old[:len(old)-1]
+	return c
+}
+
+// maximalRects returns the rectangles of uncovered pixels with the same color,
+// within the given region, that can not be extended in any direction within the region.
+// For each row, the number of uncovered pixels with the same color above each pixel
+// is used as a histogram, and the largest rectangles in the histogram are found with a stack.
+func (pi *PixelImage) maximalRects(x0, y0, w, h int) []candidate {
+	var (
+		rects   []candidate
+		heights = make([]int, w)
+		stack   []int
+	)
+	for y := y0; y < y0+h; y++ {
+		for i := range heights {
+			p := pi.pixels[y*pi.w+x0+i]
+			switch {
+			case p.covered:
+				heights[i] = 0
+			case y > y0 && pi.samePixel(y*pi.w+x0+i, (y-1)*pi.w+x0+i):
+				heights[i]++
+			default:
+				heights[i] = 1
+			}
+		}
+		// Find the largest rectangles within each run of pixels with the same color
+		for start := 0; start < w; {
+			end := start + 1
+			for end < w && heights[start] > 0 && heights[end] > 0 && pi.samePixel(y*pi.w+x0+start, y*pi.w+x0+end) {
+				end++
+			}
+			stack = stack[:0]
+			for i := start; i <= end; i++ {
+				current := 0
+				if i < end {
+					current = heights[i]
+				}
+				for len(stack) > 0 && heights[stack[len(stack)-1]] >= current {
+					top := heights[stack[len(stack)-1]]
+					stack = stack[:len(stack)-1]
+					left := start
+					if len(stack) > 0 {
+						left = stack[len(stack)-1] + 1
+					}
+					if top > 0 {
+						rects = append(rects, candidate{x0 + left, y - top + 1, i - left, top})
+					}
+				}
+				stack = append(stack, i)
+			}
+			start = end
+		}
+	}
+	return rects
+}
+
+// uncoveredRect returns true if none of the pixels within the given candidate are covered
+func (pi *PixelImage) uncoveredRect(c candidate) bool {
+	for y := c.y; y < c.y+c.h; y++ {
+		for x := c.x; x < c.x+c.w; x++ {
+			if pi.pixels[y*pi.w+x].covered {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// splitToAspect splits the given candidate in two, if it is more elongated than the
+// maximum aspect ratio. The first part is as long as the maximum aspect ratio allows.
+func (pi *PixelImage) splitToAspect(c candidate) []candidate {
+	if pi.withinAspect(c.w, c.h) {
+		return []candidate{c}
+	}
+	if c.w > c.h {
+		n := int(pi.maxAspect * float64(c.h))
+		if n < 1 {
+			n = 1
+		}
+		return []candidate{{c.x, c.y, n, c.h}, {c.x + n, c.y, c.w - n, c.h}}
+	}
+	n := int(pi.maxAspect * float64(c.w))
+	if n < 1 {
+		n = 1
+	}
+	return []candidate{{c.x, c.y, c.w, n}, {c.x, c.y + n, c.w, c.h - n}}
+}
+
+// largestBoxes marks all uncovered pixels as covered, by repeatedly covering the
+// largest rectangle of uncovered pixels with the same color, and returns the boxes.
+// All maximal rectangles are found first. When a rectangle turns out to be partially
+// covered, the maximal rectangles within it are found again, so that the largest
+// available rectangle is always the next one to be covered. Rectangles that are
+// more elongated than the maximum aspect ratio are split up.
+func (pi *PixelImage) largestBoxes() []*Box {
+	var boxes []*Box
+	candidates := candidateHeap(pi.maximalRects(0, 0, pi.w, pi.h))
+	heap.Init(&candidates)
+	for candidates.Len() > 0 && !pi.pastDeadline() {
+		c := heap.Pop(&candidates).(candidate)
+		if !pi.uncoveredRect(c) {
+			for _, r := range pi.maximalRects(c.x, c.y, c.w, c.h) {
+				heap.Push(&candidates, r)
+			}
+			continue
+		}
+		if parts := pi.splitToAspect(c); len(parts) > 1 {
+			for _, part := range parts {
+				heap.Push(&candidates, part)
+			}
+			continue
+		}
+		r, g, b, a := pi.At2(c.x, c.y)
+		boxes = append(boxes, &Box{c.x, c.y, c.w, c.h, r, g, b, a})
+		pi.markCovered(c.x, c.y, c.w, c.h)
+	}
+	return boxes
+}
+
+// rowBoxes marks all uncovered pixels as covered, like CoverBoxes does with OrderRow,
+// and returns the boxes
+func (pi *PixelImage) rowBoxes() []*Box {
+	var boxes []*Box
+	for i, p := range pi.pixels {
+		if pi.pastDeadline() {
+			break
+		}
+		if p.covered {
+			continue
+		}
+		box := pi.CreateBox(i%pi.w, i/pi.w)
+		pi.Expand(box)
+		boxes = append(boxes, box)
+		pi.markCovered(box.x, box.y, box.w, box.h)
+	}
+	return boxes
+}
+
+// markCovered marks the pixels within the given rectangle as covered
+func (pi *PixelImage) markCovered(x0, y0, w, h int) {
+	for y := y0; y < y0+h; y++ {
+		for x := x0; x < x0+w; x++ {
+			pi.pixels[y*pi.w+x].covered = true
+		}
+	}
+}
+
+// coverLargestFirst covers all pixels that are not yet covered, by repeatedly
+// covering the largest rectangle of uncovered pixels with the same color.
+// Covering the largest rectangles first can leave the remaining pixels more
+// fragmented, so the rectangles that OrderRow would place are also found, and
+// whichever order places fewer rectangles is used. Both counts are returned by Stats.
+// If pink is true, the rectangles that are larger than 1x1 will be pink.
+func (pi *PixelImage) coverLargestFirst(pink bool) {
+	if pi.verbose {
+		fmt.Print("Placing the largest rectangles first...")
+	}
+	covered := make([]bool, len(pi.pixels))
+	for i, p := range pi.pixels {
+		covered[i] = p.covered
+	}
+	restore := func() {
+		for i, p := range pi.pixels {
+			p.covered = covered[i]
+		}
+	}
+	boxes := pi.largestBoxes()
+	restore()
+	rowBoxes := pi.rowBoxes()
+	restore()
+	pi.largestRects, pi.rowRects = len(boxes), len(rowBoxes)
+	if len(rowBoxes) < len(boxes) {
+		boxes = rowBoxes
+	}
+	for _, box := range boxes {
+		if pi.TooManyRects() {
+			break
+		}
+		pi.CoverBox(box, pink && box.w*box.h > 1, pi.colorOptimize)
+	}
+	if pi.verbose {
+		fmt.Println("ok")
+	}
+}
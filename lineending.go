@@ -0,0 +1,38 @@
+package png2svg
+
+import (
+	"bytes"
+)
+
+// utf8BOM is the byte order mark that some Windows tools expect at the start of UTF-8 files
+var utf8BOM = []byte{0xef, 0xbb, 0xbf}
+
+// SetCRLF can be used for writing CRLF line endings instead of LF line endings,
+// when the SVG document is written with one tag per line. The default is LF.
+func (pi *PixelImage) SetCRLF(enabled bool) {
+	pi.crlf = enabled
+}
+
+// SetBOM can be used for writing a UTF-8 byte order mark before the XML declaration,
+// for SVG tools that require one. The default is to not write a byte order mark.
+func (pi *PixelImage) SetBOM(enabled bool) {
+	pi.bom = enabled
+}
+
+// encode applies the line endings and byte order mark to a rendered SVG document,
+// right before it is written
+func (pi *PixelImage) encode(svgDocument []byte) []byte {
+	return encode(svgDocument, pi.crlf, pi.bom)
+}
+
+// encode applies the given line endings and byte order mark to a rendered SVG document,
+// also for the documents that are put together from several PixelImages
+func encode(svgDocument []byte, crlf, bom bool) []byte {
+	if crlf {
+		svgDocument = bytes.Replace(svgDocument, []byte("\n"), []byte("\r\n"), -1)
+	}
+	if bom {
+		svgDocument = append(append([]byte{}, utf8BOM...), svgDocument...)
+	}
+	return svgDocument
+}
@@ -0,0 +1,91 @@
+package png2svg
+
+import (
+	"bytes"
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	for _, tc := range []struct {
+		crlf, bom bool
+		want      string
+	}{
+		{false, false, "<svg>\n<rect/>\n</svg>\n"},
+		{true, false, "<svg>\r\n<rect/>\r\n</svg>\r\n"},
+		{false, true, "\xef\xbb\xbf<svg>\n<rect/>\n</svg>\n"},
+		{true, true, "\xef\xbb\xbf<svg>\r\n<rect/>\r\n</svg>\r\n"},
+	} {
+		pi := &PixelImage{}
+		pi.SetCRLF(tc.crlf)
+		pi.SetBOM(tc.bom)
+		if got := string(pi.encode([]byte("<svg>\n<rect/>\n</svg>\n"))); got != tc.want {
+			t.Errorf("crlf %v, bom %v: expected %q, got %q", tc.crlf, tc.bom, tc.want, got)
+		}
+	}
+}
+
+func TestWriteIndentedWithCRLFAndBOM(t *testing.T) {
+	img := newTestImage(testPalette, "rb", "bb")
+	pi := NewPixelImage(img, false)
+	pi.SetCRLF(true)
+	pi.SetBOM(true)
+	pi.Cover()
+	var buf bytes.Buffer
+	if err := pi.WriteSVGIndentedTo(&buf, "  "); err != nil {
+		t.Fatal(err)
+	}
+	svgDocument := buf.Bytes()
+	if !bytes.HasPrefix(svgDocument, utf8BOM) {
+		t.Errorf("expected a byte order mark first in %q", svgDocument)
+	}
+	if lines, crlfs := bytes.Count(svgDocument, []byte("\n")), bytes.Count(svgDocument, []byte("\r\n")); lines < 3 || lines != crlfs {
+		t.Errorf("expected only CRLF line endings, got %d CRLF of %d line endings", crlfs, lines)
+	}
+	checkRendersAs(t, bytes.TrimPrefix(svgDocument, utf8BOM), img, 0)
+}
+
+func TestWriteCombinedWithCRLFAndBOM(t *testing.T) {
+	dir, err := ioutil.TempDir("", "png2svg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	img := newTestImage(testPalette, "rb", "bb")
+	opts := Options{CRLF: true, BOM: true, Provenance: true}
+	for name, write := range map[string]func(string) (string, error){
+		"band": func(filename string) (string, error) {
+			return filename, WriteBandedSVG(filename, img, opts, 1)
+		},
+		"animate": func(filename string) (string, error) {
+			return filename, WriteAnimatedSVG(filename, []image.Image{img}, opts, 1)
+		},
+		"pyramid": func(filename string) (string, error) {
+			return filepath.Join(filename, "0", "0_0.svg"), WritePyramid(img, opts, filename, 1, 2)
+		},
+	} {
+		filename, err := write(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		svgDocument, err := ioutil.ReadFile(filename)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(svgDocument, utf8BOM) {
+			t.Errorf("%s: expected a byte order mark first in %q", name, svgDocument)
+		}
+		if lines, crlfs := bytes.Count(svgDocument, []byte("\n")), bytes.Count(svgDocument, []byte("\r\n")); lines != crlfs {
+			t.Errorf("%s: expected only CRLF line endings, got %d CRLF of %d line endings", name, crlfs, lines)
+		}
+		svgContains(t, svgDocument, "<metadata>"+VersionString+"; "+opts.String()+"</metadata>")
+		r := renderSVG(t, bytes.TrimPrefix(svgDocument, utf8BOM))
+		if r.At(0, 0) != testPalette['r'] || r.At(1, 1) != testPalette['b'] {
+			t.Errorf("%s: expected the image to be drawn, got %s", name, svgDocument)
+		}
+	}
+}
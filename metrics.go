@@ -0,0 +1,150 @@
+package png2svg
+
+import (
+	"bytes"
+	"image"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// metricsPhases are the phases of a conversion that are measured by Metrics.
+// "total" is the time spent on the whole conversion.
+var metricsPhases = []string{"pixels", "cover", "serialize", "total"}
+
+// metricsBuckets are the upper bounds of the buckets of the duration histograms, in seconds
+var metricsBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60}
+
+// durationHistogram counts how many durations that are within each bucket
+type durationHistogram struct {
+	buckets []uint64 // the number of durations per bucket, not cumulative
+	count   uint64
+	sum     float64 // the sum of all durations, in seconds
+}
+
+// observe adds the given duration to the histogram
+func (h *durationHistogram) observe(d time.Duration) {
+	seconds := d.Seconds()
+	for i, upper := range metricsBuckets {
+		if seconds <= upper {
+			h.buckets[i]++
+			break
+		}
+	}
+	h.count++
+	h.sum += seconds
+}
+
+// Metrics counts conversions and measures how long each phase of a conversion takes,
+// for monitoring png2svg when it is used in a long-running service. The metrics can be
+// written in the Prometheus text format with WriteTo, and Metrics is also an http.Handler
+// that can be used for a /metrics endpoint. No Prometheus packages are needed.
+//
+// These metrics are written:
+//
+//	png2svg_conversions_total       counter, with the label result="ok" or "error"
+//	png2svg_phase_duration_seconds  histogram, with the label phase="pixels", "cover", "serialize" or "total"
+//	png2svg_output_bytes_total      counter
+//
+// Metrics can be used from several goroutines at once.
+type Metrics struct {
+	mu          sync.Mutex
+	ok          uint64
+	errors      uint64
+	outputBytes uint64
+	phases      []durationHistogram // one per phase, in the order of metricsPhases
+}
+
+// NewMetrics creates a new Metrics, where all counters are zero
+func NewMetrics() *Metrics {
+	phases := make([]durationHistogram, len(metricsPhases))
+	for i := range phases {
+		phases[i].buckets = make([]uint64, len(metricsBuckets))
+	}
+	return &Metrics{phases: phases}
+}
+
+// Convert converts the given image with the given options, like the Convert function,
+// and returns the SVG document. The duration of each phase, the size of the SVG document
+// and whether the conversion succeeded are recorded. The durations of failed conversions
+// are not recorded, since they would skew the histograms.
+func (m *Metrics) Convert(img image.Image, opts Options) ([]byte, error) {
+	if img == nil {
+		m.observeError()
+		return nil, ErrNoImage
+	}
+	start := time.Now()
+	pi := NewPixelImage(img, false)
+	pi.SetOptions(opts)
+	pixelsDone := time.Now()
+	pi.Cover()
+	if pi.TooManyRects() {
+		m.observeError()
+		return nil, ErrTooManyRects
+	}
+	if !pi.Done(0, 0) {
+		m.observeError()
+		return nil, ErrNotCovered
+	}
+	coverDone := time.Now()
+	svgDocument := pi.Bytes()
+	serializeDone := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.ok++
+	m.outputBytes += uint64(len(svgDocument))
+	for i, d := range []time.Duration{pixelsDone.Sub(start), coverDone.Sub(pixelsDone), serializeDone.Sub(coverDone), serializeDone.Sub(start)} {
+		m.phases[i].observe(d)
+	}
+	return svgDocument, nil
+}
+
+// observeError counts a failed conversion
+func (m *Metrics) observeError() {
+	m.mu.Lock()
+	m.errors++
+	m.mu.Unlock()
+}
+
+// formatSeconds formats a number of seconds as a Prometheus float
+func formatSeconds(seconds float64) string {
+	return strconv.FormatFloat(seconds, 'g', -1, 64)
+}
+
+// WriteTo writes the metrics to w, in the Prometheus text format
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	m.mu.Lock()
+	buf.WriteString("# HELP png2svg_conversions_total Number of conversions, by result.\n")
+	buf.WriteString("# TYPE png2svg_conversions_total counter\n")
+	buf.WriteString("png2svg_conversions_total{result=\"ok\"} " + strconv.FormatUint(m.ok, 10) + "\n")
+	buf.WriteString("png2svg_conversions_total{result=\"error\"} " + strconv.FormatUint(m.errors, 10) + "\n")
+	buf.WriteString("# HELP png2svg_phase_duration_seconds Time spent in each phase of the successful conversions.\n")
+	buf.WriteString("# TYPE png2svg_phase_duration_seconds histogram\n")
+	for i, phase := range metricsPhases {
+		h := m.phases[i]
+		label := "phase=\"" + phase + "\""
+		var cumulative uint64
+		for j, upper := range metricsBuckets {
+			cumulative += h.buckets[j]
+			buf.WriteString("png2svg_phase_duration_seconds_bucket{" + label + ",le=\"" + formatSeconds(upper) + "\"} " + strconv.FormatUint(cumulative, 10) + "\n")
+		}
+		buf.WriteString("png2svg_phase_duration_seconds_bucket{" + label + ",le=\"+Inf\"} " + strconv.FormatUint(h.count, 10) + "\n")
+		buf.WriteString("png2svg_phase_duration_seconds_sum{" + label + "} " + formatSeconds(h.sum) + "\n")
+		buf.WriteString("png2svg_phase_duration_seconds_count{" + label + "} " + strconv.FormatUint(h.count, 10) + "\n")
+	}
+	buf.WriteString("# HELP png2svg_output_bytes_total Total size of the SVG documents that have been created, in bytes.\n")
+	buf.WriteString("# TYPE png2svg_output_bytes_total counter\n")
+	buf.WriteString("png2svg_output_bytes_total " + strconv.FormatUint(m.outputBytes, 10) + "\n")
+	m.mu.Unlock()
+	return buf.WriteTo(w)
+}
+
+// ServeHTTP writes the metrics in the Prometheus text format, for a /metrics endpoint
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
@@ -0,0 +1,132 @@
+package png2svg
+
+import (
+	"bufio"
+	"bytes"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// parseMetrics returns the value of each sample in the Prometheus text format,
+// by the metric name and labels
+func parseMetrics(t *testing.T, text string) map[string]float64 {
+	t.Helper()
+	samples := make(map[string]float64)
+	scanner := bufio.NewScanner(strings.NewReader(text))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndex(line, " ")
+		value, err := strconv.ParseFloat(line[i+1:], 64)
+		if err != nil {
+			t.Fatalf("invalid sample %q: %v", line, err)
+		}
+		samples[line[:i]] = value
+	}
+	return samples
+}
+
+func TestDurationHistogram(t *testing.T) {
+	h := durationHistogram{buckets: make([]uint64, len(metricsBuckets))}
+	for _, d := range []time.Duration{time.Millisecond, 5 * time.Millisecond, 20 * time.Millisecond, 2 * time.Minute} {
+		h.observe(d)
+	}
+	// 1ms and 5ms are in the first bucket, 20ms in the third, 2 minutes in none
+	want := make([]uint64, len(metricsBuckets))
+	want[0], want[2] = 2, 1
+	for i := range want {
+		if h.buckets[i] != want[i] {
+			t.Errorf("bucket %d (le=%g): expected %d, got %d", i, metricsBuckets[i], want[i], h.buckets[i])
+		}
+	}
+	if h.count != 4 {
+		t.Errorf("expected the count 4, got %d", h.count)
+	}
+	if want := 120.026; h.sum < want-1e-9 || h.sum > want+1e-9 {
+		t.Errorf("expected the sum %g, got %g", want, h.sum)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	m := NewMetrics()
+	img := newTestImage(testPalette, "rrb", ".gg")
+	var (
+		wg          sync.WaitGroup
+		mu          sync.Mutex
+		outputBytes int
+	)
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			svgDocument, err := m.Convert(img, Options{})
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			mu.Lock()
+			outputBytes += len(svgDocument)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	if _, err := m.Convert(nil, Options{}); err != ErrNoImage {
+		t.Errorf("expected ErrNoImage, got %v", err)
+	}
+	if _, err := m.Convert(noiseImage(8, 8, 4), Options{MaxRects: 2}); err != ErrTooManyRects {
+		t.Errorf("expected ErrTooManyRects, got %v", err)
+	}
+
+	var buf bytes.Buffer
+	n, err := m.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != int64(buf.Len()) {
+		t.Errorf("expected WriteTo to return %d, got %d", buf.Len(), n)
+	}
+	samples := parseMetrics(t, buf.String())
+	for name, want := range map[string]float64{
+		`png2svg_conversions_total{result="ok"}`:                         4,
+		`png2svg_conversions_total{result="error"}`:                      2,
+		`png2svg_output_bytes_total`:                                     float64(outputBytes),
+		`png2svg_phase_duration_seconds_count{phase="total"}`:            4,
+		`png2svg_phase_duration_seconds_bucket{phase="cover",le="+Inf"}`: 4,
+	} {
+		if got, ok := samples[name]; !ok || got != want {
+			t.Errorf("expected %s to be %g, got %g", name, want, got)
+		}
+	}
+	for _, phase := range metricsPhases {
+		label := `phase="` + phase + `"`
+		last := 0.0
+		for _, upper := range metricsBuckets {
+			got, ok := samples["png2svg_phase_duration_seconds_bucket{"+label+`,le="`+formatSeconds(upper)+`"}`]
+			if !ok || got < last {
+				t.Fatalf("%s: expected cumulative buckets, got %g after %g", phase, got, last)
+			}
+			last = got
+		}
+		if samples["png2svg_phase_duration_seconds_count{"+label+"}"] != 4 {
+			t.Errorf("%s: expected the successful conversions to be counted", phase)
+		}
+		if _, ok := samples["png2svg_phase_duration_seconds_sum{"+label+"}"]; !ok {
+			t.Errorf("%s: expected a sum", phase)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, "text/plain; version=0.0.4") {
+		t.Errorf("expected the Prometheus text format, got %q", got)
+	}
+	if rec.Body.String() != buf.String() {
+		t.Errorf("expected the same metrics from ServeHTTP, got:\n%s", rec.Body.String())
+	}
+}
@@ -0,0 +1,166 @@
+package png2svg
+
+import (
+	"bytes"
+)
+
+// MinifyOptions contains the settings that are used by Optimize
+type MinifyOptions struct {
+	// GroupByColor is for grouping the rectangles by fill color, within <g> tags
+	GroupByColor bool
+	// ColorOptimize is for using only 4096 colors (short hex color strings, like #fff)
+	ColorOptimize bool
+	// OpacityDecimals is the largest number of decimals for opacity attributes and
+	// the alpha of rgba colors. 0 leaves the opacities as they are, unless
+	// OmitLeadingZero is set, in which case 3 decimals are used.
+	OpacityDecimals int
+	// OmitLeadingZero is for writing opacities like ".5" instead of "0.5", which is
+	// shorter, but not supported by all SVG parsers
+	OmitLeadingZero bool
+}
+
+// fillAttribute returns the value of the fill attribute of the given tag,
+// and the tag without the fill attribute. Returns false if there is no fill attribute.
+func fillAttribute(tag []byte) ([]byte, []byte, bool) {
+	start := bytes.Index(tag, []byte(" fill=\""))
+	if start == -1 {
+		return nil, tag, false
+	}
+	valueStart := start + len(" fill=\"")
+	valueLength := bytes.IndexByte(tag[valueStart:], '"')
+	if valueLength == -1 {
+		return nil, tag, false
+	}
+	fill := tag[valueStart : valueStart+valueLength]
+	withoutFill := append(append([]byte{}, tag[:start]...), tag[valueStart+valueLength+1:]...)
+	return fill, withoutFill, true
+}
+
+// groupRects groups all self-closing <rect> tags with a fill attribute, that
+// are placed directly within the <svg> tag, by their fill color.
+// The groups are inserted where the first of these rectangles was.
+// This assumes that the rectangles do not overlap, since the order they are
+// drawn in may change.
+func groupRects(svgDocument []byte, colorOptimize bool) []byte {
+	var (
+		pi          = &PixelImage{groups: make(map[string]*colorGroup), colorOptimize: colorOptimize}
+		out         bytes.Buffer
+		insertAt    = -1
+		depth       int
+		pos         int
+		tagStart    int
+		tagEnd      int
+		selfClosing bool
+	)
+	for {
+		tagStart = bytes.IndexByte(svgDocument[pos:], '<')
+		if tagStart == -1 {
+			break
+		}
+		tagStart += pos
+		tagEnd = bytes.IndexByte(svgDocument[tagStart:], '>')
+		if tagEnd == -1 {
+			break
+		}
+		tagEnd += tagStart + 1
+		tag := svgDocument[tagStart:tagEnd]
+		// Copy everything up to this tag
+		out.Write(svgDocument[pos:tagStart])
+		pos = tagEnd
+		selfClosing = bytes.HasSuffix(tag, []byte("/>"))
+		switch {
+		case bytes.HasPrefix(tag, []byte("<?")), bytes.HasPrefix(tag, []byte("<!")):
+			// XML declarations and comments
+		case bytes.HasPrefix(tag, []byte("</")):
+			depth--
+		case depth == 1 && selfClosing && bytes.HasPrefix(tag, []byte("<rect ")):
+			if fill, rect, ok := fillAttribute(tag); ok {
+				if insertAt == -1 {
+					insertAt = out.Len()
+				}
+				// Use "/>" as the ending, also for " />"
+				rect = append(bytes.TrimRight(rect[:len(rect)-2], " "), '/', '>')
+				group := pi.group(fill)
+				group.rects.Write(rect)
+				group.count++
+				continue
+			}
+		case !selfClosing:
+			depth++
+		}
+		out.Write(tag)
+	}
+	out.Write(svgDocument[pos:])
+	if insertAt == -1 {
+		return out.Bytes()
+	}
+	var groups bytes.Buffer
+	pi.writeGroups(&groups)
+	result := out.Bytes()
+	return append(append(append([]byte{}, result[:insertAt]...), groups.Bytes()...), result[insertAt:]...)
+}
+
+// trimSpaceBetweenTags removes whitespace and newlines between tags, like "> \n <"
+func trimSpaceBetweenTags(svgDocument []byte) []byte {
+	var out bytes.Buffer
+	for {
+		i := bytes.IndexByte(svgDocument, '>')
+		if i == -1 {
+			out.Write(svgDocument)
+			break
+		}
+		out.Write(svgDocument[:i+1])
+		svgDocument = svgDocument[i+1:]
+		if trimmed := bytes.TrimLeft(svgDocument, " \t\r\n"); len(trimmed) > 0 && trimmed[0] == '<' {
+			svgDocument = trimmed
+		}
+	}
+	return out.Bytes()
+}
+
+// shortenFills shortens the hex colors of all fill attributes, from "#aabbcc" to "#abc",
+// or to a named color, like "red", if that is shorter.
+// If colorOptimize is true, all hex colors are quantized to the short form.
+func shortenFills(svgDocument []byte, colorOptimize bool) []byte {
+	var (
+		out  bytes.Buffer
+		attr = []byte(" fill=\"")
+	)
+	for {
+		i := bytes.Index(svgDocument, attr)
+		if i == -1 {
+			out.Write(svgDocument)
+			break
+		}
+		i += len(attr)
+		out.Write(svgDocument[:i])
+		svgDocument = svgDocument[i:]
+		end := bytes.IndexByte(svgDocument, '"')
+		if end == -1 {
+			continue
+		}
+		out.Write(shortestColor(svgDocument[:end], colorOptimize))
+		svgDocument = svgDocument[end:]
+	}
+	return out.Bytes()
+}
+
+// Optimize minifies the given SVG document, that is expected to mainly consist
+// of rectangles, like the SVG documents that png2svg creates.
+// Spaces and newlines between tags and attributes that are zero are removed,
+// colors are shortened and the rectangles can be grouped by fill color.
+func Optimize(svg string, opts MinifyOptions) string {
+	svgDocument := trimSpaceBetweenTags([]byte(svg))
+	svgDocument = shortenFills(svgDocument, opts.ColorOptimize)
+	if opts.GroupByColor {
+		svgDocument = groupRects(svgDocument, opts.ColorOptimize)
+	}
+	if opts.OpacityDecimals > 0 || opts.OmitLeadingZero {
+		decimals := opts.OpacityDecimals
+		if decimals <= 0 {
+			decimals = 3
+		}
+		svgDocument = reformatOpacities(svgDocument, decimals, !opts.OmitLeadingZero)
+	}
+	return string(optimize(svgDocument))
+}
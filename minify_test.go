@@ -0,0 +1,63 @@
+package png2svg
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+// ungroupedSVG has one rectangle per pixel, with a fill attribute each
+const ungroupedSVG = `<?xml version="1.0" encoding="UTF-8"?>
+<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 3 2">
+  <rect x="0" y="0" width="1" height="1" fill="#ff0000" />
+  <rect x="1" y="0" width="1" height="1" fill="#0000ff" />
+  <rect x="2" y="0" width="1" height="1" fill="#ff0000" />
+  <rect x="0" y="1" width="1" height="1" fill="#123456" />
+  <rect x="1" y="1" width="2" height="1" fill="#0000ff" />
+</svg>
+`
+
+func TestOptimize(t *testing.T) {
+	img := newTestImage(testPalette, "rbr", ".bb")
+	img.SetNRGBA(0, 1, parseColor(t, "#123456"))
+	checkRendersAs(t, []byte(ungroupedSVG), img, 0)
+
+	for _, tc := range []struct {
+		opts   MinifyOptions
+		groups int
+		fills  []string
+	}{
+		{MinifyOptions{}, 0, []string{`fill="red"`, `fill="#00f"`, `fill="#123456"`}},
+		{MinifyOptions{GroupByColor: true}, 2, []string{`<g fill="red">`, `<g fill="#00f">`}},
+		{MinifyOptions{ColorOptimize: true}, 0, []string{`fill="#135"`}},
+	} {
+		optimized := Optimize(ungroupedSVG, tc.opts)
+		if len(optimized) >= len(ungroupedSVG) {
+			t.Errorf("%+v: expected the document to be smaller, %d >= %d bytes", tc.opts, len(optimized), len(ungroupedSVG))
+		}
+		if strings.Contains(optimized, "> <") || strings.Contains(optimized, ">\n  <") {
+			t.Errorf("%+v: expected no space between the tags in %s", tc.opts, optimized)
+		}
+		for _, fill := range tc.fills {
+			if !strings.Contains(optimized, fill) {
+				t.Errorf("%+v: expected %s in %s", tc.opts, fill, optimized)
+			}
+		}
+		var root svgNode
+		if err := xml.Unmarshal([]byte(optimized), &root); err != nil {
+			t.Fatal(err)
+		}
+		groups := 0
+		for _, child := range root.Children {
+			if child.XMLName.Local == "g" {
+				groups++
+			}
+		}
+		if groups != tc.groups {
+			t.Errorf("%+v: expected %d groups, got %d", tc.opts, tc.groups, groups)
+		}
+		if !tc.opts.ColorOptimize {
+			checkRendersAs(t, []byte(optimized), img, 0)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package png2svg
+
+import (
+	"errors"
+)
+
+// Dither decides how pixels are converted to black and white by SetMono
+type Dither int
+
+const (
+	// DitherOff leaves the pixels as they are (the default)
+	DitherOff Dither = iota
+	// DitherThreshold makes pixels that are darker than 50% black, and the rest white
+	DitherThreshold
+	// DitherOrdered uses a 4x4 Bayer matrix as the threshold
+	DitherOrdered
+	// DitherFloydSteinberg diffuses the error of each pixel to the neighbouring pixels
+	DitherFloydSteinberg
+)
+
+// String returns the name of the dithering, as used by NewDither
+func (dither Dither) String() string {
+	switch dither {
+	case DitherThreshold:
+		return "threshold"
+	case DitherOrdered:
+		return "ordered"
+	case DitherFloydSteinberg:
+		return "floyd"
+	default:
+		return "off"
+	}
+}
+
+// ErrUnknownDither is returned by NewDither if the dithering is not recognized
+var ErrUnknownDither = errors.New("unknown dithering, use off, threshold, ordered or floyd")
+
+// NewDither returns a Dither, given "off", "threshold", "ordered" or "floyd"
+func NewDither(dither string) (Dither, error) {
+	switch dither {
+	case "off", "":
+		return DitherOff, nil
+	case "threshold":
+		return DitherThreshold, nil
+	case "ordered":
+		return DitherOrdered, nil
+	case "floyd":
+		return DitherFloydSteinberg, nil
+	}
+	return DitherOff, ErrUnknownDither
+}
+
+// bayer4 is a 4x4 Bayer matrix, for ordered dithering
+var bayer4 = [4][4]int{
+	{0, 8, 2, 10},
+	{12, 4, 14, 6},
+	{3, 11, 1, 9},
+	{15, 7, 13, 5},
+}
+
+// SetMono converts all pixels that are not transparent to black or white, with
+// the given dithering, for e-ink displays and other 1-bit targets. The pixels are
+// placed on a white background first, if they are partially transparent. Black
+// pixels become opaque, while white pixels become transparent, so that only
+// black rectangles are placed.
+func (pi *PixelImage) SetMono(dither Dither) {
+	pi.mono = dither
+	if dither == DitherOff {
+		return
+	}
+	// The luminance of each pixel, placed on a white background, from 0 to 255
+	luminance := make([]float64, len(pi.pixels))
+	for i, p := range pi.pixels {
+		if p.a == 0 {
+			luminance[i] = 255
+			continue
+		}
+		l := 0.299*float64(p.r) + 0.587*float64(p.g) + 0.114*float64(p.b)
+		luminance[i] = (l*float64(p.a) + 255*float64(255-p.a)) / 255
+	}
+	for i, p := range pi.pixels {
+		if p.a == 0 {
+			continue
+		}
+		x, y := i%pi.w, i/pi.w
+		threshold := 127.5
+		if dither == DitherOrdered {
+			threshold = (float64(bayer4[y%4][x%4]) + 0.5) * 16
+		}
+		black := luminance[i] < threshold
+		if dither == DitherFloydSteinberg {
+			var quantized float64
+			if !black {
+				quantized = 255
+			}
+			diffuse := func(dx, dy int, weight float64) {
+				nx, ny := x+dx, y+dy
+				if nx < 0 || nx >= pi.w || ny >= pi.h || pi.pixels[ny*pi.w+nx].a == 0 {
+					return
+				}
+				luminance[ny*pi.w+nx] += (luminance[i] - quantized) * weight
+			}
+			diffuse(1, 0, 7.0/16)
+			diffuse(-1, 1, 3.0/16)
+			diffuse(0, 1, 5.0/16)
+			diffuse(1, 1, 1.0/16)
+		}
+		if black {
+			p.r, p.g, p.b, p.a = 0, 0, 0, 255
+			p.covered = false
+		} else {
+			p.r, p.g, p.b, p.a = 255, 255, 255, 0
+			p.covered = true
+		}
+	}
+}
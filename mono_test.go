@@ -0,0 +1,82 @@
+package png2svg
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestNewDither(t *testing.T) {
+	for _, dither := range []Dither{DitherOff, DitherThreshold, DitherOrdered, DitherFloydSteinberg} {
+		if got, err := NewDither(dither.String()); err != nil || got != dither {
+			t.Errorf("NewDither(%q) = %v, %v", dither.String(), got, err)
+		}
+	}
+	if _, err := NewDither("atkinson"); err != ErrUnknownDither {
+		t.Errorf("expected ErrUnknownDither, got %v", err)
+	}
+}
+
+func TestMono(t *testing.T) {
+	// A horizontal grayscale gradient, from black to white
+	const w, h = 64, 16
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := uint8(x * 255 / (w - 1))
+			img.SetNRGBA(x, y, color.NRGBA{v, v, v, 255})
+		}
+	}
+	for _, dither := range []Dither{DitherThreshold, DitherOrdered, DitherFloydSteinberg} {
+		pi := NewPixelImage(img, false)
+		pi.SetOptions(Options{Mono: dither})
+		pi.Cover()
+		r := renderSVG(t, pi.Bytes())
+
+		// Only black pixels are painted, and the rest is transparent
+		black := make([]int, w)
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				switch got := r.At(x, y); got {
+				case color.NRGBA{0, 0, 0, 255}:
+					black[x]++
+				case color.NRGBA{}:
+				default:
+					t.Fatalf("%v: expected black or transparent at (%d, %d), got %v", dither, x, y, got)
+				}
+			}
+		}
+
+		// The dark end is black and the light end is transparent
+		if black[0] != h || black[w-1] != 0 {
+			t.Errorf("%v: expected %d and 0 black pixels at the ends, got %d and %d", dither, h, black[0], black[w-1])
+		}
+		// The number of black pixels goes down from left to right, for each quarter of the image
+		quarters := make([]int, 4)
+		for x, n := range black {
+			quarters[x*4/w] += n
+		}
+		for i := 1; i < len(quarters); i++ {
+			if quarters[i] > quarters[i-1] {
+				t.Errorf("%v: expected fewer black pixels in each quarter, got %v", dither, quarters)
+			}
+		}
+		if dither != DitherThreshold && (quarters[1] == 0 || quarters[2] == 0) {
+			t.Errorf("%v: expected the middle of the gradient to be dithered, got %v", dither, quarters)
+		}
+	}
+}
+
+func TestMonoTranslucent(t *testing.T) {
+	// Partially transparent pixels are placed on a white background first
+	img := newTestImage(map[byte]color.NRGBA{
+		'k': {0, 0, 0, 255},
+		'l': {0, 0, 0, 40},  // light, on white
+		'd': {0, 0, 0, 220}, // dark, on white
+		'.': {},
+	}, "kld.")
+	pi := NewPixelImage(img, false)
+	pi.SetOptions(Options{Mono: DitherThreshold})
+	pi.Cover()
+	checkRendersAs(t, pi.Bytes(), newTestImage(testPalette, "k.k."), 0)
+}
@@ -0,0 +1,130 @@
+package png2svg
+
+import (
+	"bytes"
+	"sort"
+)
+
+// attributeOrder is the order that attributes are written in, by normalizeAttributes.
+// Attributes that are not listed here are placed after these, sorted by name.
+var attributeOrder = map[string]int{
+	"xmlns":       0,
+	"version":     1,
+	"baseProfile": 2,
+	"id":          3,
+	"class":       4,
+	"x":           5,
+	"y":           6,
+	"viewBox":     7,
+	"width":       8,
+	"height":      9,
+	"fill":        10,
+	"style":       11,
+	"clip-path":   12,
+	"transform":   13,
+}
+
+// attribute is a name="value" pair within a tag
+type attribute struct {
+	name  []byte
+	value []byte // including the quotes
+}
+
+// attributeLess decides the order of two attributes, by using attributeOrder
+func attributeLess(a, b attribute) bool {
+	rankA, knownA := attributeOrder[string(a.name)]
+	rankB, knownB := attributeOrder[string(b.name)]
+	switch {
+	case knownA && knownB:
+		return rankA < rankB
+	case knownA != knownB:
+		return knownA
+	}
+	return bytes.Compare(a.name, b.name) < 0
+}
+
+// parseAttributes parses the name="value" pairs of the given tag contents,
+// which is the text between the tag name and the closing ">" or "/>".
+// Returns false if the contents could not be parsed.
+func parseAttributes(contents []byte) ([]attribute, bool) {
+	var attrs []attribute
+	for {
+		contents = bytes.TrimLeft(contents, " ")
+		if len(contents) == 0 {
+			return attrs, true
+		}
+		eq := bytes.IndexByte(contents, '=')
+		if eq < 1 || eq+1 >= len(contents) {
+			return nil, false
+		}
+		quote := contents[eq+1]
+		if quote != '"' && quote != '\'' {
+			return nil, false
+		}
+		end := bytes.IndexByte(contents[eq+2:], quote)
+		if end == -1 {
+			return nil, false
+		}
+		end += eq + 3
+		attrs = append(attrs, attribute{contents[:eq], contents[eq+1 : end]})
+		contents = contents[end:]
+	}
+}
+
+// normalizeAttributes returns the given SVG document where the attributes of all
+// tags are written in the same order, as given by attributeOrder. The attributes
+// of the <svg> tag are stored in a map by tinysvg, so without this, the order of
+// those attributes could differ between conversions of the same image.
+func normalizeAttributes(svgDocument []byte) []byte {
+	var (
+		out bytes.Buffer
+		pos int
+	)
+	out.Grow(len(svgDocument))
+	for {
+		tagStart := bytes.IndexByte(svgDocument[pos:], '<')
+		if tagStart == -1 {
+			break
+		}
+		tagStart += pos
+		tagEnd := bytes.IndexByte(svgDocument[tagStart:], '>')
+		if tagEnd == -1 {
+			break
+		}
+		tagEnd += tagStart
+		tag := svgDocument[tagStart:tagEnd]
+		nameEnd := bytes.IndexByte(tag, ' ')
+		// Only start tags with two or more attributes need to be normalized
+		if nameEnd == -1 || tag[1] == '?' || tag[1] == '!' || tag[1] == '/' || bytes.Count(tag, []byte("=")) < 2 {
+			out.Write(svgDocument[pos : tagEnd+1])
+			pos = tagEnd + 1
+			continue
+		}
+		contents := tag[nameEnd:]
+		selfClosing := bytes.HasSuffix(contents, []byte("/"))
+		if selfClosing {
+			contents = contents[:len(contents)-1]
+		}
+		attrs, ok := parseAttributes(contents)
+		if !ok || sort.SliceIsSorted(attrs, func(i, j int) bool { return attributeLess(attrs[i], attrs[j]) }) {
+			out.Write(svgDocument[pos : tagEnd+1])
+			pos = tagEnd + 1
+			continue
+		}
+		sort.SliceStable(attrs, func(i, j int) bool { return attributeLess(attrs[i], attrs[j]) })
+		out.Write(svgDocument[pos : tagStart+nameEnd])
+		for _, attr := range attrs {
+			out.WriteByte(' ')
+			out.Write(attr.name)
+			out.WriteByte('=')
+			out.Write(attr.value)
+		}
+		if selfClosing {
+			out.WriteByte('/')
+		}
+		out.WriteByte('>')
+		pos = tagEnd + 1
+	}
+	out.Write(svgDocument[pos:])
+	return out.Bytes()
+}
@@ -0,0 +1,52 @@
+package png2svg
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestNormalizeAttributes(t *testing.T) {
+	for _, tc := range []struct {
+		svgDocument string
+		want        string
+	}{
+		{
+			`<svg viewBox="0 0 2 2" xmlns="http://www.w3.org/2000/svg" version="1.2"></svg>`,
+			`<svg xmlns="http://www.w3.org/2000/svg" version="1.2" viewBox="0 0 2 2"></svg>`,
+		},
+		{
+			`<rect height="1" width="2" y="3" x="4" fill="red"/>`,
+			`<rect x="4" y="3" width="2" height="1" fill="red"/>`,
+		},
+		{
+			`<g transform="translate(1,0)" data-row="2" fill='#abc' aria-label="a b"/>`,
+			`<g fill='#abc' transform="translate(1,0)" aria-label="a b" data-row="2"/>`,
+		},
+		// Already in order, a single attribute, text, declarations and end tags are unchanged
+		{
+			`<?xml version="1.0" encoding="UTF-8"?><g fill="red"><title>a=b c=d</title></g>`,
+			`<?xml version="1.0" encoding="UTF-8"?><g fill="red"><title>a=b c=d</title></g>`,
+		},
+		// Tags that can not be parsed are unchanged
+		{
+			`<rect y=1 x=2/>`,
+			`<rect y=1 x=2/>`,
+		},
+	} {
+		if got := string(normalizeAttributes([]byte(tc.svgDocument))); got != tc.want {
+			t.Errorf("normalizeAttributes(%s) =\n%s\nexpected\n%s", tc.svgDocument, got, tc.want)
+		}
+	}
+}
+
+func TestReproducibleOutput(t *testing.T) {
+	img := noiseImage(12, 12, 5)
+	opts := Options{RootID: "logo", RootClass: "icon", Tooltips: true, FillMode: FillClass}
+	first := convertBytes(t, img, opts)
+	for i := 0; i < 20; i++ {
+		if svgDocument := convertBytes(t, img, opts); !bytes.Equal(svgDocument, first) {
+			t.Fatalf("the output differs between conversions:\n%s\n%s", first, svgDocument)
+		}
+	}
+	checkRendersAs(t, first, img, 0)
+}
@@ -0,0 +1,72 @@
+package png2svg
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+)
+
+// formatOpacity formats an opacity between 0 and 1 with at most the given number
+// of decimals and without trailing zeros. If leadingZero is false, "0.5" is written as ".5".
+func formatOpacity(opacity float64, decimals int, leadingZero bool) string {
+	s := strconv.FormatFloat(opacity, 'f', decimals, 64)
+	if strings.Contains(s, ".") {
+		s = strings.TrimSuffix(strings.TrimRight(s, "0"), ".")
+	}
+	if !leadingZero && strings.HasPrefix(s, "0.") {
+		s = s[1:]
+	}
+	return s
+}
+
+// reformatOpacity parses the given opacity value and formats it again,
+// or returns it as it is if it can not be parsed
+func reformatOpacity(value []byte, decimals int, leadingZero bool) []byte {
+	opacity, err := strconv.ParseFloat(string(bytes.TrimSpace(value)), 64)
+	if err != nil {
+		return value
+	}
+	return []byte(formatOpacity(opacity, decimals, leadingZero))
+}
+
+// reformatOpacities formats all opacity attributes and the alpha of all rgba(...)
+// colors in the given SVG document with the given number of decimals
+func reformatOpacities(svgDocument []byte, decimals int, leadingZero bool) []byte {
+	// Opacity attributes, like fill-opacity="0.500"
+	for _, attr := range []string{" opacity=\"", " fill-opacity=\"", " stroke-opacity=\""} {
+		svgDocument = replaceValues(svgDocument, []byte(attr), '"', func(value []byte) []byte {
+			return reformatOpacity(value, decimals, leadingZero)
+		})
+	}
+	// The alpha of rgba colors, like rgba(1,2,3,0.500)
+	return replaceValues(svgDocument, []byte("rgba("), ')', func(value []byte) []byte {
+		comma := bytes.LastIndexByte(value, ',')
+		if comma == -1 {
+			return value
+		}
+		return append(append([]byte{}, value[:comma+1]...), reformatOpacity(value[comma+1:], decimals, leadingZero)...)
+	})
+}
+
+// replaceValues finds all values that starts after the given prefix and ends before
+// the given byte, and replaces them with the result of the given function
+func replaceValues(svgDocument, prefix []byte, end byte, replace func([]byte) []byte) []byte {
+	var out bytes.Buffer
+	for {
+		i := bytes.Index(svgDocument, prefix)
+		if i == -1 {
+			out.Write(svgDocument)
+			break
+		}
+		i += len(prefix)
+		out.Write(svgDocument[:i])
+		svgDocument = svgDocument[i:]
+		valueEnd := bytes.IndexByte(svgDocument, end)
+		if valueEnd == -1 {
+			continue
+		}
+		out.Write(replace(svgDocument[:valueEnd]))
+		svgDocument = svgDocument[valueEnd:]
+	}
+	return out.Bytes()
+}
@@ -0,0 +1,71 @@
+package png2svg
+
+import (
+	"testing"
+)
+
+func TestFormatOpacity(t *testing.T) {
+	for _, tc := range []struct {
+		opacity     float64
+		decimals    int
+		leadingZero bool
+		want        string
+	}{
+		{0.5, 3, true, "0.5"},
+		{0.5, 3, false, ".5"},
+		{0.502, 3, true, "0.502"},
+		{0.502, 2, true, "0.5"},
+		{0.502, 1, false, ".5"},
+		{0.126, 2, true, "0.13"},
+		{1, 3, false, "1"},
+		{0, 3, false, "0"},
+		{0.004, 2, false, "0"},
+	} {
+		if got := formatOpacity(tc.opacity, tc.decimals, tc.leadingZero); got != tc.want {
+			t.Errorf("formatOpacity(%v, %d, %v) = %q, expected %q", tc.opacity, tc.decimals, tc.leadingZero, got, tc.want)
+		}
+	}
+}
+
+func TestReformatOpacities(t *testing.T) {
+	for _, tc := range []struct {
+		in          string
+		decimals    int
+		leadingZero bool
+		want        string
+	}{
+		{`<g fill-opacity="0.502"><rect opacity="0.250"/></g>`, 2, false, `<g fill-opacity=".5"><rect opacity=".25"/></g>`},
+		{`<rect fill="rgba(255,0,0,0.502)"/>`, 1, true, `<rect fill="rgba(255,0,0,0.5)"/>`},
+		{`<rect stroke-opacity="0.75" fill-opacity="x"/>`, 3, false, `<rect stroke-opacity=".75" fill-opacity="x"/>`},
+		// Other attributes that end with "opacity" are not changed
+		{`<rect data-opacity="0.500"/>`, 1, false, `<rect data-opacity="0.500"/>`},
+	} {
+		if got := string(reformatOpacities([]byte(tc.in), tc.decimals, tc.leadingZero)); got != tc.want {
+			t.Errorf("reformatOpacities(%q) = %q, expected %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestOptimizeOpacity(t *testing.T) {
+	img := newTestImage(testPalette,
+		"hhb",
+		"rhh",
+	)
+	svgDocument := string(convertBytes(t, img, Options{ColorFormat: ColorRGBA}))
+	for _, tc := range []struct {
+		opts      MinifyOptions
+		alpha     string
+		tolerance int
+	}{
+		{MinifyOptions{OmitLeadingZero: true}, ",.502)", 1},
+		{MinifyOptions{OpacityDecimals: 2}, ",0.5)", 2},
+		{MinifyOptions{OpacityDecimals: 1, OmitLeadingZero: true}, ",.5)", 2},
+	} {
+		optimized := Optimize(svgDocument, tc.opts)
+		if len(optimized) >= len(svgDocument) {
+			t.Errorf("%+v: expected the document to be smaller, %d >= %d bytes", tc.opts, len(optimized), len(svgDocument))
+		}
+		svgContains(t, []byte(optimized), tc.alpha)
+		checkRendersAs(t, []byte(optimized), img, tc.tolerance)
+	}
+}
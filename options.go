@@ -0,0 +1,416 @@
+package png2svg
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Options contains the settings that are used when converting an image
+type Options struct {
+	// ColorOptimize is for using only 4096 colors (short hex color strings, like #fff)
+	ColorOptimize bool
+	// Rounding is how color channels are rounded when ColorOptimize is true
+	Rounding Rounding
+	// ColorFormat is how the fill colors are written
+	ColorFormat ColorFormat
+	// Tooltips is for adding a <title> with the hex color to each color group
+	Tooltips bool
+	// MaxRectsPerGroup is the maximum number of rectangles per <g> tag, 0 is unlimited
+	MaxRectsPerGroup int
+	// SinglePixelRectangles is for using only 1x1 rectangles
+	SinglePixelRectangles bool
+	// Pink is for coloring the expanded rectangles pink
+	Pink bool
+	// DistanceFunc is used for finding the nearest color when Rounding is RoundNearest.
+	// If nil, each color channel is rounded to the nearest level, which gives the
+	// same result as using SquaredEuclidean.
+	DistanceFunc DistanceFunc
+	// ChromaKey is a color that should be treated as transparent, or nil
+	ChromaKey *color.NRGBA
+	// ChromaTolerance is the largest allowed difference per color channel
+	// for a pixel to be regarded as having the ChromaKey color
+	ChromaTolerance int
+	// MaxAspect is the maximum aspect ratio of the rectangles, 0 is unlimited
+	MaxAspect float64
+	// Order is the order the uncovered pixels are visited in, when placing boxes
+	Order Order
+	// MergeLeast is the number of most used colors to keep, 0 keeps all colors.
+	// The other colors are changed to the nearest of the kept colors.
+	MergeLeast int
+	// FillMode is if fill colors are written as fill attributes or as style attributes
+	FillMode FillMode
+	// Background is a color that all pixels are placed on top of, or nil.
+	// Transparent pixels gets this color.
+	Background *color.NRGBA
+	// Clip is for covering the opaque region with a single background rectangle
+	// in the most used color, clipped to the opaque region with a <clipPath>
+	Clip bool
+	// CurrentColor is a color that is written as fill="currentColor", or nil
+	CurrentColor *color.NRGBA
+	// SeedFunc selects the next pixel to place a box at, or nil for using Order
+	SeedFunc SeedFunc
+	// RootID and RootClass are the id and class attributes of the root <svg> tag, if not empty
+	RootID, RootClass string
+	// Gamma is the gamma the pixels are encoded with, for converting them to sRGB.
+	// 0 means that the pixels are already sRGB.
+	Gamma float64
+	// MaxRects is the largest number of rectangles to place before giving up, 0 is unlimited
+	MaxRects int
+	// RemoveRedundant is for removing rectangles that are fully covered by
+	// other rectangles with the same color, after covering the pixels
+	RemoveRedundant bool
+	// Mono is the dithering used for converting the pixels to black and white,
+	// or DitherOff for leaving the colors as they are
+	Mono Dither
+	// GroupByRow is for grouping the rectangles by row instead of by color,
+	// with RowTransform as the transform of each row, if not empty
+	GroupByRow   bool
+	RowTransform string
+	// PixelFilter decides which pixels are converted, or nil for all pixels that are not transparent
+	PixelFilter PixelFilter
+	// Paths is for writing the rectangles of each color as a single <path>
+	Paths bool
+	// Symmetry is for only covering one half of symmetric images, and mirroring it
+	Symmetry bool
+	// Components is for grouping the rectangles by connected component, instead of by color
+	Components bool
+	// Fallback is the maximum width and height of a raster image that is included for
+	// renderers that can not show the vector content, 0 means no fallback image
+	Fallback int
+	// Deadline is the maximum time spent on placing expanding rectangles, before the
+	// remaining pixels are covered with horizontal runs. 0 means no deadline.
+	Deadline time.Duration
+	// Separations is for writing each color as a separate layer, for print separations
+	Separations bool
+	// SpotColors maps hex colors on the form "#rrggbb" to spot color names, for the separations
+	SpotColors map[string]string
+	// AlphaLevels is the number of evenly spaced alpha levels that the alpha of
+	// each pixel is snapped to, 0 means that the alpha is kept as it is
+	AlphaLevels int
+	// GridCSS is for setting the size of 1x1 rectangles with CSS, instead of with attributes
+	GridCSS bool
+	// Views are named regions that are written as <view> tags, for using regions with URL fragments
+	Views []View
+	// Gradients is for covering linear gradients with rectangles that are filled with a <linearGradient>
+	Gradients bool
+	// GradientTolerance is the largest difference per color channel between a pixel and a gradient
+	GradientTolerance int
+	// Dedup is for defining rectangles with the same size and color once, and placing them with <use>
+	Dedup bool
+	// Interlace is for ordering the rectangles from coarse to fine, like an interlaced PNG image
+	Interlace bool
+	// Recolor maps old colors to new colors, which are replaced before anything else is done with the pixels
+	Recolor map[color.NRGBA]color.NRGBA
+	// Hybrid is for covering the semi-transparent edges of the transparent regions with small rectangles,
+	// and only the rest of the pixels with expanding rectangles
+	Hybrid Hybrid
+	// EvenOdd is for covering the opaque region with a single path in the most used color,
+	// where the transparent holes are cut out with the even-odd fill rule
+	EvenOdd bool
+	// SnapExtremes is the largest difference per color channel, for a color to be snapped
+	// to pure black or pure white. 0 is off.
+	SnapExtremes int
+	// Outline is the stroke color for drawing the outline of each rectangle, for debugging
+	Outline *color.NRGBA
+	// OutlineWidth is the stroke width of the outlines, 0 gives 0.1 pixels
+	OutlineWidth float64
+	// Provenance is for adding a <metadata> tag with the png2svg version and the options
+	Provenance bool
+	// Timestamp is for also adding the time of the conversion to the <metadata> tag
+	Timestamp bool
+	// CRLF is for writing CRLF line endings instead of LF line endings
+	CRLF bool
+	// BOM is for writing a UTF-8 byte order mark before the XML declaration
+	BOM bool
+}
+
+// SetOptions applies the given options to the PixelImage.
+// SinglePixelRectangles and Pink are used by Cover.
+// If Recolor, Gamma, Background, PixelFilter, SnapExtremes, ChromaKey, AlphaLevels, Mono or MergeLeast is set, the pixels are modified right away,
+// in that order. The background comes before the filters, so that the filtered pixels stay transparent.
+func (pi *PixelImage) SetOptions(opts Options) {
+	pi.SetColorOptimize(opts.ColorOptimize)
+	pi.SetQuantizeRounding(opts.Rounding)
+	pi.SetColorFormat(opts.ColorFormat)
+	pi.SetTooltips(opts.Tooltips)
+	pi.SetMaxRectsPerGroup(opts.MaxRectsPerGroup)
+	pi.SetDistanceFunc(opts.DistanceFunc)
+	// A maximum aspect ratio between 0 and 1 is ignored, see SetMaxAspect
+	pi.SetMaxAspect(opts.MaxAspect)
+	pi.SetOrder(opts.Order)
+	pi.SetFillMode(opts.FillMode)
+	pi.SetProvenance(opts.Provenance, opts.Timestamp)
+	pi.SetCRLF(opts.CRLF)
+	pi.SetBOM(opts.BOM)
+	pi.SetClip(opts.Clip)
+	pi.SetEvenOdd(opts.EvenOdd)
+	pi.SetHybrid(opts.Hybrid)
+	pi.SetSeedFunc(opts.SeedFunc)
+	pi.SetRootAttributes(opts.RootID, opts.RootClass)
+	pi.SetMaxRects(opts.MaxRects)
+	pi.SetRemoveRedundant(opts.RemoveRedundant)
+	pi.SetGroupByRow(opts.GroupByRow, opts.RowTransform)
+	pi.SetPaths(opts.Paths)
+	pi.SetSymmetry(opts.Symmetry)
+	pi.SetComponents(opts.Components)
+	pi.SetFallback(opts.Fallback)
+	pi.SetDeadline(opts.Deadline)
+	pi.SetSeparations(opts.Separations, opts.SpotColors)
+	pi.SetGridCSS(opts.GridCSS)
+	pi.SetViews(opts.Views)
+	pi.SetGradients(opts.Gradients, opts.GradientTolerance)
+	pi.SetDedup(opts.Dedup)
+	pi.SetInterlace(opts.Interlace)
+	if opts.CurrentColor != nil {
+		pi.SetCurrentColor(*opts.CurrentColor)
+	}
+	if opts.Outline != nil {
+		pi.SetOutline(*opts.Outline, opts.OutlineWidth)
+	}
+	if len(opts.Recolor) > 0 {
+		pi.SetRecolor(opts.Recolor)
+	}
+	if opts.Gamma > 0 {
+		pi.SetGamma(opts.Gamma)
+	}
+	if opts.Background != nil {
+		pi.SetBackground(*opts.Background)
+	}
+	if opts.PixelFilter != nil {
+		pi.SetPixelFilter(opts.PixelFilter)
+	}
+	if opts.SnapExtremes > 0 {
+		pi.SetSnapExtremes(opts.SnapExtremes)
+	}
+	if opts.ChromaKey != nil {
+		pi.SetChromaKey(*opts.ChromaKey, opts.ChromaTolerance)
+	}
+	if opts.AlphaLevels > 1 {
+		pi.SetAlphaLevels(opts.AlphaLevels)
+	}
+	if opts.Mono != DitherOff {
+		pi.SetMono(opts.Mono)
+	}
+	if opts.MergeLeast > 0 {
+		pi.MergeLeastUsedColors(opts.MergeLeast)
+	}
+	pi.singlePixelRectangles = opts.SinglePixelRectangles
+	pi.pink = opts.Pink
+}
+
+// Cover covers all pixels that are not yet covered, either by using
+// expanding rectangles or by using only 1x1 rectangles, depending on the options.
+func (pi *PixelImage) Cover() {
+	if pi.deadline > 0 {
+		pi.stopAt = time.Now().Add(pi.deadline)
+	}
+	if pi.symmetry {
+		// Only cover one half, if the image is symmetric
+		pi.coverMirroredHalf()
+	}
+	if pi.clip {
+		// Cover the most used opaque color with a clipped background rectangle
+		pi.coverClip()
+	} else if pi.evenOdd {
+		// Cover the most used opaque color with a path that has the holes cut out
+		pi.coverEvenOdd()
+	}
+	if pi.gradients {
+		// Cover linear gradients with rectangles that are filled with gradients
+		pi.coverGradients()
+	}
+	var edges []int
+	if pi.hybrid != HybridOff {
+		// Leave the edges of the transparent regions for coverEdges
+		edges = pi.hideEdges()
+	}
+	placedBefore := len(pi.rects)
+	if pi.singlePixelRectangles {
+		// Cover all remaining pixels with rectangles of size 1x1
+		pi.CoverAllPixels()
+	} else {
+		// Cover pixels by creating expanding rectangles
+		pi.CoverBoxes(pi.pink)
+	}
+	if pi.hybrid != HybridOff {
+		// Cover the edges that were not covered by the expanding rectangles
+		pi.coverEdges(edges, pi.rects[placedBefore:])
+	}
+	if pi.pastDeadline() {
+		// Cover the remaining pixels quickly, to have a complete image
+		count := pi.coverRuns()
+		if pi.verbose {
+			fmt.Printf("The deadline was reached, covered the remaining %d pixels with horizontal runs.\n", count)
+		}
+		return
+	}
+	if pi.removeRedundant {
+		removed := pi.RemoveRedundantRects()
+		if pi.verbose {
+			fmt.Printf("Removed %d redundant rectangles.\n", removed)
+		}
+	}
+}
+
+// String returns the options as space separated key=value pairs.
+// Provenance and Timestamp are left out, since they describe the <metadata> tag that the string is written to.
+func (opts Options) String() string {
+	fields := []string{
+		"colorOptimize=" + strconv.FormatBool(opts.ColorOptimize),
+		"rounding=" + opts.Rounding.String(),
+		"colorFormat=" + opts.ColorFormat.String(),
+		"tooltips=" + strconv.FormatBool(opts.Tooltips),
+		"maxRectsPerGroup=" + strconv.Itoa(opts.MaxRectsPerGroup),
+		"singlePixelRectangles=" + strconv.FormatBool(opts.SinglePixelRectangles),
+		"pink=" + strconv.FormatBool(opts.Pink),
+		"customDistance=" + strconv.FormatBool(opts.DistanceFunc != nil),
+		"maxAspect=" + strconv.FormatFloat(opts.MaxAspect, 'g', -1, 64),
+		"order=" + opts.Order.String(),
+		"mergeLeast=" + strconv.Itoa(opts.MergeLeast),
+		"fillMode=" + opts.FillMode.String(),
+		"clip=" + strconv.FormatBool(opts.Clip),
+		"customSeed=" + strconv.FormatBool(opts.SeedFunc != nil),
+		"gamma=" + strconv.FormatFloat(opts.Gamma, 'g', -1, 64),
+		"maxRects=" + strconv.Itoa(opts.MaxRects),
+		"removeRedundant=" + strconv.FormatBool(opts.RemoveRedundant),
+		"mono=" + opts.Mono.String(),
+		"groupByRow=" + strconv.FormatBool(opts.GroupByRow),
+		"customPixelFilter=" + strconv.FormatBool(opts.PixelFilter != nil),
+		"paths=" + strconv.FormatBool(opts.Paths),
+		"symmetry=" + strconv.FormatBool(opts.Symmetry),
+		"components=" + strconv.FormatBool(opts.Components),
+		"fallback=" + strconv.Itoa(opts.Fallback),
+		"deadline=" + opts.Deadline.String(),
+		"separations=" + strconv.FormatBool(opts.Separations),
+		"spotColors=" + spotColorsString(opts.SpotColors),
+		"alphaLevels=" + strconv.Itoa(opts.AlphaLevels),
+		"gridCSS=" + strconv.FormatBool(opts.GridCSS),
+		"views=" + viewsString(opts.Views),
+		"gradients=" + strconv.FormatBool(opts.Gradients),
+		"gradientTolerance=" + strconv.Itoa(opts.GradientTolerance),
+		"dedup=" + strconv.FormatBool(opts.Dedup),
+		"interlace=" + strconv.FormatBool(opts.Interlace),
+		"recolor=" + recolorString(opts.Recolor),
+		"hybrid=" + opts.Hybrid.String(),
+		"evenOdd=" + strconv.FormatBool(opts.EvenOdd),
+		"snapExtremes=" + strconv.Itoa(opts.SnapExtremes),
+		"crlf=" + strconv.FormatBool(opts.CRLF),
+		"bom=" + strconv.FormatBool(opts.BOM),
+	}
+	if opts.ChromaKey != nil {
+		fields = append(fields,
+			"chromaKey="+string(hexColor(int(opts.ChromaKey.R), int(opts.ChromaKey.G), int(opts.ChromaKey.B))),
+			"chromaTolerance="+strconv.Itoa(opts.ChromaTolerance))
+	}
+	if opts.Background != nil {
+		fields = append(fields, "background="+string(hexColor(int(opts.Background.R), int(opts.Background.G), int(opts.Background.B))))
+	}
+	if opts.CurrentColor != nil {
+		fields = append(fields, "currentColor="+string(hexColor(int(opts.CurrentColor.R), int(opts.CurrentColor.G), int(opts.CurrentColor.B))))
+	}
+	if opts.Outline != nil {
+		fields = append(fields,
+			"outline="+string(hexColor(int(opts.Outline.R), int(opts.Outline.G), int(opts.Outline.B))),
+			"outlineWidth="+strconv.FormatFloat(opts.OutlineWidth, 'g', -1, 64))
+	}
+	if opts.RowTransform != "" {
+		fields = append(fields, "rowTransform="+strconv.Quote(opts.RowTransform))
+	}
+	if opts.RootID != "" {
+		fields = append(fields, "rootID="+strconv.Quote(opts.RootID))
+	}
+	if opts.RootClass != "" {
+		fields = append(fields, "rootClass="+strconv.Quote(opts.RootClass))
+	}
+	return strings.Join(fields, " ")
+}
+
+// recolorString returns the color replacements as comma separated old>new pairs, sorted by the old color
+func recolorString(replacements map[color.NRGBA]color.NRGBA) string {
+	pairs := make([]string, 0, len(replacements))
+	for from, to := range replacements {
+		pairs = append(pairs, string(hexColor(int(from.R), int(from.G), int(from.B)))+">"+string(hexColor(int(to.R), int(to.G), int(to.B))))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// spotColorsString returns the spot colors as comma separated color=name pairs, sorted by the color
+func spotColorsString(spotColors map[string]string) string {
+	pairs := make([]string, 0, len(spotColors))
+	for hex, name := range spotColors {
+		pairs = append(pairs, hex+"="+strconv.Quote(name))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// viewsString returns the views as comma separated, quoted name:x,y,w,h strings
+func viewsString(views []View) string {
+	quoted := make([]string, len(views))
+	for i, view := range views {
+		quoted[i] = strconv.Quote(view.String())
+	}
+	return strings.Join(quoted, ",")
+}
+
+// Options returns the options that are currently used by the PixelImage
+func (pi *PixelImage) Options() Options {
+	return Options{
+		ColorOptimize:         pi.colorOptimize,
+		Rounding:              pi.rounding,
+		ColorFormat:           pi.colorFormat,
+		Tooltips:              pi.tooltips,
+		MaxRectsPerGroup:      pi.maxRectsPerGroup,
+		SinglePixelRectangles: pi.singlePixelRectangles,
+		Pink:                  pi.pink,
+		DistanceFunc:          pi.distance,
+		MaxAspect:             pi.maxAspect,
+		Order:                 pi.order,
+		ChromaKey:             pi.chromaKey,
+		ChromaTolerance:       pi.chromaTolerance,
+		MergeLeast:            pi.mergeLeast,
+		FillMode:              pi.fillMode,
+		Background:            pi.background,
+		Clip:                  pi.clip,
+		CurrentColor:          pi.currentColor,
+		SeedFunc:              pi.seed,
+		RootID:                pi.rootID,
+		RootClass:             pi.rootClass,
+		Gamma:                 pi.gamma,
+		MaxRects:              pi.maxRects,
+		RemoveRedundant:       pi.removeRedundant,
+		Mono:                  pi.mono,
+		GroupByRow:            pi.groupByRow,
+		RowTransform:          pi.rowTransform,
+		PixelFilter:           pi.filter,
+		Paths:                 pi.paths,
+		Symmetry:              pi.symmetry,
+		Components:            pi.components,
+		Fallback:              pi.fallback,
+		Deadline:              pi.deadline,
+		Separations:           pi.separations,
+		SpotColors:            pi.spotColors,
+		AlphaLevels:           pi.alphaLevels,
+		GridCSS:               pi.gridCSS,
+		Views:                 pi.views,
+		Gradients:             pi.gradients,
+		GradientTolerance:     pi.gradientTolerance,
+		Dedup:                 pi.dedup,
+		Interlace:             pi.interlace,
+		Recolor:               pi.recolor,
+		SnapExtremes:          pi.snapExtremes,
+		EvenOdd:               pi.evenOdd,
+		Hybrid:                pi.hybrid,
+		Outline:               pi.outline,
+		OutlineWidth:          pi.outlineWidth,
+		Provenance:            pi.provenance,
+		Timestamp:             pi.timestamp,
+		CRLF:                  pi.crlf,
+		BOM:                   pi.bom,
+	}
+}
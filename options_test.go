@@ -0,0 +1,62 @@
+package png2svg
+
+import (
+	"image"
+	"image/color"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOptionsRoundTrip(t *testing.T) {
+	opts := Options{
+		ColorOptimize:    true,
+		Rounding:         RoundNearest,
+		ColorFormat:      ColorRGBA,
+		Tooltips:         true,
+		MaxRectsPerGroup: 8,
+		MaxAspect:        4,
+		Order:            OrderColumn,
+		ChromaKey:        &color.NRGBA{255, 0, 255, 255},
+		ChromaTolerance:  3,
+		FillMode:         FillStyle,
+		RootID:           "logo",
+		RootClass:        "icon",
+		Deadline:         time.Second,
+		AlphaLevels:      4,
+		Hybrid:           HybridRuns,
+		EvenOdd:          true,
+	}
+	pi := NewPixelImage(image.NewNRGBA(image.Rect(0, 0, 2, 2)), false)
+	pi.SetOptions(opts)
+	if got := pi.Options(); !reflect.DeepEqual(got, opts) {
+		t.Errorf("expected the options to be returned unchanged:\n%v\ngot:\n%v", opts, got)
+	}
+	if s := opts.String(); !strings.Contains(s, "colorFormat=rgba") || !strings.Contains(s, "chromaKey=#ff00ff") || !strings.Contains(s, `rootID="logo"`) {
+		t.Errorf("unexpected options string: %s", s)
+	}
+}
+
+func TestEstimateSVGSize(t *testing.T) {
+	for name, img := range map[string]image.Image{
+		"solid": newTestImage(testPalette, "rrrr", "rrrr"),
+		"stripes": newTestImage(testPalette,
+			"rrrrrrrr",
+			"bbbbbbbb",
+			"rrrrrrrr",
+			"bbbbbbbb",
+		),
+		"transparent": newTestImage(testPalette, "....", "..r."),
+		"noise":       noiseImage(32, 32, 6),
+	} {
+		for _, opts := range []Options{{}, {ColorOptimize: true}, {SinglePixelRectangles: true}} {
+			actual := len(convertBytes(t, img, opts))
+			estimate := EstimateSVGSize(img, opts)
+			// The estimate should be within a factor of two of the actual size
+			if estimate < actual/2 || estimate > actual*2 {
+				t.Errorf("%s: estimated %d bytes with %s, but the SVG document is %d bytes", name, estimate, opts, actual)
+			}
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package png2svg
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Order decides in which order the pixels are used as starting points
+// for the boxes, when covering the image with expanding boxes.
+// OrderRow places the fewest rectangles for most images. For the images in the
+// img directory, OrderColumn places as many rectangles as OrderRow and OrderHilbert
+// places 20% to 90% more. OrderLargest never places more than OrderRow. The number
+// of rectangles that are placed is returned by Stats, for comparing the orders.
+type Order int
+
+const (
+	// OrderRow searches for uncovered pixels row by row, from the top left (the default)
+	OrderRow Order = iota
+	// OrderColumn searches for uncovered pixels column by column, from the top left.
+	// Since the boxes are still expanded to the right first, this places the same
+	// number of rectangles as OrderRow for most images.
+	OrderColumn
+	// OrderHilbert searches for uncovered pixels along a Hilbert curve.
+	// Boxes are then often started in the middle of a region, which splits it up,
+	// so this usually places more rectangles than OrderRow.
+	OrderHilbert
+	// OrderLargest covers the largest uncovered rectangle of pixels with the same color first.
+	// This keeps large regions from being split up, but the remaining pixels can end up more
+	// fragmented, so if OrderRow places fewer rectangles, the OrderRow rectangles are used.
+	// Stats returns how many rectangles each of the two orders places.
+	OrderLargest
+)
+
+// ErrUnknownOrder is returned by NewOrder if the order is not recognized
+var ErrUnknownOrder = errors.New("unknown order, use row, column, hilbert or largest")
+
+// NewOrder returns an Order, given "row", "column", "hilbert" or "largest"
+func NewOrder(order string) (Order, error) {
+	switch order {
+	case "row", "":
+		return OrderRow, nil
+	case "column":
+		return OrderColumn, nil
+	case "hilbert":
+		return OrderHilbert, nil
+	case "largest":
+		return OrderLargest, nil
+	}
+	return OrderRow, ErrUnknownOrder
+}
+
+// String returns the name of the order, as used by NewOrder
+func (order Order) String() string {
+	switch order {
+	case OrderColumn:
+		return "column"
+	case OrderHilbert:
+		return "hilbert"
+	case OrderLargest:
+		return "largest"
+	default:
+		return "row"
+	}
+}
+
+// hilbertPoint converts a distance d along a Hilbert curve that fills
+// an n x n square (where n is a power of two) to a position
+func hilbertPoint(n, d int) (int, int) {
+	x, y := 0, 0
+	for s := 1; s < n; s *= 2 {
+		rx := 1 & (d / 2)
+		ry := 1 & (d ^ rx)
+		if ry == 0 {
+			if rx == 1 {
+				x = s - 1 - x
+				y = s - 1 - y
+			}
+			x, y = y, x
+		}
+		x += s * rx
+		y += s * ry
+		d /= 4
+	}
+	return x, y
+}
+
+// seedOrder returns the pixel indices in the order they should be used as starting points
+func (pi *PixelImage) seedOrder() []int {
+	indices := make([]int, 0, pi.w*pi.h)
+	switch pi.order {
+	case OrderColumn:
+		for x := 0; x < pi.w; x++ {
+			for y := 0; y < pi.h; y++ {
+				indices = append(indices, y*pi.w+x)
+			}
+		}
+	case OrderHilbert:
+		n := 1
+		for n < pi.w || n < pi.h {
+			n *= 2
+		}
+		for d := 0; d < n*n; d++ {
+			if x, y := hilbertPoint(n, d); x < pi.w && y < pi.h {
+				indices = append(indices, y*pi.w+x)
+			}
+		}
+	default:
+		for i := range pi.pixels {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// coverBoxesInOrder covers all pixels that are not yet covered, by creating
+// boxes at the uncovered pixels in the selected order and expanding them
+// to the right and downwards, until they can not expand any more.
+// If pink is true, the expanded boxes (larger than 1x1) will be pink.
+func (pi *PixelImage) coverBoxesInOrder(pink bool) {
+	if pi.verbose {
+		fmt.Print("Placing rectangles... 0%")
+	}
+
+	percentage := 0
+	lastPercentage := 0
+
+	indices := pi.seedOrder()
+	for n, i := range indices {
+		if pi.stopCovering() {
+			break
+		}
+		if pi.pixels[i].covered {
+			continue
+		}
+
+		if pi.verbose {
+			lastPercentage = percentage
+			percentage = int((float64(n) / float64(len(indices))) * 100.0)
+			if percentage != lastPercentage {
+				Erase(len(fmt.Sprintf("%d%%", lastPercentage)))
+				fmt.Printf("%d%%", percentage)
+			}
+		}
+
+		box := pi.CreateBox(i%pi.w, i/pi.w)
+		expanded := pi.Expand(box)
+		pi.CoverBox(box, expanded && pink, pi.colorOptimize)
+	}
+
+	if pi.verbose {
+		Erase(len(fmt.Sprintf("%d%%", percentage)))
+		fmt.Println("100%")
+	}
+}
@@ -0,0 +1,138 @@
+package png2svg
+
+import (
+	"image"
+	"testing"
+)
+
+func TestNewOrder(t *testing.T) {
+	for _, order := range []Order{OrderRow, OrderColumn, OrderHilbert, OrderLargest} {
+		got, err := NewOrder(order.String())
+		if err != nil || got != order {
+			t.Errorf("NewOrder(%q) = %v, %v, expected %v", order.String(), got, err, order)
+		}
+	}
+	if _, err := NewOrder("spiral"); err != ErrUnknownOrder {
+		t.Errorf("expected ErrUnknownOrder, got %v", err)
+	}
+}
+
+func TestSeedOrder(t *testing.T) {
+	for _, order := range []Order{OrderRow, OrderColumn, OrderHilbert} {
+		for _, size := range [][2]int{{1, 1}, {3, 5}, {8, 8}, {9, 2}} {
+			pi := NewPixelImage(noiseImage(size[0], size[1], 2), false)
+			pi.SetOrder(order)
+			seen := make(map[int]bool)
+			for _, i := range pi.seedOrder() {
+				if seen[i] {
+					t.Errorf("%v order, %dx%d: pixel %d is visited twice", order, size[0], size[1], i)
+				}
+				seen[i] = true
+			}
+			if len(seen) != size[0]*size[1] {
+				t.Errorf("%v order, %dx%d: visited %d pixels, expected %d", order, size[0], size[1], len(seen), size[0]*size[1])
+			}
+		}
+	}
+}
+
+func TestOrdersCoverAllPixels(t *testing.T) {
+	for name, img := range map[string]*image.NRGBA{
+		"noise": noiseImage(13, 11, 4),
+		"shapes": newTestImage(testPalette,
+			"..rrrr..",
+			".rrbbrr.",
+			"rrbbbbrr",
+			"rrrrrrrr",
+			"g.g..g.g",
+			"hhhhhhhh",
+		),
+	} {
+		for _, order := range []Order{OrderRow, OrderColumn, OrderHilbert, OrderLargest} {
+			pi := NewPixelImage(img, false)
+			pi.SetOptions(Options{Order: order, ColorFormat: ColorRGBA})
+			pi.Cover()
+			if !pi.Done(0, 0) {
+				t.Errorf("%s: the %v order does not cover all pixels", name, order)
+				continue
+			}
+			r := checkRendersAs(t, pi.Bytes(), img, 1)
+			for y := 0; y < r.h; y++ {
+				for x := 0; x < r.w; x++ {
+					if img.NRGBAAt(x, y).A > 0 && r.Painted(x, y) == 0 {
+						t.Errorf("%s: the %v order does not paint pixel (%d, %d)", name, order, x, y)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestOrderLargest(t *testing.T) {
+	// Covering the 4x2 red rectangle first places one rectangle less than the row order
+	img := newTestImage(testPalette,
+		"rbrr",
+		"rrrr",
+		".rb.",
+	)
+	pi := NewPixelImage(img, false)
+	pi.SetOptions(Options{Order: OrderLargest})
+	pi.Cover()
+	checkRendersAs(t, pi.Bytes(), img, 0)
+	if stats := pi.Stats(); stats.Rects != 5 || stats.LargestRects != 5 || stats.RowRects != 6 {
+		t.Errorf("expected 5 rectangles, 5 with the largest first and 6 in row order, got %+v", stats)
+	}
+
+	// The row order is used when it places fewer rectangles
+	for n := 3; n < 9; n++ {
+		img := noiseImage(4*n, 3*n, n)
+		row := NewPixelImage(img, false)
+		row.Cover()
+		pi := NewPixelImage(img, false)
+		pi.SetOptions(Options{Order: OrderLargest})
+		pi.Cover()
+		stats := pi.Stats()
+		want := stats.LargestRects
+		if stats.RowRects < want {
+			want = stats.RowRects
+		}
+		if stats.RowRects != row.Stats().Rects || stats.Rects != want {
+			t.Errorf("%d colors: expected the fewest rectangles of %+v, and %d in row order", n, stats, row.Stats().Rects)
+		}
+		if len(pi.Uncovered()) > 0 {
+			t.Errorf("%d colors: not all pixels are covered", n)
+		}
+		// The result is the same every time
+		again := NewPixelImage(img, false)
+		again.SetOptions(Options{Order: OrderLargest})
+		again.Cover()
+		if string(again.Bytes()) != string(pi.Bytes()) {
+			t.Errorf("%d colors: expected the same result every time", n)
+		}
+	}
+}
+
+func TestOrderLargestMaxAspect(t *testing.T) {
+	img := newTestImage(testPalette,
+		"rrrrrrrrr",
+		"rrrrrrrrr",
+		"b........",
+		"b........",
+		"b........",
+		"b........",
+		"b........",
+	)
+	pi := NewPixelImage(img, false)
+	pi.SetOptions(Options{Order: OrderLargest, MaxAspect: 2})
+	pi.Cover()
+	checkRendersAs(t, pi.Bytes(), img, 0)
+	for _, rect := range pi.rects {
+		if !pi.withinAspect(rect.Width, rect.Height) {
+			t.Errorf("the rectangle %+v is more elongated than the maximum aspect ratio", rect)
+		}
+	}
+	// 9x2 is split into 4x2, 4x2 and 1x2, and 1x5 into 1x2, 1x2 and 1x1
+	if stats := pi.Stats(); stats.LargestRects != 6 {
+		t.Errorf("expected 6 rectangles with the largest first, got %d", stats.LargestRects)
+	}
+}
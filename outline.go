@@ -0,0 +1,43 @@
+package png2svg
+
+import (
+	"bytes"
+	"image/color"
+	"strconv"
+)
+
+// defaultOutlineWidth is the stroke width of the outlines, if no width is given
+const defaultOutlineWidth = 0.1
+
+// SetOutline can be used for drawing the outline of each rectangle with the given
+// stroke color and width, in a separate layer on top of the image. This is useful for
+// inspecting how the image has been covered with rectangles. A width of 0 or less
+// gives a width of 0.1 pixels.
+func (pi *PixelImage) SetOutline(stroke color.NRGBA, width float64) {
+	if width <= 0 {
+		width = defaultOutlineWidth
+	}
+	pi.outline = &stroke
+	pi.outlineWidth = width
+}
+
+// writeOutline writes the layer with the outlines of all rectangles, if SetOutline has been used
+func (pi *PixelImage) writeOutline(buf *bytes.Buffer) {
+	if pi.outline == nil {
+		return
+	}
+	buf.WriteString("<g id=\"")
+	buf.WriteString(pi.classPrefix)
+	buf.WriteString("outline\" fill=\"none\" stroke=\"")
+	buf.Write(shortenColor(hexColor(int(pi.outline.R), int(pi.outline.G), int(pi.outline.B)), false))
+	buf.WriteString("\" stroke-width=\"")
+	buf.WriteString(strconv.FormatFloat(pi.outlineWidth, 'f', -1, 64))
+	buf.WriteString("\">")
+	for _, rect := range pi.rects {
+		writeRect(buf, rect.X, rect.Y, rect.Width, rect.Height)
+	}
+	for _, g := range pi.gradientRects {
+		writeRect(buf, g.x, g.y, g.w, g.h)
+	}
+	buf.WriteString("</g>")
+}
@@ -0,0 +1,49 @@
+package png2svg
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image/color"
+	"testing"
+)
+
+func TestOutline(t *testing.T) {
+	img := newTestImage(testPalette,
+		"rrb",
+		"rgg",
+		"b.r",
+	)
+	flat := convertBytes(t, img, Options{})
+	for _, tc := range []struct {
+		width float64
+		want  string
+	}{
+		{0, "0.1"},
+		{0.5, "0.5"},
+		{2, "2"},
+	} {
+		svgDocument := convertBytes(t, img, Options{Outline: &color.NRGBA{255, 0, 255, 255}, OutlineWidth: tc.width})
+		// The outlines are not filled, so the image looks the same
+		checkRendersAs(t, svgDocument, img, 0)
+
+		var root svgNode
+		if err := xml.Unmarshal(svgDocument, &root); err != nil {
+			t.Fatal(err)
+		}
+		outline := root.Children[len(root.Children)-1]
+		if id, _ := outline.attr("id"); id != "outline" {
+			t.Fatalf("expected the outlines to be the last layer, got %s", svgDocument)
+		}
+		for name, want := range map[string]string{"fill": "none", "stroke": "#f0f", "stroke-width": tc.want} {
+			if got, _ := outline.attr(name); got != want {
+				t.Errorf("expected %s=%q on the outline layer, got %q", name, want, got)
+			}
+		}
+		if want := bytes.Count(flat, []byte("<rect")); len(outline.Children) != want {
+			t.Errorf("expected one outline for each of the %d rectangles, got %d", want, len(outline.Children))
+		}
+	}
+	if bytes.Contains(flat, []byte("stroke")) {
+		t.Error("expected no outlines by default")
+	}
+}
@@ -0,0 +1,86 @@
+package png2svg
+
+import (
+	"bytes"
+	"strconv"
+)
+
+// SetPaths can be used for writing all rectangles with the same fill color as
+// a single <path>, instead of as <rect> tags within a <g> tag, which is more compact.
+// The path commands use relative coordinates where that is shorter.
+func (pi *PixelImage) SetPaths(enabled bool) {
+	pi.paths = enabled
+}
+
+// writeNumbers writes the given numbers, separated by spaces where needed.
+// A minus sign works as a separator, so no space is needed before negative numbers.
+func writeNumbers(buf *bytes.Buffer, numbers ...int) {
+	for i, n := range numbers {
+		if i > 0 && n >= 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(strconv.Itoa(n))
+	}
+}
+
+// numbersLength returns how many bytes writeNumbers would write for the given numbers
+func numbersLength(numbers ...int) int {
+	var buf bytes.Buffer
+	writeNumbers(&buf, numbers...)
+	return buf.Len()
+}
+
+// pathData returns the d attribute of a path that consists of the given rectangles.
+// Each rectangle is a closed subpath, like "M2 3h4v1h-4z". After "z", the current
+// point is the start of the subpath, so the next subpath can be moved to with a
+// relative "m" command, if that is shorter than an absolute "M" command.
+func pathData(rects []Rect) []byte {
+	var (
+		buf    bytes.Buffer
+		cx, cy int // the current point
+	)
+	for i, rect := range rects {
+		dx, dy := rect.X-cx, rect.Y-cy
+		if i > 0 && numbersLength(dx, dy) < numbersLength(rect.X, rect.Y) {
+			buf.WriteByte('m')
+			writeNumbers(&buf, dx, dy)
+		} else {
+			buf.WriteByte('M')
+			writeNumbers(&buf, rect.X, rect.Y)
+		}
+		buf.WriteByte('h')
+		writeNumbers(&buf, rect.Width)
+		buf.WriteByte('v')
+		writeNumbers(&buf, rect.Height)
+		buf.WriteByte('h')
+		writeNumbers(&buf, -rect.Width)
+		buf.WriteByte('z')
+		cx, cy = rect.X, rect.Y
+	}
+	return buf.Bytes()
+}
+
+// writePaths writes one <path> per fill color, in the order the colors were first used
+func (pi *PixelImage) writePaths(buf *bytes.Buffer) {
+	byFill := make(map[string][]Rect, len(pi.groupOrder))
+	for _, rect := range pi.rects {
+		byFill[rect.Fill] = append(byFill[rect.Fill], rect)
+	}
+	for i, group := range pi.groupOrder {
+		rects := byFill[string(group.fill)]
+		if len(rects) == 0 {
+			continue
+		}
+		buf.WriteString("<path")
+		pi.writeFill(buf, i, group.fill)
+		buf.WriteString("\" d=\"")
+		buf.Write(pathData(rects))
+		if pi.tooltips {
+			buf.WriteString("\">")
+			writeTooltip(buf, group.hex)
+			buf.WriteString("</path>")
+		} else {
+			buf.WriteString("\"/>")
+		}
+	}
+}
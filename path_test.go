@@ -0,0 +1,111 @@
+package png2svg
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+)
+
+func TestWriteNumbers(t *testing.T) {
+	for _, tc := range []struct {
+		numbers []int
+		want    string
+	}{
+		{[]int{3}, "3"},
+		{[]int{3, 4}, "3 4"},
+		{[]int{3, -4}, "3-4"},
+		{[]int{-3, -4}, "-3-4"},
+		{[]int{-3, 40}, "-3 40"},
+	} {
+		var buf bytes.Buffer
+		writeNumbers(&buf, tc.numbers...)
+		if buf.String() != tc.want {
+			t.Errorf("writeNumbers(%v) wrote %q, expected %q", tc.numbers, buf.String(), tc.want)
+		}
+		if n := numbersLength(tc.numbers...); n != len(tc.want) {
+			t.Errorf("numbersLength(%v) = %d, expected %d", tc.numbers, n, len(tc.want))
+		}
+	}
+}
+
+func TestPathData(t *testing.T) {
+	for _, tc := range []struct {
+		rects []Rect
+		want  string
+	}{
+		{[]Rect{{X: 2, Y: 3, Width: 4, Height: 1}}, "M2 3h4v1h-4z"},
+		// The relative move is not shorter for small coordinates
+		{[]Rect{{X: 2, Y: 3, Width: 1, Height: 1}, {X: 5, Y: 1, Width: 2, Height: 2}}, "M2 3h1v1h-1zM5 1h2v2h-2z"},
+		// The relative move is shorter for large coordinates that are close
+		{[]Rect{{X: 1000, Y: 2000, Width: 1, Height: 1}, {X: 1003, Y: 1999, Width: 1, Height: 1}}, "M1000 2000h1v1h-1zm3-1h1v1h-1z"},
+	} {
+		if got := string(pathData(tc.rects)); got != tc.want {
+			t.Errorf("expected the path %q, got %q", tc.want, got)
+		}
+	}
+}
+
+// absolutePathLength returns the length of the path data for the given
+// rectangles, if only absolute move commands were used
+func absolutePathLength(rects []Rect) int {
+	n := 0
+	for _, rect := range rects {
+		n += len("Mhvhz") + numbersLength(rect.X, rect.Y, rect.Width, rect.Height, -rect.Width)
+	}
+	return n
+}
+
+func TestPaths(t *testing.T) {
+	img := newTestImage(testPalette,
+		"rrb.",
+		"rgg.",
+		"bggr",
+	)
+	for _, tooltips := range []bool{false, true} {
+		svgDocument := convertBytes(t, img, Options{Paths: true, Tooltips: tooltips})
+		checkRendersAs(t, svgDocument, img, 0)
+		var root svgNode
+		if err := xml.Unmarshal(svgDocument, &root); err != nil {
+			t.Fatal(err)
+		}
+		if len(root.Children) != 3 {
+			t.Errorf("expected one path per color, got %d tags", len(root.Children))
+		}
+		for _, child := range root.Children {
+			if child.XMLName.Local != "path" {
+				t.Errorf("expected only <path> tags, got <%s>", child.XMLName.Local)
+			}
+		}
+	}
+
+	// Paths with many subpaths render like the rectangles
+	noise := noiseImage(32, 24, 4)
+	checkRendersAs(t, convertBytes(t, noise, Options{Paths: true}), noise, 0)
+
+	// The relative coordinates make the paths of a large image smaller
+	large := noiseImage(160, 120, 4)
+	pi := NewPixelImage(large, false)
+	pi.SetOptions(Options{Paths: true})
+	pi.Cover()
+	svgDocument := pi.Bytes()
+	relative, absolute := 0, 0
+	var root svgNode
+	if err := xml.Unmarshal(svgDocument, &root); err != nil {
+		t.Fatal(err)
+	}
+	byFill := make(map[string][]Rect)
+	for _, rect := range pi.rects {
+		byFill[rect.Fill] = append(byFill[rect.Fill], rect)
+	}
+	for _, child := range root.Children {
+		d, _ := child.attr("d")
+		relative += len(d)
+	}
+	for _, rects := range byFill {
+		absolute += absolutePathLength(rects)
+	}
+	if relative >= absolute {
+		t.Errorf("expected the relative paths to be smaller, got %d >= %d bytes", relative, absolute)
+	}
+	t.Logf("path data with relative moves: %d bytes, with absolute moves: %d bytes", relative, absolute)
+}
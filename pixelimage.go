@@ -2,13 +2,17 @@ package png2svg
 
 import (
 	"bytes"
+	"compress/gzip"
 	"errors"
 	"fmt"
+	"html"
 	"image"
 	"image/color"
 	"image/png"
+	"io"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/xyproto/tinysvg"
 )
@@ -29,19 +33,85 @@ type Pixel struct {
 // Pixels is a slice of pointers to Pixel
 type Pixels []*Pixel
 
+// xmlHeader is the XML declaration that is placed before the <svg> tag
+const xmlHeader = `<?xml version="1.0" encoding="UTF-8"?>`
+
 // PixelImage contains the data needed to convert a PNG to an SVG:
-// pixels (with an overview of which pixels are covered) and
-// an SVG document, starting with the document and root tag +
+// pixels (with an overview of which pixels are covered),
+// the root SVG tag, the rectangles grouped by fill color +
 // colorOptimize, for if only 4096 colors should be used
 // (short hex color strings, like #fff).
 type PixelImage struct {
 	pixels        Pixels
-	document      *tinysvg.Document
 	svgTag        *tinysvg.Tag
+	groups        map[string]*colorGroup
+	groupOrder    []*colorGroup
 	verbose       bool
 	w             int
 	h             int
 	colorOptimize bool
+	rounding      Rounding
+	tooltips      bool
+	colorFormat   ColorFormat
+	// maxRectsPerGroup is the maximum number of rectangles per <g> tag, 0 is unlimited
+	maxRectsPerGroup      int
+	singlePixelRectangles bool
+	pink                  bool
+	distance              DistanceFunc
+	provenance            bool
+	timestamp             bool
+	maxAspect             float64
+	order                 Order
+	chromaKey             *color.NRGBA
+	chromaTolerance       int
+	mergeLeast            int
+	fillMode              FillMode
+	background            *color.NRGBA
+	classPrefix           string // for keeping the CSS class names and ids apart, in sprite sheets
+	clip                  bool
+	clipRegion            *clipRegion
+	evenOdd               bool
+	evenOddRegion         *evenOddRegion
+	hybrid                Hybrid
+	edgeRects             int
+	largestRects          int
+	rowRects              int
+	crlf                  bool
+	bom                   bool
+	rects                 []Rect // all placed rectangles, in order
+	currentColor          *color.NRGBA
+	seed                  SeedFunc
+	diffRects             bytes.Buffer // pixels that differ from the reference image
+	rootID                string
+	rootClass             string
+	gamma                 float64
+	maxRects              int
+	removeRedundant       bool
+	mono                  Dither
+	groupByRow            bool
+	rowTransform          string
+	filter                PixelFilter
+	paths                 bool
+	symmetry              bool
+	mirror                Mirror
+	components            bool
+	fallback              int
+	deadline              time.Duration
+	stopAt                time.Time
+	separations           bool
+	spotColors            map[string]string
+	alphaLevels           int
+	gridCSS               bool
+	views                 []View
+	gradients             bool
+	gradientTolerance     int
+	gradientRects         []gradient
+	dedup                 bool
+	interlace             bool
+	recolor               map[color.NRGBA]color.NRGBA
+	snapExtremes          int
+	outline               *color.NRGBA
+	outlineWidth          float64
 }
 
 // SetColorOptimize can be used to set the colorOptimize flag,
@@ -50,6 +120,153 @@ func (pi *PixelImage) SetColorOptimize(enabled bool) {
 	pi.colorOptimize = enabled
 }
 
+// SetQuantizeRounding can be used to select how color channels are rounded
+// when only 4096 colors are used. The default is RoundFloor.
+func (pi *PixelImage) SetQuantizeRounding(rounding Rounding) {
+	pi.rounding = rounding
+}
+
+// SetTooltips can be used to add a <title> with the hex color to each color group,
+// which is shown by browsers when hovering over the group.
+func (pi *PixelImage) SetTooltips(enabled bool) {
+	pi.tooltips = enabled
+}
+
+// SetColorFormat can be used to select if fill colors should be written
+// as hex colors (the default), or as rgb(...) or rgba(...).
+func (pi *PixelImage) SetColorFormat(colorFormat ColorFormat) {
+	pi.colorFormat = colorFormat
+}
+
+// SetMaxRectsPerGroup can be used to split large color groups into several
+// <g> tags with at most n rectangles each. 0 means no limit (the default).
+func (pi *PixelImage) SetMaxRectsPerGroup(n int) {
+	pi.maxRectsPerGroup = n
+}
+
+// SetDistanceFunc can be used to set the function that is used for finding the
+// nearest color, when colors are limited to 4096 colors with RoundNearest.
+// If nil, each color channel is rounded to the nearest level.
+func (pi *PixelImage) SetDistanceFunc(distance DistanceFunc) {
+	pi.distance = distance
+}
+
+// SetProvenance can be used to add a <metadata> tag with the png2svg version
+// and the options that were used. If timestamp is true, the time of the
+// conversion is also included. Leave it out for reproducible output.
+func (pi *PixelImage) SetProvenance(enabled, timestamp bool) {
+	pi.provenance = enabled
+	pi.timestamp = timestamp
+}
+
+// writeProvenance writes a <metadata> tag with the png2svg version, the options
+// and possibly the current time
+func (pi *PixelImage) writeProvenance(buf *bytes.Buffer) {
+	writeProvenance(buf, pi.Options(), pi.timestamp)
+}
+
+// writeProvenance writes a <metadata> tag with the png2svg version, the given options
+// and possibly the current time, also for the documents that are put together from several PixelImages
+func writeProvenance(buf *bytes.Buffer, opts Options, timestamp bool) {
+	buf.WriteString("<metadata>")
+	buf.WriteString(VersionString)
+	buf.WriteString("; ")
+	buf.WriteString(opts.String())
+	if timestamp {
+		buf.WriteString("; ")
+		buf.WriteString(time.Now().UTC().Format(time.RFC3339))
+	}
+	buf.WriteString("</metadata>")
+}
+
+// SetChromaKey marks all pixels that has the given color as transparent and covered,
+// so that no SVG elements are created for them. The tolerance is the largest
+// allowed difference per color channel, for a pixel to be regarded as the same color.
+// Returns the number of pixels that were made transparent.
+func (pi *PixelImage) SetChromaKey(key color.NRGBA, tolerance int) int {
+	pi.chromaKey = &key
+	pi.chromaTolerance = tolerance
+	within := func(a int, b uint8) bool {
+		d := a - int(b)
+		return d <= tolerance && d >= -tolerance
+	}
+	count := 0
+	for _, p := range pi.pixels {
+		if p.a > 0 && within(p.r, key.R) && within(p.g, key.G) && within(p.b, key.B) {
+			p.a = 0
+			p.covered = true
+			count++
+		}
+	}
+	if pi.verbose {
+		fmt.Printf("Made %d pixels transparent.\n", count)
+	}
+	return count
+}
+
+// ErrInvalidMaxAspect is returned by SetMaxAspect if the maximum aspect ratio is between 0 and 1
+var ErrInvalidMaxAspect = errors.New("the maximum aspect ratio must be 0 (unlimited) or at least 1")
+
+// SetMaxAspect can be used to limit how elongated the rectangles can be.
+// For example, with a maximum aspect ratio of 4, a 1x8 run of pixels is
+// covered by two 1x4 rectangles. 0 means no limit (the default).
+// Ratios between 0 and 1 would not even allow squares, so they are
+// rejected with ErrInvalidMaxAspect, and the maximum is left as it is.
+func (pi *PixelImage) SetMaxAspect(maxAspect float64) error {
+	if maxAspect > 0 && maxAspect < 1 {
+		return ErrInvalidMaxAspect
+	}
+	pi.maxAspect = maxAspect
+	return nil
+}
+
+// SetOrder can be used to select the order that the uncovered pixels are
+// visited in, when covering the image with expanding boxes. The default is OrderRow.
+func (pi *PixelImage) SetOrder(order Order) {
+	pi.order = order
+}
+
+// SetFillMode can be used to select if fill colors are written as fill
+// attributes (the default) or as style attributes, like style="fill:#abc"
+func (pi *PixelImage) SetFillMode(fillMode FillMode) {
+	pi.fillMode = fillMode
+}
+
+// SetRootAttributes can be used to set the id and class attributes of the root
+// <svg> tag, for styling or scripting converted images that are embedded in the
+// same page. Empty strings are left out (the default).
+func (pi *PixelImage) SetRootAttributes(id, class string) {
+	pi.rootID = id
+	pi.rootClass = class
+}
+
+// addRootAttributes adds the id and class attributes to the rendered <svg> tag, if they are set,
+// and the xlink namespace, if it is needed.
+// The attributes are placed in the right order afterwards, by normalizeAttributes.
+func (pi *PixelImage) addRootAttributes(svgTag []byte) []byte {
+	if pi.rootID == "" && pi.rootClass == "" && !pi.usesXlink() {
+		return svgTag
+	}
+	var attrs bytes.Buffer
+	attrs.WriteString("<svg")
+	if pi.usesXlink() {
+		// The mirrored half and the deduplicated shapes are written with
+		// <use xlink:href="...">, and the fallback image with <image xlink:href="...">
+		attrs.WriteString(xlinkNamespace)
+	}
+	if pi.rootID != "" {
+		attrs.WriteString(" id=\"")
+		attrs.WriteString(html.EscapeString(pi.rootID))
+		attrs.WriteByte('"')
+	}
+	if pi.rootClass != "" {
+		attrs.WriteString(" class=\"")
+		attrs.WriteString(html.EscapeString(pi.rootClass))
+		attrs.WriteByte('"')
+	}
+	return bytes.Replace(svgTag, []byte("<svg"), attrs.Bytes(), 1)
+}
+
 // ReadPNG tries to read the given PNG image filename and returns and image.Image
 // and an error. If verbose is true, some basic information is printed to stdout.
 func ReadPNG(filename string, verbose bool) (image.Image, error) {
@@ -62,7 +279,7 @@ func ReadPNG(filename string, verbose bool) (image.Image, error) {
 		return nil, err
 	}
 	defer f.Close()
-	img, err := png.Decode(f)
+	img, err := ReadPNGFromReader(f)
 	if err != nil {
 		return nil, err
 	}
@@ -72,6 +289,12 @@ func ReadPNG(filename string, verbose bool) (image.Image, error) {
 	return img, nil
 }
 
+// ReadPNGFromReader decodes a PNG image from the given reader,
+// which can be a file, a network connection or a buffer
+func ReadPNGFromReader(r io.Reader) (image.Image, error) {
+	return png.Decode(r)
+}
+
 // Erase characters on the terminal
 func Erase(n int) {
 	fmt.Print(strings.Repeat("\b", n))
@@ -79,6 +302,8 @@ func Erase(n int) {
 
 // NewPixelImage initializes a new PixelImage struct,
 // given an image.Image.
+// Fully transparent pixels are marked as covered. This includes indexed
+// images where the palette transparency is given by a tRNS chunk.
 func NewPixelImage(img image.Image, verbose bool) *PixelImage {
 	width := img.Bounds().Max.X - img.Bounds().Min.X
 	height := img.Bounds().Max.Y - img.Bounds().Min.Y
@@ -108,7 +333,9 @@ func NewPixelImage(img image.Image, verbose bool) *PixelImage {
 		for x := img.Bounds().Min.X; x < img.Bounds().Max.X; x++ {
 			c = color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
 			alpha := int(c.A)
-			// Mark transparent pixels as already being "covered"
+			// Mark transparent pixels as already being "covered".
+			// Paletted images with a tRNS chunk are decoded to NRGBA palette
+			// entries, so the alpha is available here as well.
 			covered := alpha == 0
 			pixels[i] = &Pixel{x, y, int(c.R), int(c.G), int(c.B), alpha, covered}
 			i++
@@ -116,14 +343,61 @@ func NewPixelImage(img image.Image, verbose bool) *PixelImage {
 	}
 
 	// Create a new XML document with a new SVG tag
-	document, svgTag := tinysvg.NewTinySVG(width, height)
+	_, svgTag := tinysvg.NewTinySVG(width, height)
 
 	if verbose {
 		Erase(len(fmt.Sprintf("%d%%", lastPercentage)))
 		fmt.Println("100%")
 	}
 
-	return &PixelImage{pixels, document, svgTag, verbose, width, height, false}
+	return &PixelImage{
+		pixels:  pixels,
+		svgTag:  svgTag,
+		groups:  make(map[string]*colorGroup),
+		verbose: verbose,
+		w:       width,
+		h:       height,
+	}
+}
+
+// Reset prepares the PixelImage for converting another image, with the same options.
+// All placed rectangles and the SVG tag are cleared, so that nothing from the
+// previous conversion ends up in the next SVG document. The pixel memory is reused
+// if the new image has the same number of pixels. Options that modify the pixels,
+// like Gamma, ChromaKey, Background and MergeLeast, are applied to the new pixels.
+// The reference image set with SetReference is cleared.
+func (pi *PixelImage) Reset(img image.Image) {
+	opts := pi.Options()
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if len(pi.pixels) != width*height {
+		pi.pixels = make(Pixels, width*height)
+		for i := range pi.pixels {
+			pi.pixels[i] = &Pixel{}
+		}
+	}
+	i := 0
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			c := color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA)
+			*pi.pixels[i] = Pixel{x, y, int(c.R), int(c.G), int(c.B), int(c.A), c.A == 0}
+			i++
+		}
+	}
+	pi.w, pi.h = width, height
+	_, pi.svgTag = tinysvg.NewTinySVG(width, height)
+	pi.groups = make(map[string]*colorGroup)
+	pi.groupOrder = nil
+	pi.rects = nil
+	pi.clipRegion = nil
+	pi.evenOddRegion = nil
+	pi.gradientRects = nil
+	pi.edgeRects = 0
+	pi.largestRects, pi.rowRects = 0, 0
+	pi.mirror = MirrorNone
+	pi.stopAt = time.Time{}
+	pi.diffRects.Reset()
+	pi.SetOptions(opts)
 }
 
 // Done checks if all pixels are covered, in terms of being represented by an SVG element
@@ -169,13 +443,26 @@ func (pi *PixelImage) Covered(x, y int) bool {
 	return p.covered
 }
 
+// Width returns the width of the image, in pixels
+func (pi *PixelImage) Width() int {
+	return pi.w
+}
+
+// Height returns the height of the image, in pixels
+func (pi *PixelImage) Height() int {
+	return pi.h
+}
+
 // CoverAllPixels will cover all pixels that are not yet covered by an SVG element
 // , by creating a rectangle per pixel.
 func (pi *PixelImage) CoverAllPixels() {
 	coverCount := 0
 	for _, p := range pi.pixels {
+		if pi.TooManyRects() {
+			break
+		}
 		if !(*p).covered {
-			pi.svgTag.Pixel((*p).x, (*p).y, (*p).r, (*p).g, (*p).b)
+			pi.addRect((*p).x, (*p).y, 1, 1, pi.colorBytes((*p).r, (*p).g, (*p).b, (*p).a, pi.colorOptimize))
 			(*p).covered = true
 			coverCount++
 		}
@@ -202,7 +489,26 @@ func (pi *PixelImage) FirstUncovered(startx, starty int) (int, int) {
 	panic("All pixels are covered")
 }
 
+// Uncovered returns the coordinates of all pixels that are not yet covered,
+// line by line. This can be used for finding gaps that are left by a custom
+// coverage strategy, before writing the SVG document fails with ErrNotCovered.
+func (pi *PixelImage) Uncovered() []image.Point {
+	var points []image.Point
+	for i, p := range pi.pixels {
+		if !p.covered {
+			points = append(points, image.Point{i % pi.w, i / pi.w})
+		}
+	}
+	return points
+}
+
+// shortenColor returns a hex color on the short form "#abc", if possible.
+// If colorOptimize is true, the color is quantized to the short form.
+// Colors that are not hex colors, like "rgb(1,2,3)", are returned as they are.
 func shortenColor(hexColorBytes []byte, colorOptimize bool) []byte {
+	if len(hexColorBytes) != 7 || hexColorBytes[0] != '#' {
+		return hexColorBytes
+	}
 	if colorOptimize && len(hexColorBytes) > 5 {
 		// Use the shorthand form: #a?c?d? -> #acd
 		return []byte{'#', hexColorBytes[1], hexColorBytes[3], hexColorBytes[5]}
@@ -214,128 +520,59 @@ func shortenColor(hexColorBytes []byte, colorOptimize bool) []byte {
 	return hexColorBytes
 }
 
-// colorFromLine will extract the fill color from a svg rect line.
-// "<rect ... fill="#ff0f00" ..." gives "#ff0f00".
-// #ff0000 is shortened to  #f00.
-// Returns false if no fill color is found.
-// Returns an empty string if no fill color is found.
-func colorFromLine(line []byte, colorOptimize bool) ([]byte, []byte, bool) {
-	if !bytes.Contains(line, []byte(" fill=\"")) {
-		return nil, nil, false
-	}
-	fields := bytes.Fields(line)
-	for _, field := range fields {
-		if bytes.HasPrefix(field, []byte("fill=")) {
-			// assumption: there are always quotes, so that elems[1] exists
-			elems := bytes.Split(field, []byte("\""))
-			return elems[1], shortenColor(elems[1], colorOptimize), true
-		}
-	}
-	// This should never happen
-	return nil, nil, false
-}
-
-// groupLinesByFillColor will group lines that has a fill color by color, organized under <g> tags
-// This is not the prettiest function, but it works.
-// TODO: Rewrite, to make it prettier
-// TODO: Benchmark
-func groupLinesByFillColor(lines [][]byte, colorOptimize bool) [][]byte {
-	// Group lines by fill color
-	var (
-		groupedLines                  = make(map[string][][]byte)
-		fillColor, shortenedFillColor []byte
-		found                         bool
-	)
-	for i, line := range lines {
-		fillColor, shortenedFillColor, found = colorFromLine(line, colorOptimize)
-		if !found {
-			// skip
-			continue
-		}
-		// Erase this line. The grouped lines will be inserted at the first empty line.
-		lines[i] = make([]byte, 0)
-		// TODO: Use the byte string as the key instead of converting to a string
-		cs := string(shortenedFillColor)
-		if _, ok := groupedLines[cs]; !ok {
-			// Start an empty line
-			groupedLines[cs] = make([][]byte, 0)
-		}
-		line = bytes.Replace(line, fillColor, shortenedFillColor, 1)
-		line = append(line, '>')
-		groupedLines[cs] = append(groupedLines[cs], line)
-	}
-
-	//for k, _ := range groupedLines {
-	//	fmt.Println("COLOR: ", string(k))
-	//}
-
-	// Build a string of all lines with fillcolor, grouped by fillcolor, inside <g> tags
-	var (
-		buf  bytes.Buffer
-		from []byte
-	)
-	for key, lines := range groupedLines {
-		if len(lines) > 1 {
-			buf.Write([]byte("<g fill=\""))
-			//fmt.Printf("WRITING KEY %s\n", key)
-			buf.WriteString(key)
-			buf.Write([]byte("\">"))
-			for _, line := range lines {
-				from = append([]byte(" fill=\""), key...)
-				buf.Write(bytes.Replace(line, append(from, '"'), []byte{}, 1))
-			}
-			buf.Write([]byte("</g>"))
-		} else {
-			buf.Write(lines[0])
-		}
-	}
-	// Insert the contents in the first non-empty slice of lines
-	for i, line := range lines {
-		if len(line) == 0 {
-			lines[i] = buf.Bytes()
-			break
-		}
-	}
-	// Return lines, some of them empty. One of them is a really long line with the above contents.
-	return lines
+// namedColors are the named colors that are shorter than the hex colors they represent
+var namedColors = map[string]string{
+	"#f0ffff": "azure",
+	"#f5f5dc": "beige",
+	"#ffe4c4": "bisque",
+	"#a52a2a": "brown",
+	"#ff7f50": "coral",
+	"#ffd700": "gold",
+	"#808080": "gray", // "grey" is also possible
+	"#008000": "green",
+	"#4b0082": "indigo",
+	"#fffff0": "ivory",
+	"#f0e68c": "khaki",
+	"#faf0e6": "linen",
+	"#800000": "maroon",
+	"#000080": "navy",
+	"#808000": "olive",
+	"#ffa500": "orange",
+	"#da70d6": "orchid",
+	"#cd853f": "peru",
+	"#ffc0cb": "pink",
+	"#dda0dd": "plum",
+	"#800080": "purple",
+	"#ff0000": "red",
+	"#fa8072": "salmon",
+	"#a0522d": "sienna",
+	"#c0c0c0": "silver",
+	"#fffafa": "snow",
+	"#d2b48c": "tan",
+	"#008080": "teal",
+	"#ff6347": "tomato",
+	"#ee82ee": "violet",
+	"#f5deb3": "wheat",
 }
 
-// Bytes returns the rendered SVG document as bytes
-func (pi *PixelImage) Bytes() []byte {
-	if pi.verbose {
-		fmt.Print("Rendering SVG...")
-	}
-
-	// Render the SVG document
-	// TODO: pi.document.WriteTo also exists, and might be faster
-	svgDocument := pi.document.Bytes()
-
-	if pi.verbose {
-		fmt.Println("ok")
-		fmt.Print("Grouping elements by color...")
+// shortestColor returns the shortest representation of the given fill color,
+// which is either a named color, a hex color on the short form "#abc" or a hex
+// color on the form "#aabbcc". If colorOptimize is true, the color is quantized
+// to the short form first. Colors that are not hex colors are returned as they are.
+func shortestColor(fill []byte, colorOptimize bool) []byte {
+	fill = shortenColor(fill, colorOptimize)
+	if len(fill) == 0 || fill[0] != '#' {
+		return fill
 	}
-
-	// TODO: Make the code related to grouping both faster and more readable
-
-	// Group lines by fill color, insert <g> tags
-	lines := bytes.Split(svgDocument, []byte(">"))
-	lines = groupLinesByFillColor(lines, pi.colorOptimize)
-
-	for i, line := range lines {
-		if len(line) > 0 && !bytes.HasSuffix(line, []byte(">")) {
-			lines[i] = append(line, '>')
-		}
+	if name, ok := namedColors[string(bytes.ToLower(longColor(fill)))]; ok && len(name) < len(fill) {
+		return []byte(name)
 	}
-	// Use the line contents as the new svgDocument
-	svgDocument = bytes.Join(lines, []byte{})
-
-	if pi.verbose {
-		fmt.Println("ok")
-		fmt.Print("Additional optimizations...")
-	}
-
-	// Only non-destructive and spec-conforming optimizations goes here
+	return fill
+}
 
+// optimize performs the non-destructive and spec-conforming optimizations
+// on a rendered SVG document, like removing spaces and empty attributes.
+func optimize(svgDocument []byte) []byte {
 	// NOTE: Removing width and height for "1" gave incorrect results in GIMP.
 	// NOTE: GIMP complains about the width and height not being set, but it is set.
 
@@ -356,46 +593,35 @@ func (pi *PixelImage) Bytes() []byte {
 	svgDocument = bytes.Replace(svgDocument, []byte(" height=\"0\""), []byte{}, -1)
 	svgDocument = bytes.Replace(svgDocument, []byte("> <"), []byte("><"), -1)
 
-	// Replacement of colors that are not shortened, colors that has been shortened
-	// and color names to even shorter strings.
-	colorReplacements := map[string][]byte{
-		"#f0ffff": []byte("azure"),
-		"#f5f5dc": []byte("beige"),
-		"#ffe4c4": []byte("bisque"),
-		"#a52a2a": []byte("brown"),
-		"#ff7f50": []byte("coral"),
-		"#ffd700": []byte("gold"),
-		"#808080": []byte("gray"), // "grey" is also possible
-		"#008000": []byte("green"),
-		"#4b0082": []byte("indigo"),
-		"#fffff0": []byte("ivory"),
-		"#f0e68c": []byte("khaki"),
-		"#faf0e6": []byte("linen"),
-		"#800000": []byte("maroon"),
-		"#000080": []byte("navy"),
-		"#808000": []byte("olive"),
-		"#ffa500": []byte("orange"),
-		"#da70d6": []byte("orchid"),
-		"#cd853f": []byte("peru"),
-		"#ffc0cb": []byte("pink"),
-		"#dda0dd": []byte("plum"),
-		"#800080": []byte("purple"),
-		"#f00":    []byte("red"),
-		"#fa8072": []byte("salmon"),
-		"#a0522d": []byte("sienna"),
-		"#c0c0c0": []byte("silver"),
-		"#fffafa": []byte("snow"),
-		"#d2b48c": []byte("tan"),
-		"#008080": []byte("teal"),
-		"#ff6347": []byte("tomato"),
-		"#ee82ee": []byte("violet"),
-		"#f5deb3": []byte("wheat"),
-	}
-
-	// Replace colors with the shorter version
-	for k, v := range colorReplacements {
-		svgDocument = bytes.Replace(svgDocument, []byte(k), v, -1)
+	// Write the attributes in the same order every time, for reproducible output
+	return normalizeAttributes(svgDocument)
+}
+
+// Bytes returns the rendered SVG document as bytes
+func (pi *PixelImage) Bytes() []byte {
+	if pi.verbose {
+		fmt.Print("Rendering SVG...")
+	}
+
+	// Render the SVG document, with the rectangles grouped by fill color.
+	// The rectangles were grouped when they were added, so the tag is copied
+	// and the groups are appended to the copy, leaving pi.svgTag as it is.
+	var buf bytes.Buffer
+	if pi.provenance {
+		pi.writeProvenance(&buf)
 	}
+	writeViews(&buf, pi.views)
+	pi.writeGroups(&buf)
+	svgTag := pi.svgTag.ShallowCopy()
+	svgTag.AppendContent(buf.Bytes())
+	svgDocument := append([]byte(xmlHeader), pi.addRootAttributes(svgTag.Bytes())...)
+
+	if pi.verbose {
+		fmt.Println("ok")
+		fmt.Print("Additional optimizations...")
+	}
+
+	svgDocument = optimize(svgDocument)
 
 	if pi.verbose {
 		fmt.Println("ok")
@@ -404,31 +630,55 @@ func (pi *PixelImage) Bytes() []byte {
 	return svgDocument
 }
 
+// writeFile writes the given SVG document to a file, or to stdout if filename is "-"
+func writeFile(filename string, svgDocument []byte) error {
+	if filename == "-" {
+		_, err := os.Stdout.Write(svgDocument)
+		return err
+	}
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(svgDocument)
+	return err
+}
+
+// ErrNotCovered is returned when writing an SVG document that does not cover all pixels
+var ErrNotCovered = errors.New("the SVG representation does not cover all pixels")
+
+// WriteSVGTo will write the current SVG document to the given io.Writer
+func (pi *PixelImage) WriteSVGTo(w io.Writer) error {
+	if !pi.Done(0, 0) {
+		return ErrNotCovered
+	}
+	_, err := w.Write(pi.encode(pi.Bytes()))
+	return err
+}
+
+// WriteSVGGzipTo will write the current SVG document to the given io.Writer,
+// compressed with gzip. This can be used for serving compressed SVG images
+// over HTTP, without using temporary files. Setting the
+// "Content-Encoding: gzip" header is left to the caller.
+func (pi *PixelImage) WriteSVGGzipTo(w io.Writer) error {
+	gzipWriter := gzip.NewWriter(w)
+	if err := pi.WriteSVGTo(gzipWriter); err != nil {
+		gzipWriter.Close()
+		return err
+	}
+	return gzipWriter.Close()
+}
+
 // WriteSVG will save the current SVG document to a file
 func (pi *PixelImage) WriteSVG(filename string) error {
-	var (
-		err error
-		f   *os.File
-	)
-
 	if !pi.Done(0, 0) {
-		return errors.New("the SVG representation does not cover all pixels")
+		return ErrNotCovered
 	}
 	if filename == "-" {
-		f = os.Stdout
 		// Turn off verbose messages, so that they don't end up in the SVG output
 		pi.verbose = false
-	} else {
-		f, err = os.Create(filename)
-		if err != nil {
-			return err
-		}
-		defer f.Close()
 	}
-
 	// Write the generated SVG image to file or to stdout
-	if _, err = f.Write(pi.Bytes()); err != nil {
-		return err
-	}
-	return nil
+	return writeFile(filename, pi.encode(pi.Bytes()))
 }
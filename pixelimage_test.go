@@ -0,0 +1,317 @@
+package png2svg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIndexedTransparency(t *testing.T) {
+	img, err := ReadPNG("testdata/indexed_trns.png", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := img.(*image.Paletted); !ok {
+		t.Fatalf("expected a paletted image, got %T", img)
+	}
+	pi := NewPixelImage(img, false)
+	transparent := 0
+	for y := 0; y < pi.Height(); y++ {
+		for x := 0; x < pi.Width(); x++ {
+			_, _, _, a := pi.At2(x, y)
+			if a == 0 {
+				transparent++
+				if !pi.Covered(x, y) {
+					t.Errorf("the transparent pixel (%d, %d) is not marked as covered", x, y)
+				}
+			}
+		}
+	}
+	if transparent != 48 {
+		t.Errorf("expected 48 transparent pixels, got %d", transparent)
+	}
+	pi.Cover()
+	r := checkRendersAs(t, pi.Bytes(), img, 0)
+	for y := 0; y < r.h; y++ {
+		for x := 0; x < r.w; x++ {
+			if _, _, _, a := img.At(x, y).RGBA(); a == 0 && r.Painted(x, y) > 0 {
+				t.Errorf("the transparent pixel (%d, %d) is painted", x, y)
+			}
+		}
+	}
+}
+
+func TestProvenance(t *testing.T) {
+	img := newTestImage(testPalette, "rb", "gg")
+	opts := Options{MaxAspect: 2}
+	convert := func(timestamp bool) []byte {
+		pi := NewPixelImage(img, false)
+		pi.SetOptions(opts)
+		pi.SetProvenance(true, timestamp)
+		pi.Cover()
+		return pi.Bytes()
+	}
+
+	svgDocument := convert(false)
+	checkRendersAs(t, svgDocument, img, 0)
+	var root svgNode
+	if err := xml.Unmarshal(svgDocument, &root); err != nil {
+		t.Fatal(err)
+	}
+	if len(root.Children) == 0 || root.Children[0].XMLName.Local != "metadata" {
+		t.Fatalf("expected a <metadata> tag first in %s", svgDocument)
+	}
+	if want := VersionString + "; " + opts.String(); root.Children[0].Text != want {
+		t.Errorf("expected the metadata %q, got %q", want, root.Children[0].Text)
+	}
+	if !bytes.Equal(svgDocument, convert(false)) {
+		t.Error("expected reproducible output without a timestamp")
+	}
+
+	var withTimestamp svgNode
+	if err := xml.Unmarshal(convert(true), &withTimestamp); err != nil {
+		t.Fatal(err)
+	}
+	fields := strings.Split(withTimestamp.Children[0].Text, "; ")
+	if _, err := time.Parse(time.RFC3339, fields[len(fields)-1]); err != nil {
+		t.Errorf("expected a timestamp at the end of the metadata: %v", err)
+	}
+}
+
+func TestWriteSVGTo(t *testing.T) {
+	img := newTestImage(testPalette, "rr.", "bbg")
+	pi := NewPixelImage(img, false)
+	pi.Cover()
+
+	var plain bytes.Buffer
+	if err := pi.WriteSVGTo(&plain); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(plain.Bytes(), pi.Bytes()) {
+		t.Error("expected WriteSVGTo to write the same document as Bytes")
+	}
+	checkRendersAs(t, plain.Bytes(), img, 0)
+
+	var compressed bytes.Buffer
+	if err := pi.WriteSVGGzipTo(&compressed); err != nil {
+		t.Fatal(err)
+	}
+	gzipReader, err := gzip.NewReader(&compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decompressed, err := ioutil.ReadAll(gzipReader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decompressed, plain.Bytes()) {
+		t.Error("expected the decompressed document to be the same as the plain document")
+	}
+
+	// An image that is not covered is not written
+	var buf bytes.Buffer
+	if err := NewPixelImage(img, false).WriteSVGTo(&buf); err != ErrNotCovered {
+		t.Errorf("expected ErrNotCovered, got %v", err)
+	}
+	if err := NewPixelImage(img, false).WriteSVGGzipTo(&buf); err != ErrNotCovered {
+		t.Errorf("expected ErrNotCovered, got %v", err)
+	}
+}
+
+func TestChromaKey(t *testing.T) {
+	palette := map[byte]color.NRGBA{
+		'm': {255, 0, 255, 255},
+		'n': {250, 4, 252, 255}, // close to magenta
+		'r': {255, 0, 0, 255},
+		'.': {0, 0, 0, 0},
+	}
+	img := newTestImage(palette,
+		"mmnm",
+		"mrrn",
+		"mrrm",
+	)
+	for _, tc := range []struct {
+		tolerance int
+		count     int
+		want      *image.NRGBA
+	}{
+		{0, 6, newTestImage(palette, "..n.", ".rrn", ".rr.")},
+		{5, 8, newTestImage(palette, "....", ".rr.", ".rr.")},
+	} {
+		pi := NewPixelImage(img, false)
+		if count := pi.SetChromaKey(palette['m'], tc.tolerance); count != tc.count {
+			t.Errorf("tolerance %d: expected %d transparent pixels, got %d", tc.tolerance, tc.count, count)
+		}
+		pi.Cover()
+		r := checkRendersAs(t, pi.Bytes(), tc.want, 0)
+		for y := 0; y < r.h; y++ {
+			for x := 0; x < r.w; x++ {
+				if tc.want.NRGBAAt(x, y).A == 0 && r.Painted(x, y) > 0 {
+					t.Errorf("tolerance %d: the keyed pixel (%d, %d) is painted", tc.tolerance, x, y)
+				}
+			}
+		}
+	}
+}
+
+func TestUncovered(t *testing.T) {
+	img := newTestImage(testPalette,
+		"r.b",
+		".gg",
+	)
+	pi := NewPixelImage(img, false)
+	want := []image.Point{{0, 0}, {2, 0}, {1, 1}, {2, 1}}
+	if got := pi.Uncovered(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the uncovered pixels %v, got %v", want, got)
+	}
+	// Cover the first row only
+	pi.CoverBox(pi.CreateBox(0, 0), false, false)
+	pi.CoverBox(pi.CreateBox(2, 0), false, false)
+	want = want[2:]
+	if got := pi.Uncovered(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected the uncovered pixels %v, got %v", want, got)
+	}
+	var buf bytes.Buffer
+	if err := pi.WriteSVGTo(&buf); err != ErrNotCovered {
+		t.Errorf("expected ErrNotCovered, got %v", err)
+	}
+	pi.Cover()
+	if got := pi.Uncovered(); len(got) != 0 {
+		t.Errorf("expected all pixels to be covered, got %v", got)
+	}
+}
+
+func TestShortestColor(t *testing.T) {
+	for _, tc := range []struct {
+		fill          string
+		colorOptimize bool
+		want          string
+	}{
+		{"#ff0000", false, "red"},
+		{"#FF0000", false, "red"},
+		{"#0000ff", false, "#00f"},
+		{"#d2b48c", false, "tan"},
+		{"#f0ffff", false, "azure"},
+		{"#123456", false, "#123456"},
+		{"#123456", true, "#135"},
+		{"#f00", false, "red"},
+		{"#000080", false, "navy"},
+		{"rgb(1,2,3)", false, "rgb(1,2,3)"},
+		{"currentColor", false, "currentColor"},
+	} {
+		if got := string(shortestColor([]byte(tc.fill), tc.colorOptimize)); got != tc.want {
+			t.Errorf("shortestColor(%q, %v) = %q, expected %q", tc.fill, tc.colorOptimize, got, tc.want)
+		}
+	}
+
+	// The shortest representation is used for each color group
+	palette := map[byte]color.NRGBA{
+		't': {0xd2, 0xb4, 0x8c, 255},
+		'n': {0, 0, 0x80, 255},
+		'x': {0x12, 0x34, 0x56, 255},
+	}
+	img := newTestImage(palette, "ttn", "xnn")
+	svgDocument := convertBytes(t, img, Options{})
+	for _, fill := range []string{`fill="tan"`, `fill="navy"`, `fill="#123456"`} {
+		svgContains(t, svgDocument, fill)
+	}
+	checkRendersAs(t, svgDocument, img, 0)
+}
+
+func TestRootAttributes(t *testing.T) {
+	img := newTestImage(testPalette, "rb")
+	for _, tc := range []struct {
+		id, class string
+	}{
+		{"logo", ""},
+		{"", "icon large"},
+		{"a&b", `"quoted"`},
+	} {
+		svgDocument := convertBytes(t, img, Options{RootID: tc.id, RootClass: tc.class})
+		var root svgNode
+		if err := xml.Unmarshal(svgDocument, &root); err != nil {
+			t.Fatalf("invalid SVG document for id %q and class %q: %v", tc.id, tc.class, err)
+		}
+		id, hasID := root.attr("id")
+		class, hasClass := root.attr("class")
+		if id != tc.id || hasID != (tc.id != "") || class != tc.class || hasClass != (tc.class != "") {
+			t.Errorf("expected the id %q and class %q, got %q and %q", tc.id, tc.class, id, class)
+		}
+		checkRendersAs(t, svgDocument, img, 0)
+	}
+}
+
+func TestReset(t *testing.T) {
+	// The first image is a horizontal gradient, that is covered with a <linearGradient>
+	gradientImage := image.NewNRGBA(image.Rect(0, 0, 8, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 8; x++ {
+			gradientImage.SetNRGBA(x, y, color.NRGBA{uint8(x * 32), 0, 255, 255})
+		}
+	}
+	second := newTestImage(testPalette,
+		"rrb",
+		".gg",
+		"www",
+	)
+
+	pi := NewPixelImage(gradientImage, false)
+	pi.SetOptions(Options{Gradients: true, GradientTolerance: 2, Deadline: time.Hour})
+	pi.Cover()
+	first := pi.Bytes()
+	svgContains(t, first, "<linearGradient")
+	checkRendersAs(t, first, gradientImage, 3)
+
+	for _, img := range []*image.NRGBA{second, gradientImage, second} {
+		pi.Reset(img)
+		pi.Cover()
+		svgDocument := pi.Bytes()
+		checkRendersAs(t, svgDocument, img, 3)
+		// Nothing from the previous conversion is left
+		if img == second && bytes.Contains(svgDocument, []byte("<linearGradient")) {
+			t.Errorf("expected no gradients after Reset, got %s", svgDocument)
+		}
+		fresh := NewPixelImage(img, false)
+		fresh.SetOptions(pi.Options())
+		fresh.Cover()
+		if want := fresh.Bytes(); !bytes.Equal(svgDocument, want) {
+			t.Errorf("expected the same document as with a new PixelImage:\n%s\ngot\n%s", want, svgDocument)
+		}
+		if got, want := pi.Stats(), fresh.Stats(); got != want {
+			t.Errorf("expected the stats %+v, got %+v", want, got)
+		}
+	}
+}
+
+func TestResetWithOtherOptions(t *testing.T) {
+	gradientImage := image.NewNRGBA(image.Rect(0, 0, 8, 2))
+	for y := 0; y < 2; y++ {
+		for x := 0; x < 8; x++ {
+			gradientImage.SetNRGBA(x, y, color.NRGBA{uint8(x * 32), 0, 255, 255})
+		}
+	}
+	pi := NewPixelImage(gradientImage, false)
+	pi.SetOptions(Options{Gradients: true, GradientTolerance: 2})
+	pi.Cover()
+
+	// The gradients of the first image are not kept when gradients are turned off
+	second := newTestImage(testPalette, "rrb", ".gg")
+	pi.Reset(second)
+	pi.SetOptions(Options{})
+	pi.Cover()
+	svgDocument := pi.Bytes()
+	if bytes.Contains(svgDocument, []byte("<linearGradient")) {
+		t.Errorf("expected no gradients after Reset, got %s", svgDocument)
+	}
+	checkRendersAs(t, svgDocument, second, 0)
+	if stats := pi.Stats(); stats.Rects != 3 {
+		t.Errorf("expected 3 rectangles, got %d", stats.Rects)
+	}
+}
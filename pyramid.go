@@ -0,0 +1,106 @@
+package png2svg
+
+import (
+	"encoding/json"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// PyramidLevel describes one zoom level of a tile pyramid
+type PyramidLevel struct {
+	Level   int `json:"level"`
+	Width   int `json:"width"`
+	Height  int `json:"height"`
+	Columns int `json:"columns"`
+	Rows    int `json:"rows"`
+	// Downsample is how many pixels in each direction of the original image each pixel represents
+	Downsample int `json:"downsample"`
+}
+
+// PyramidManifest describes a tile pyramid, as written by WritePyramid
+type PyramidManifest struct {
+	Width    int            `json:"width"`
+	Height   int            `json:"height"`
+	TileSize int            `json:"tileSize"`
+	Format   string         `json:"format"`
+	Levels   []PyramidLevel `json:"levels"`
+}
+
+// cropImage copies the given rectangle of an image to a new image that starts at (0, 0)
+func cropImage(img image.Image, r image.Rectangle) *image.NRGBA {
+	r = r.Intersect(img.Bounds())
+	cropped := image.NewNRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			cropped.SetNRGBA(x-r.Min.X, y-r.Min.Y, color.NRGBAModel.Convert(img.At(x, y)).(color.NRGBA))
+		}
+	}
+	return cropped
+}
+
+// WritePyramid converts the given image to a pyramid of SVG tiles, for deep zoom viewers.
+// Level 0 is the smallest level, and each following level is twice as large, up until
+// the last level, which has the same size as the image. Each level is downsampled with
+// Downsample and split into tiles of tileSize x tileSize pixels, that are converted
+// separately and written to dir/level/column_row.svg. A manifest that describes the
+// levels is written to dir/pyramid.json. Each tile is written like WriteSVG, so
+// opts.Provenance, opts.CRLF and opts.BOM are used for every tile. ErrTooManyRects is returned
+// if a tile needs more than opts.MaxRects rectangles, and ErrNotCovered if a tile could not be covered.
+func WritePyramid(img image.Image, opts Options, dir string, levels, tileSize int) error {
+	if levels < 1 {
+		levels = 1
+	}
+	if tileSize < 1 {
+		tileSize = 256
+	}
+	bounds := img.Bounds()
+	manifest := PyramidManifest{
+		Width:    bounds.Dx(),
+		Height:   bounds.Dy(),
+		TileSize: tileSize,
+		Format:   "svg",
+	}
+	for level := 0; level < levels; level++ {
+		n := 1 << uint(levels-1-level)
+		levelImage := Downsample(img, n)
+		w, h := levelImage.Bounds().Dx(), levelImage.Bounds().Dy()
+		pyramidLevel := PyramidLevel{
+			Level:      level,
+			Width:      w,
+			Height:     h,
+			Columns:    (w + tileSize - 1) / tileSize,
+			Rows:       (h + tileSize - 1) / tileSize,
+			Downsample: n,
+		}
+		levelDir := filepath.Join(dir, strconv.Itoa(level))
+		if err := os.MkdirAll(levelDir, 0755); err != nil {
+			return err
+		}
+		min := levelImage.Bounds().Min
+		for row := 0; row < pyramidLevel.Rows; row++ {
+			for column := 0; column < pyramidLevel.Columns; column++ {
+				tileRect := image.Rect(column*tileSize, row*tileSize, (column+1)*tileSize, (row+1)*tileSize).Add(min)
+				pi := NewPixelImage(cropImage(levelImage, tileRect), false)
+				pi.SetOptions(opts)
+				pi.Cover()
+				if pi.TooManyRects() {
+					return ErrTooManyRects
+				}
+				filename := filepath.Join(levelDir, strconv.Itoa(column)+"_"+strconv.Itoa(row)+".svg")
+				if err := pi.WriteSVG(filename); err != nil {
+					return err
+				}
+			}
+		}
+		manifest.Levels = append(manifest.Levels, pyramidLevel)
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, "pyramid.json"), append(data, '\n'), 0644)
+}
@@ -0,0 +1,62 @@
+package png2svg
+
+import (
+	"encoding/json"
+	"image"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestWritePyramid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "png2svg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	img := noiseImage(10, 6, 3)
+	if err := WritePyramid(img, Options{}, dir, 2, 4); err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, "pyramid.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var manifest PyramidManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatal(err)
+	}
+	want := []PyramidLevel{
+		{Level: 0, Width: 5, Height: 3, Columns: 2, Rows: 1, Downsample: 2},
+		{Level: 1, Width: 10, Height: 6, Columns: 3, Rows: 2, Downsample: 1},
+	}
+	if manifest.Width != 10 || manifest.Height != 6 || manifest.TileSize != 4 || len(manifest.Levels) != len(want) {
+		t.Fatalf("unexpected manifest: %+v", manifest)
+	}
+	for i, level := range manifest.Levels {
+		if level != want[i] {
+			t.Errorf("level %d is %+v, expected %+v", i, level, want[i])
+		}
+		// Each tile looks like its part of the downsampled image
+		levelImage := Downsample(img, level.Downsample)
+		for row := 0; row < level.Rows; row++ {
+			for column := 0; column < level.Columns; column++ {
+				filename := filepath.Join(dir, strconv.Itoa(level.Level), strconv.Itoa(column)+"_"+strconv.Itoa(row)+".svg")
+				svgDocument, err := ioutil.ReadFile(filename)
+				if err != nil {
+					t.Fatal(err)
+				}
+				tile := cropImage(levelImage, image.Rect(column*4, row*4, column*4+4, row*4+4))
+				checkRendersAs(t, svgDocument, tile, 0)
+			}
+		}
+	}
+
+	// Each tile must be covered with at most MaxRects rectangles
+	if err := WritePyramid(img, Options{MaxRects: 2}, dir, 2, 4); err != ErrTooManyRects {
+		t.Errorf("expected ErrTooManyRects, got %v", err)
+	}
+}
@@ -0,0 +1,136 @@
+package png2svg
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Rounding decides how a color channel (0..255) is mapped to a single hex digit,
+// when colors are limited to 4096 colors (#abc instead of #aabbcc).
+type Rounding int
+
+const (
+	// RoundFloor uses the first hex digit of the channel value, #1f -> #1 (the default)
+	RoundFloor Rounding = iota
+	// RoundNearest uses the representable level (#00, #11 ... #ff) that is closest to the channel value
+	RoundNearest
+	// RoundCeil uses the lowest representable level that is larger than or equal to the channel value
+	RoundCeil
+)
+
+// String returns the name of the rounding mode, as used by NewRounding
+func (rounding Rounding) String() string {
+	switch rounding {
+	case RoundNearest:
+		return "round"
+	case RoundCeil:
+		return "ceil"
+	default:
+		return "floor"
+	}
+}
+
+// ErrUnknownRounding is returned by NewRounding if the rounding mode is not recognized
+var ErrUnknownRounding = errors.New("unknown rounding mode, use floor, round or ceil")
+
+// NewRounding returns a Rounding, given "floor", "round" or "ceil"
+func NewRounding(mode string) (Rounding, error) {
+	switch mode {
+	case "floor", "":
+		return RoundFloor, nil
+	case "round", "nearest":
+		return RoundNearest, nil
+	case "ceil":
+		return RoundCeil, nil
+	}
+	return RoundFloor, ErrUnknownRounding
+}
+
+// quantizeChannel maps a color channel value (0..255) to a hex digit (0..15),
+// using the given rounding mode. The digit d represents the level d*17.
+func quantizeChannel(x int, rounding Rounding) int {
+	switch rounding {
+	case RoundNearest:
+		return (x + 8) / 17
+	case RoundCeil:
+		return (x + 16) / 17
+	default:
+		return x >> 4
+	}
+}
+
+// DistanceFunc returns the distance between two colors.
+// It is used when finding the nearest color.
+type DistanceFunc func(r1, g1, b1, r2, g2, b2 int) float64
+
+// SquaredEuclidean returns the squared euclidean distance between two colors.
+// This is the default DistanceFunc.
+func SquaredEuclidean(r1, g1, b1, r2, g2, b2 int) float64 {
+	dr, dg, db := r1-r2, g1-g2, b1-b2
+	return float64(dr*dr + dg*dg + db*db)
+}
+
+// quantizeNearest maps a color to the hex digits of the nearest color of the 4096
+// colors that can be written as #abc, using the given distance function.
+// Only the levels directly below and above each channel value are considered.
+func quantizeNearest(r, g, b int, distance DistanceFunc) (int, int, int) {
+	var (
+		rs         = [2]int{r / 17, (r + 16) / 17}
+		gs         = [2]int{g / 17, (g + 16) / 17}
+		bs         = [2]int{b / 17, (b + 16) / 17}
+		bestDist   = -1.0
+		qr, qg, qb int
+	)
+	for _, dr := range rs {
+		for _, dg := range gs {
+			for _, db := range bs {
+				if d := distance(r, g, b, dr*17, dg*17, db*17); bestDist < 0 || d < bestDist {
+					bestDist = d
+					qr, qg, qb = dr, dg, db
+				}
+			}
+		}
+	}
+	return qr, qg, qb
+}
+
+// quantize maps a color to the hex digits of one of the 4096 colors that can be
+// written as #abc, using the selected rounding mode. If the rounding mode is
+// RoundNearest and a distance function is set, it is used for finding the nearest color.
+func (pi *PixelImage) quantize(r, g, b int) (int, int, int) {
+	if pi.rounding == RoundNearest && pi.distance != nil {
+		return quantizeNearest(r, g, b, pi.distance)
+	}
+	return quantizeChannel(r, pi.rounding), quantizeChannel(g, pi.rounding), quantizeChannel(b, pi.rounding)
+}
+
+// SetAlphaLevels snaps the alpha of all pixels to the nearest of the given number
+// of evenly spaced levels, from fully transparent to opaque. For instance, 4 levels
+// gives the alpha values 0, 85, 170 and 255. This reduces the number of distinct
+// fill opacities, and thus the number of groups, for translucent images.
+// Pixels that become fully transparent are marked as covered.
+// Returns the number of distinct alpha values that are used by the pixels that
+// are not fully transparent. Less than 2 levels does nothing.
+func (pi *PixelImage) SetAlphaLevels(levels int) int {
+	if levels < 2 {
+		return 0
+	}
+	pi.alphaLevels = levels
+	steps := levels - 1
+	used := make(map[int]bool)
+	for _, p := range pi.pixels {
+		if p.a == 0 {
+			continue
+		}
+		p.a = ((p.a*steps+127)/255*255 + steps/2) / steps
+		if p.a == 0 {
+			p.covered = true
+			continue
+		}
+		used[p.a] = true
+	}
+	if pi.verbose {
+		fmt.Printf("Using %d distinct opacity levels.\n", len(used))
+	}
+	return len(used)
+}
@@ -0,0 +1,128 @@
+package png2svg
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestQuantizeChannel(t *testing.T) {
+	for _, tc := range []struct {
+		x                  int
+		floor, round, ceil int
+	}{
+		{0x00, 0x0, 0x0, 0x0},
+		{0x11, 0x1, 0x1, 0x1},
+		{0x1f, 0x1, 0x2, 0x2},
+		{0x19, 0x1, 0x1, 0x2},
+		{0x1a, 0x1, 0x2, 0x2},
+		{0xf0, 0xf, 0xe, 0xf},
+		{0xff, 0xf, 0xf, 0xf},
+	} {
+		if got := quantizeChannel(tc.x, RoundFloor); got != tc.floor {
+			t.Errorf("floor(%#x) = %#x, expected %#x", tc.x, got, tc.floor)
+		}
+		if got := quantizeChannel(tc.x, RoundNearest); got != tc.round {
+			t.Errorf("round(%#x) = %#x, expected %#x", tc.x, got, tc.round)
+		}
+		if got := quantizeChannel(tc.x, RoundCeil); got != tc.ceil {
+			t.Errorf("ceil(%#x) = %#x, expected %#x", tc.x, got, tc.ceil)
+		}
+	}
+}
+
+func TestNewRounding(t *testing.T) {
+	for _, rounding := range []Rounding{RoundFloor, RoundNearest, RoundCeil} {
+		got, err := NewRounding(rounding.String())
+		if err != nil || got != rounding {
+			t.Errorf("NewRounding(%q) = %v, %v", rounding.String(), got, err)
+		}
+	}
+	if _, err := NewRounding("sideways"); err != ErrUnknownRounding {
+		t.Errorf("expected ErrUnknownRounding, got %v", err)
+	}
+}
+
+func TestRoundingFill(t *testing.T) {
+	img := newTestImage(map[byte]color.NRGBA{'x': {0x1f, 0x89, 0xee, 255}}, "xx")
+	for _, tc := range []struct {
+		rounding Rounding
+		fill     string
+	}{
+		{RoundFloor, `fill="#18e"`},
+		{RoundNearest, `fill="#28e"`},
+		{RoundCeil, `fill="#29e"`},
+	} {
+		svgDocument := convertBytes(t, img, Options{ColorOptimize: true, Rounding: tc.rounding})
+		svgContains(t, svgDocument, tc.fill)
+	}
+}
+
+func TestQuantizeNearest(t *testing.T) {
+	// With the squared euclidean distance, the nearest color is found per channel
+	for r := 0; r < 256; r += 7 {
+		for g := 0; g < 256; g += 11 {
+			for b := 0; b < 256; b += 13 {
+				qr, qg, qb := quantizeNearest(r, g, b, SquaredEuclidean)
+				if qr != quantizeChannel(r, RoundNearest) || qg != quantizeChannel(g, RoundNearest) || qb != quantizeChannel(b, RoundNearest) {
+					t.Fatalf("the nearest color of (%d, %d, %d) is (%#x, %#x, %#x), expected the same as when rounding each channel", r, g, b, qr, qg, qb)
+				}
+			}
+		}
+	}
+
+	// A distance function that only cares about the red channel picks the lower levels for the others
+	redOnly := func(r1, g1, b1, r2, g2, b2 int) float64 {
+		return float64((r1 - r2) * (r1 - r2))
+	}
+	pi := NewPixelImage(image.NewNRGBA(image.Rect(0, 0, 1, 1)), false)
+	pi.SetOptions(Options{ColorOptimize: true, Rounding: RoundNearest, DistanceFunc: redOnly})
+	if got := string(pi.colorBytes(0x1f, 0xfa, 0xee, 255, true)); got != "#2ee" {
+		t.Errorf("expected #2ee with the custom distance function, got %s", got)
+	}
+	pi.SetOptions(Options{ColorOptimize: true, Rounding: RoundNearest})
+	if got := string(pi.colorBytes(0x1f, 0xfa, 0xee, 255, true)); got != "#2fe" {
+		t.Errorf("expected #2fe, got %s", got)
+	}
+}
+
+func TestAlphaLevels(t *testing.T) {
+	for _, tc := range []struct {
+		levels int
+		alphas map[uint8]uint8
+	}{
+		{2, map[uint8]uint8{1: 0, 127: 0, 128: 255, 255: 255}},
+		{4, map[uint8]uint8{1: 0, 42: 0, 43: 85, 100: 85, 128: 170, 212: 170, 213: 255, 255: 255}},
+		{5, map[uint8]uint8{31: 0, 32: 64, 96: 128, 160: 191, 224: 255}},
+	} {
+		img := image.NewNRGBA(image.Rect(0, 0, len(tc.alphas), 1))
+		x := 0
+		for a := range tc.alphas {
+			img.SetNRGBA(x, 0, color.NRGBA{0, 0, 255, a})
+			x++
+		}
+		pi := NewPixelImage(img, false)
+		used := make(map[uint8]bool)
+		for _, want := range tc.alphas {
+			if want > 0 {
+				used[want] = true
+			}
+		}
+		if n := pi.SetAlphaLevels(tc.levels); n != len(used) {
+			t.Errorf("%d levels: expected %d alpha values to be used, got %d", tc.levels, len(used), n)
+		}
+		pi.SetColorFormat(ColorRGBA)
+		pi.Cover()
+		r := renderSVG(t, pi.Bytes())
+		for x := 0; x < img.Bounds().Dx(); x++ {
+			a := img.NRGBAAt(x, 0).A
+			want := tc.alphas[a]
+			if got := r.At(x, 0).A; got != want {
+				t.Errorf("%d levels: expected the alpha %d to become %d, got %d", tc.levels, a, want, got)
+			}
+			if painted := r.Painted(x, 0); (want == 0 && painted != 0) || (want > 0 && painted != 1) {
+				t.Errorf("%d levels: the pixel with alpha %d is painted %d times", tc.levels, a, painted)
+			}
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package png2svg
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"os"
+	"strings"
+)
+
+// SetRecolor replaces the colors of the pixels that have one of the colors in the
+// given map with the corresponding new color. Only the red, green and blue channels
+// are compared and replaced, the alpha of each pixel is kept as it is. Colors that
+// are not in the map are left unchanged. Returns the number of pixels that were changed.
+func (pi *PixelImage) SetRecolor(replacements map[color.NRGBA]color.NRGBA) int {
+	pi.recolor = replacements
+	count := 0
+	for _, p := range pi.pixels {
+		if p.a == 0 {
+			continue
+		}
+		if c, ok := replacements[color.NRGBA{uint8(p.r), uint8(p.g), uint8(p.b), 0xff}]; ok {
+			p.r, p.g, p.b = int(c.R), int(c.G), int(c.B)
+			count++
+		}
+	}
+	if pi.verbose {
+		fmt.Printf("Changed the color of %d pixels.\n", count)
+	}
+	return count
+}
+
+// ReadRecolorFile reads color replacements from a text file, for SetRecolor.
+// Each line has an old and a new hex color, like "#ff0000 #0000ff" or
+// "#ff0000 -> #0000ff". Empty lines are ignored. The returned colors are opaque.
+func ReadRecolorFile(filename string) (map[color.NRGBA]color.NRGBA, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	replacements := make(map[color.NRGBA]color.NRGBA)
+	scanner := bufio.NewScanner(f)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) == 3 && (fields[1] == "->" || fields[1] == "→") {
+			fields = []string{fields[0], fields[2]}
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%s:%d: expected an old and a new color, like #ff0000 #0000ff", filename, lineNumber)
+		}
+		from, err := ParseHexColor(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", filename, lineNumber, err)
+		}
+		to, err := ParseHexColor(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", filename, lineNumber, err)
+		}
+		replacements[from] = to
+	}
+	return replacements, scanner.Err()
+}
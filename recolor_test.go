@@ -0,0 +1,97 @@
+package png2svg
+
+import (
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecolor(t *testing.T) {
+	img := newTestImage(testPalette,
+		"rrb.",
+		"hgbr",
+	)
+	replacements := map[color.NRGBA]color.NRGBA{
+		testPalette['r']: testPalette['y'],
+		testPalette['b']: testPalette['r'],
+		testPalette['k']: testPalette['w'],
+	}
+	pi := NewPixelImage(img, false)
+	// The translucent red pixel is also recolored, but keeps its alpha
+	if got := pi.SetRecolor(replacements); got != 6 {
+		t.Errorf("expected 6 pixels to be changed, got %d", got)
+	}
+	pi.SetColorFormat(ColorRGBA)
+	pi.Cover()
+	// Blue becomes red, but red that has been changed to yellow is not changed again
+	want := newTestImage(map[byte]color.NRGBA{
+		'y': testPalette['y'],
+		'r': testPalette['r'],
+		'g': testPalette['g'],
+		'.': {},
+		'h': {255, 255, 0, 128},
+	},
+		"yyr.",
+		"hgry",
+	)
+	checkRendersAs(t, pi.Bytes(), want, 1)
+
+	if got := convertBytes(t, img, Options{Recolor: replacements}); string(got) == string(convertBytes(t, img, Options{})) {
+		t.Error("expected Options.Recolor to change the colors")
+	}
+}
+
+func TestReadRecolorFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "png2svg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for _, tc := range []struct {
+		contents string
+		want     map[color.NRGBA]color.NRGBA
+		err      string
+	}{
+		{"#ff0000 #0000ff\n\n#000 -> #fff\n#0f0 → #ff0\n", map[color.NRGBA]color.NRGBA{
+			{255, 0, 0, 255}: {0, 0, 255, 255},
+			{0, 0, 0, 255}:   {255, 255, 255, 255},
+			{0, 255, 0, 255}: {255, 255, 0, 255},
+		}, ""},
+		{"", map[color.NRGBA]color.NRGBA{}, ""},
+		{"#ff0000 #0000ff\n#ff0000\n", nil, ":2: expected an old and a new color"},
+		{"#ff0000 => #0000ff\n", nil, ":1: expected an old and a new color"},
+		{"#ff0000 #00f #0f0\n", nil, ":1: expected an old and a new color"},
+		{"\n#ff000g #0000ff\n", nil, ":2: " + ErrInvalidHexColor.Error()},
+		{"#ff0000 blue\n", nil, ":1: " + ErrInvalidHexColor.Error()},
+	} {
+		filename := filepath.Join(dir, "colors.txt")
+		if err := ioutil.WriteFile(filename, []byte(tc.contents), 0644); err != nil {
+			t.Fatal(err)
+		}
+		got, err := ReadRecolorFile(filename)
+		if tc.err != "" {
+			if err == nil || !strings.HasPrefix(err.Error(), filename+tc.err) {
+				t.Errorf("%q: expected an error starting with %q, got %v", tc.contents, filename+tc.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tc.contents, err)
+			continue
+		}
+		if len(got) != len(tc.want) {
+			t.Errorf("%q: expected %d replacements, got %v", tc.contents, len(tc.want), got)
+		}
+		for from, to := range tc.want {
+			if got[from] != to {
+				t.Errorf("%q: expected %v to be replaced with %v, got %v", tc.contents, from, to, got[from])
+			}
+		}
+	}
+	if _, err := ReadRecolorFile(filepath.Join(dir, "missing.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected a missing file to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,65 @@
+package png2svg
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// jsonRect is a rectangle in the JSON output, with the color as "#rrggbb"
+// and the opacity between 0 and 1, if the color is not opaque
+type jsonRect struct {
+	X       int      `json:"x"`
+	Y       int      `json:"y"`
+	W       int      `json:"w"`
+	H       int      `json:"h"`
+	Color   string   `json:"color"`
+	Opacity *float64 `json:"opacity,omitempty"`
+}
+
+// jsonDocument is the JSON output, with the size of the canvas and all rectangles
+type jsonDocument struct {
+	Width  int        `json:"width"`
+	Height int        `json:"height"`
+	Rects  []jsonRect `json:"rects"`
+}
+
+// JSON returns the placed rectangles as JSON, for renderers in other languages:
+//
+//	{"width": 16, "height": 16, "rects": [{"x": 0, "y": 0, "w": 16, "h": 1, "color": "#ffffff"}, ...]}
+//
+// The rectangles are in the order they were placed, and the colors are always
+// on the form "#rrggbb", regardless of the color format. Colors that are not
+// opaque also have an "opacity" between 0 and 1. If the opaque region is clipped,
+// the clipped background rectangle is not included.
+func (pi *PixelImage) JSON() ([]byte, error) {
+	doc := jsonDocument{Width: pi.w, Height: pi.h, Rects: make([]jsonRect, 0, len(pi.rects))}
+	for _, rect := range pi.rects {
+		// All pixels within a rectangle have the same color
+		p := pi.pixels[rect.Y*pi.w+rect.X]
+		r, g, b := p.r, p.g, p.b
+		if pi.colorOptimize {
+			qr, qg, qb := pi.quantize(r, g, b)
+			r, g, b = qr*17, qg*17, qb*17
+		}
+		jr := jsonRect{X: rect.X, Y: rect.Y, W: rect.Width, H: rect.Height, Color: string(hexColor(r, g, b))}
+		if p.a < 255 {
+			opacity, _ := strconv.ParseFloat(formatAlpha(p.a), 64)
+			jr.Opacity = &opacity
+		}
+		doc.Rects = append(doc.Rects, jr)
+	}
+	return json.Marshal(doc)
+}
+
+// WriteJSON will save the placed rectangles as JSON to a file,
+// or write it to stdout if filename is "-"
+func (pi *PixelImage) WriteJSON(filename string) error {
+	if !pi.Done(0, 0) {
+		return ErrNotCovered
+	}
+	data, err := pi.JSON()
+	if err != nil {
+		return err
+	}
+	return writeFile(filename, append(data, '\n'))
+}
@@ -0,0 +1,65 @@
+package png2svg
+
+import (
+	"encoding/json"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+func TestJSON(t *testing.T) {
+	img := newTestImage(testPalette,
+		"rrb.",
+		"rrhh",
+		"gggw",
+	)
+	pi := NewPixelImage(img, false)
+	pi.SetColorFormat(ColorRGB) // the JSON colors are hex colors, regardless of the color format
+	pi.Cover()
+	data, err := pi.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Width, Height int
+		Rects         []struct {
+			X, Y, W, H int
+			Color      string
+			Opacity    *float64
+		}
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc.Width != 4 || doc.Height != 3 || len(doc.Rects) != len(pi.rects) {
+		t.Fatalf("unexpected JSON document: %s", data)
+	}
+
+	// Painting the rectangles gives the same image
+	painted := image.NewNRGBA(image.Rect(0, 0, doc.Width, doc.Height))
+	for _, rect := range doc.Rects {
+		c, err := ParseHexColor(rect.Color)
+		if err != nil || len(rect.Color) != 7 {
+			t.Fatalf("expected a #rrggbb color, got %q", rect.Color)
+		}
+		if rect.Opacity != nil {
+			c.A = uint8(math.Round(*rect.Opacity * 255))
+		}
+		for y := rect.Y; y < rect.Y+rect.H; y++ {
+			for x := rect.X; x < rect.X+rect.W; x++ {
+				if painted.NRGBAAt(x, y) != (color.NRGBA{}) {
+					t.Errorf("pixel (%d, %d) is covered by more than one rectangle", x, y)
+				}
+				painted.SetNRGBA(x, y, c)
+			}
+		}
+	}
+	for y := 0; y < doc.Height; y++ {
+		for x := 0; x < doc.Width; x++ {
+			if got, want := painted.NRGBAAt(x, y), img.NRGBAAt(x, y); got != want {
+				t.Errorf("pixel (%d, %d) is %v, expected %v", x, y, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package png2svg
+
+// SetRemoveRedundant can be used for removing redundant rectangles with
+// RemoveRedundantRects, after the pixels have been covered by Cover
+func (pi *PixelImage) SetRemoveRedundant(enabled bool) {
+	pi.removeRedundant = enabled
+}
+
+// RemoveRedundantRects removes the rectangles that are fully covered by other
+// rectangles with the same fill color, which can happen when boxes are expanded
+// over pixels that are already covered. The rectangles are checked in the order
+// they were placed, so that an earlier rectangle is removed in favor of the later
+// rectangles that cover it. Returns the number of rectangles that were removed.
+func (pi *PixelImage) RemoveRedundantRects() int {
+	var (
+		byFill  = make(map[string][]int) // indices into pi.rects, per fill color
+		fills   []string
+		counts  = make([]int, pi.w*pi.h) // how many rectangles of the current color cover each pixel
+		removed = make([]bool, len(pi.rects))
+		count   int
+	)
+	for i, rect := range pi.rects {
+		if _, ok := byFill[rect.Fill]; !ok {
+			fills = append(fills, rect.Fill)
+		}
+		byFill[rect.Fill] = append(byFill[rect.Fill], i)
+	}
+	// add adds delta to the counts of all pixels covered by the given rectangle
+	add := func(rect Rect, delta int) {
+		for y := rect.Y; y < rect.Y+rect.Height; y++ {
+			for x := rect.X; x < rect.X+rect.Width; x++ {
+				counts[y*pi.w+x] += delta
+			}
+		}
+	}
+	// covered returns true if all pixels of the rectangle are covered by another rectangle
+	covered := func(rect Rect) bool {
+		for y := rect.Y; y < rect.Y+rect.Height; y++ {
+			for x := rect.X; x < rect.X+rect.Width; x++ {
+				if counts[y*pi.w+x] < 2 {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	for _, fill := range fills {
+		indices := byFill[fill]
+		for _, i := range indices {
+			add(pi.rects[i], 1)
+		}
+		for _, i := range indices {
+			if covered(pi.rects[i]) {
+				add(pi.rects[i], -1)
+				removed[i] = true
+				count++
+			}
+		}
+		// Reset the counts for the next color
+		for _, i := range indices {
+			if !removed[i] {
+				add(pi.rects[i], -1)
+			}
+		}
+	}
+	if count == 0 {
+		return 0
+	}
+
+	// Place the remaining rectangles in their groups again
+	for _, group := range pi.groupOrder {
+		group.rects.Reset()
+		group.count = 0
+	}
+	rects := pi.rects[:0]
+	for i, rect := range pi.rects {
+		if removed[i] {
+			continue
+		}
+		group := pi.groups[rect.Fill]
+		pi.writeRect(&group.rects, rect.X, rect.Y, rect.Width, rect.Height)
+		group.count++
+		rects = append(rects, rect)
+	}
+	pi.rects = rects
+	return count
+}
@@ -0,0 +1,49 @@
+package png2svg
+
+import (
+	"testing"
+)
+
+func TestRemoveRedundantRects(t *testing.T) {
+	img := newTestImage(testPalette,
+		"rrrb",
+		"rrrb",
+		"bbbb",
+	)
+	pi := NewPixelImage(img, false)
+	// Place overlapping boxes by hand: the first red box is covered by the two
+	// later red boxes, while the blue boxes only overlap each other partially
+	for _, box := range []*Box{
+		{1, 0, 2, 2, 255, 0, 0, 255},
+		{0, 0, 2, 2, 255, 0, 0, 255},
+		{2, 0, 1, 2, 255, 0, 0, 255},
+		{3, 0, 1, 3, 0, 0, 255, 255},
+		{0, 2, 4, 1, 0, 0, 255, 255},
+	} {
+		pi.CoverBox(box, false, false)
+	}
+	if removed := pi.RemoveRedundantRects(); removed != 1 {
+		t.Errorf("expected 1 rectangle to be removed, got %d", removed)
+	}
+	if len(pi.rects) != 4 || pi.rects[0] != (Rect{0, 0, 2, 2, "red"}) {
+		t.Errorf("expected the first rectangle to be removed, got %v", pi.rects)
+	}
+	if stats := pi.Stats(); stats.Rects != 4 {
+		t.Errorf("expected 4 rectangles in the stats, got %d", stats.Rects)
+	}
+	checkRendersAs(t, pi.Bytes(), img, 0)
+	if removed := pi.RemoveRedundantRects(); removed != 0 {
+		t.Errorf("expected no more rectangles to be removed, got %d", removed)
+	}
+
+	// With SetRemoveRedundant, the redundant rectangles are removed by Cover
+	pi = NewPixelImage(img, false)
+	pi.CoverBox(&Box{3, 0, 1, 1, 0, 0, 255, 255}, false, false)
+	pi.CoverBox(&Box{3, 0, 1, 3, 0, 0, 255, 255}, false, false)
+	pi.SetRemoveRedundant(true)
+	pi.Cover()
+	if len(pi.rects) != 3 {
+		t.Errorf("expected 3 rectangles, got %v", pi.rects)
+	}
+	checkRendersAs(t, pi.Bytes(), img, 0)
+}
@@ -0,0 +1,563 @@
+package png2svg
+
+import (
+	"bytes"
+	"encoding/xml"
+	"image"
+	"image/color"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// This file contains a small SVG rasterizer, for checking what the generated SVG
+// documents look like. It only supports what png2svg writes: rectangles, paths with
+// straight lines, <use>, <g>, <switch>, clip paths made of rectangles, linear gradients,
+// CSS classes with fill colors, and translate and matrix transforms that keep the
+// shapes aligned to the pixel grid. Each pixel is painted if its center is within a shape.
+
+// svgNode is an element of a parsed SVG document
+type svgNode struct {
+	XMLName  xml.Name
+	Attrs    []xml.Attr `xml:",any,attr"`
+	Children []*svgNode `xml:",any"`
+	Text     string     `xml:",chardata"`
+}
+
+// attr returns the value of the attribute with the given local name
+func (n *svgNode) attr(name string) (string, bool) {
+	for _, a := range n.Attrs {
+		if a.Name.Local == name {
+			return a.Value, true
+		}
+	}
+	return "", false
+}
+
+// number returns the attribute with the given local name as a number, or def if it is missing
+func (n *svgNode) number(t *testing.T, name string, def float64) float64 {
+	s, ok := n.attr(name)
+	if !ok {
+		return def
+	}
+	f, err := strconv.ParseFloat(strings.TrimSuffix(s, "px"), 64)
+	if err != nil {
+		t.Fatalf("invalid %s attribute: %q", name, s)
+	}
+	return f
+}
+
+// affine is the transform x' = a*x + c*y + e, y' = b*x + d*y + f
+type affine struct {
+	a, b, c, d, e, f float64
+}
+
+var identity = affine{1, 0, 0, 1, 0, 0}
+
+// then returns the transform that applies inner first, and then m
+func (m affine) then(inner affine) affine {
+	return affine{
+		m.a*inner.a + m.c*inner.b, m.b*inner.a + m.d*inner.b,
+		m.a*inner.c + m.c*inner.d, m.b*inner.c + m.d*inner.d,
+		m.a*inner.e + m.c*inner.f + m.e, m.b*inner.e + m.d*inner.f + m.f,
+	}
+}
+
+func (m affine) apply(x, y float64) (float64, float64) {
+	return m.a*x + m.c*y + m.e, m.b*x + m.d*y + m.f
+}
+
+var (
+	transformPattern = regexp.MustCompile(`(translate|matrix|scale)\(([^)]*)\)`)
+	numberPattern    = regexp.MustCompile(`-?[0-9.]+(e-?[0-9]+)?`)
+	cssRulePattern   = regexp.MustCompile(`\.([A-Za-z0-9_-]+)\{fill:([^}]+)\}`)
+	pathPattern      = regexp.MustCompile(`[MmHhVvLlZz]|-?[0-9.]+`)
+)
+
+// parseTransform parses a transform attribute with translate, scale and matrix functions
+func parseTransform(t *testing.T, s string) affine {
+	m := identity
+	for _, f := range transformPattern.FindAllStringSubmatch(s, -1) {
+		var args []float64
+		for _, n := range numberPattern.FindAllString(f[2], -1) {
+			v, _ := strconv.ParseFloat(n, 64)
+			args = append(args, v)
+		}
+		switch {
+		case f[1] == "translate" && len(args) == 1:
+			m = m.then(affine{1, 0, 0, 1, args[0], 0})
+		case f[1] == "translate" && len(args) == 2:
+			m = m.then(affine{1, 0, 0, 1, args[0], args[1]})
+		case f[1] == "scale" && len(args) == 1:
+			m = m.then(affine{args[0], 0, 0, args[0], 0, 0})
+		case f[1] == "scale" && len(args) == 2:
+			m = m.then(affine{args[0], 0, 0, args[1], 0, 0})
+		case f[1] == "matrix" && len(args) == 6:
+			m = m.then(affine{args[0], args[1], args[2], args[3], args[4], args[5]})
+		default:
+			t.Fatalf("unsupported transform: %q", s)
+		}
+	}
+	return m
+}
+
+// parseColor parses a fill color, as written by png2svg
+func parseColor(t *testing.T, s string) color.NRGBA {
+	s = strings.TrimSpace(s)
+	switch {
+	case s == "currentColor":
+		return color.NRGBA{0, 0, 0, 255}
+	case strings.HasPrefix(s, "#"):
+		c, err := ParseHexColor(s)
+		if err != nil {
+			t.Fatalf("invalid color: %q", s)
+		}
+		return c
+	case strings.HasPrefix(s, "rgb"):
+		var v []float64
+		for _, n := range numberPattern.FindAllString(s, -1) {
+			f, _ := strconv.ParseFloat(n, 64)
+			v = append(v, f)
+		}
+		c := color.NRGBA{uint8(v[0]), uint8(v[1]), uint8(v[2]), 255}
+		if len(v) == 4 {
+			c.A = uint8(math.Round(v[3] * 255))
+		}
+		return c
+	}
+	for hex, name := range namedColors {
+		if name == s {
+			c, _ := ParseHexColor(hex)
+			return c
+		}
+	}
+	t.Fatalf("unknown color: %q", s)
+	return color.NRGBA{}
+}
+
+// premultiplied is a premultiplied color, with channels from 0 to 1
+type premultiplied struct {
+	r, g, b, a float64
+}
+
+// raster is an image that SVG documents can be painted on
+type raster struct {
+	w, h   int
+	pixels []premultiplied
+	// painted counts how many times each pixel has been painted with a color that is not transparent
+	painted []int
+	ids     map[string]*svgNode
+	classes map[string]string
+	grid    bool // true if 1x1 rectangles can leave out the width and height
+}
+
+// At returns the color of the given pixel, or transparent if it is outside of the image
+func (r *raster) At(x, y int) color.NRGBA {
+	if x < 0 || y < 0 || x >= r.w || y >= r.h {
+		return color.NRGBA{}
+	}
+	p := r.pixels[y*r.w+x]
+	if p.a == 0 {
+		return color.NRGBA{}
+	}
+	round := func(v float64) uint8 { return uint8(math.Round(v * 255)) }
+	return color.NRGBA{round(p.r / p.a), round(p.g / p.a), round(p.b / p.a), round(p.a)}
+}
+
+// Painted returns how many times the given pixel has been painted
+func (r *raster) Painted(x, y int) int {
+	return r.painted[y*r.w+x]
+}
+
+// blend paints the given color over the pixel that contains the given point
+func (r *raster) blend(x, y float64, c color.NRGBA, opacity float64) {
+	px, py := int(math.Floor(x)), int(math.Floor(y))
+	if px < 0 || py < 0 || px >= r.w || py >= r.h {
+		return
+	}
+	a := float64(c.A) / 255 * opacity
+	if a == 0 {
+		return
+	}
+	i := py*r.w + px
+	dst := r.pixels[i]
+	r.pixels[i] = premultiplied{
+		float64(c.R)/255*a + dst.r*(1-a),
+		float64(c.G)/255*a + dst.g*(1-a),
+		float64(c.B)/255*a + dst.b*(1-a),
+		a + dst.a*(1-a),
+	}
+	r.painted[i]++
+}
+
+// paintStyle is the inherited fill and opacity
+type paintStyle struct {
+	fill    string
+	opacity float64
+}
+
+// renderSVG parses and paints the given SVG document
+func renderSVG(t *testing.T, svgDocument []byte) *raster {
+	t.Helper()
+	var root svgNode
+	if err := xml.Unmarshal(svgDocument, &root); err != nil {
+		t.Fatalf("invalid SVG document: %v\n%s", err, svgDocument)
+	}
+	viewBox, ok := root.attr("viewBox")
+	if !ok {
+		t.Fatalf("no viewBox in %s", svgDocument)
+	}
+	fields := strings.Fields(viewBox)
+	w, _ := strconv.Atoi(fields[2])
+	h, _ := strconv.Atoi(fields[3])
+	r := &raster{
+		w:       w,
+		h:       h,
+		pixels:  make([]premultiplied, w*h),
+		painted: make([]int, w*h),
+		ids:     make(map[string]*svgNode),
+		classes: make(map[string]string),
+	}
+	var index func(n *svgNode)
+	index = func(n *svgNode) {
+		if id, ok := n.attr("id"); ok {
+			r.ids[id] = n
+		}
+		if n.XMLName.Local == "style" {
+			for _, rule := range cssRulePattern.FindAllStringSubmatch(n.Text, -1) {
+				r.classes[rule[1]] = rule[2]
+			}
+			if strings.Contains(n.Text, gridRule) {
+				r.grid = true
+			}
+		}
+		for _, child := range n.Children {
+			index(child)
+		}
+	}
+	index(&root)
+	for _, child := range root.Children {
+		r.paint(t, child, paintStyle{"#000", 1}, identity)
+	}
+	return r
+}
+
+// paint paints the given node and its children
+func (r *raster) paint(t *testing.T, n *svgNode, style paintStyle, m affine) {
+	switch n.XMLName.Local {
+	case "defs", "clipPath", "linearGradient", "title", "metadata", "style", "view", "image", "desc", "animate":
+		return
+	}
+	if visibility, _ := n.attr("visibility"); visibility == "hidden" {
+		return
+	}
+	if s, ok := n.attr("transform"); ok {
+		m = m.then(parseTransform(t, s))
+	}
+	if fill, ok := n.attr("fill"); ok {
+		style.fill = fill
+	}
+	if class, ok := n.attr("class"); ok {
+		if fill, ok := r.classes[class]; ok {
+			style.fill = fill
+		}
+	}
+	if s, ok := n.attr("style"); ok && strings.HasPrefix(s, "fill:") {
+		style.fill = strings.TrimPrefix(s, "fill:")
+	}
+	style.opacity *= n.number(t, "fill-opacity", 1) * n.number(t, "opacity", 1)
+
+	switch n.XMLName.Local {
+	case "svg", "g":
+		for _, child := range n.Children {
+			r.paint(t, child, style, m)
+		}
+	case "switch":
+		if len(n.Children) > 0 {
+			r.paint(t, n.Children[0], style, m)
+		}
+	case "use":
+		href, _ := n.attr("href")
+		ref, ok := r.ids[strings.TrimPrefix(href, "#")]
+		if !ok {
+			t.Fatalf("<use> refers to a missing id: %q", href)
+		}
+		m = m.then(affine{1, 0, 0, 1, n.number(t, "x", 0), n.number(t, "y", 0)})
+		r.paint(t, ref, style, m)
+	case "rect":
+		size := 0.0
+		if r.grid {
+			size = 1
+		}
+		x, y := n.number(t, "x", 0), n.number(t, "y", 0)
+		w, h := n.number(t, "width", size), n.number(t, "height", size)
+		r.fillShape(t, n, style, m, x, y, w, h, func(px, py float64) bool {
+			return px >= x && px < x+w && py >= y && py < y+h
+		})
+	case "path":
+		d, _ := n.attr("d")
+		loops := parsePath(t, d)
+		minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+		for _, loop := range loops {
+			for _, p := range loop {
+				minX, minY = math.Min(minX, p[0]), math.Min(minY, p[1])
+				maxX, maxY = math.Max(maxX, p[0]), math.Max(maxY, p[1])
+			}
+		}
+		evenOdd := false
+		if rule, ok := n.attr("fill-rule"); ok && rule == "evenodd" {
+			evenOdd = true
+		}
+		r.fillShape(t, n, style, m, minX, minY, maxX-minX, maxY-minY, func(px, py float64) bool {
+			winding, crossings := 0, 0
+			for _, loop := range loops {
+				for i := range loop {
+					a, b := loop[i], loop[(i+1)%len(loop)]
+					if (a[1] > py) != (b[1] > py) && px < a[0]+(py-a[1])*(b[0]-a[0])/(b[1]-a[1]) {
+						crossings++
+						if b[1] > a[1] {
+							winding++
+						} else {
+							winding--
+						}
+					}
+				}
+			}
+			if evenOdd {
+				return crossings%2 == 1
+			}
+			return winding != 0
+		})
+	default:
+		t.Fatalf("unsupported element: <%s>", n.XMLName.Local)
+	}
+}
+
+// fillShape paints the pixels within the given bounding box, in the coordinates of the
+// shape, where the center is within the shape and within the clip path, if any
+func (r *raster) fillShape(t *testing.T, n *svgNode, style paintStyle, m affine, x, y, w, h float64, inside func(px, py float64) bool) {
+	if style.fill == "none" {
+		return
+	}
+	var clip []*svgNode
+	if s, ok := n.attr("clip-path"); ok {
+		id := strings.TrimSuffix(strings.TrimPrefix(s, "url(#"), ")")
+		clipPath, ok := r.ids[id]
+		if !ok {
+			t.Fatalf("clip-path refers to a missing id: %q", s)
+		}
+		clip = clipPath.Children
+	}
+	var gradient *svgNode
+	if strings.HasPrefix(style.fill, "url(#") {
+		id := strings.TrimSuffix(strings.TrimPrefix(style.fill, "url(#"), ")")
+		if gradient = r.ids[id]; gradient == nil {
+			t.Fatalf("fill refers to a missing id: %q", style.fill)
+		}
+	}
+	var c color.NRGBA
+	if gradient == nil {
+		c = parseColor(t, style.fill)
+	}
+	for py := math.Floor(y) + 0.5; py < y+h; py++ {
+		for px := math.Floor(x) + 0.5; px < x+w; px++ {
+			if !inside(px, py) {
+				continue
+			}
+			if clip != nil {
+				within := false
+				for _, rect := range clip {
+					cx, cy := rect.number(t, "x", 0), rect.number(t, "y", 0)
+					cw, ch := rect.number(t, "width", 1), rect.number(t, "height", 1)
+					if px >= cx && px < cx+cw && py >= cy && py < cy+ch {
+						within = true
+						break
+					}
+				}
+				if !within {
+					continue
+				}
+			}
+			if gradient != nil {
+				c = gradientAt(t, gradient, px, py)
+			}
+			dx, dy := m.apply(px, py)
+			r.blend(dx, dy, c, style.opacity)
+		}
+	}
+}
+
+// gradientAt returns the color of a linear gradient with two stops, in user space, at the given point
+func gradientAt(t *testing.T, g *svgNode, px, py float64) color.NRGBA {
+	x1, y1 := g.number(t, "x1", 0), g.number(t, "y1", 0)
+	x2, y2 := g.number(t, "x2", 1), g.number(t, "y2", 0)
+	dx, dy := x2-x1, y2-y1
+	f := ((px-x1)*dx + (py-y1)*dy) / (dx*dx + dy*dy)
+	f = math.Max(0, math.Min(1, f))
+	from, _ := g.Children[0].attr("stop-color")
+	to, _ := g.Children[1].attr("stop-color")
+	a, b := parseColor(t, from), parseColor(t, to)
+	mix := func(p, q uint8) uint8 { return uint8(math.Round(float64(p) + (float64(q)-float64(p))*f)) }
+	return color.NRGBA{mix(a.R, b.R), mix(a.G, b.G), mix(a.B, b.B), 255}
+}
+
+// parsePath parses the d attribute of a path with M, m, H, h, V, v, L, l, Z and z commands,
+// and returns the closed subpaths
+func parsePath(t *testing.T, d string) [][][2]float64 {
+	var (
+		loops        [][][2]float64
+		x, y, sx, sy float64
+		command      string
+		tokens       = pathPattern.FindAllString(d, -1)
+		current      [][2]float64
+	)
+	next := func(i *int) float64 {
+		*i++
+		if *i >= len(tokens) {
+			t.Fatalf("truncated path: %q", d)
+		}
+		v, err := strconv.ParseFloat(tokens[*i], 64)
+		if err != nil {
+			t.Fatalf("invalid path: %q", d)
+		}
+		return v
+	}
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		if strings.ContainsAny(tok, "MmHhVvLlZz") {
+			command = tok
+		} else {
+			// An implicit repetition of the previous command
+			i--
+		}
+		switch command {
+		case "M", "m":
+			nx, ny := next(&i), next(&i)
+			if command == "m" {
+				nx, ny = x+nx, y+ny
+			}
+			x, y, sx, sy = nx, ny, nx, ny
+			current = [][2]float64{{x, y}}
+			loops = append(loops, current)
+			command = map[string]string{"M": "L", "m": "l"}[command]
+			continue
+		case "H":
+			x = next(&i)
+		case "h":
+			x += next(&i)
+		case "V":
+			y = next(&i)
+		case "v":
+			y += next(&i)
+		case "L":
+			x, y = next(&i), next(&i)
+		case "l":
+			nx, ny := next(&i), next(&i)
+			x, y = x+nx, y+ny
+		case "Z", "z":
+			x, y = sx, sy
+			continue
+		}
+		current = append(current, [2]float64{x, y})
+		loops[len(loops)-1] = current
+	}
+	return loops
+}
+
+// checkRendersAs checks that the given SVG document looks like the given image,
+// where pixels that are not fully opaque are compared with the given tolerance
+func checkRendersAs(t *testing.T, svgDocument []byte, img image.Image, tolerance int) *raster {
+	t.Helper()
+	r := renderSVG(t, svgDocument)
+	bounds := img.Bounds()
+	if r.w != bounds.Dx() || r.h != bounds.Dy() {
+		t.Fatalf("the SVG image is %dx%d, expected %dx%d", r.w, r.h, bounds.Dx(), bounds.Dy())
+	}
+	differ := 0
+	for y := 0; y < r.h; y++ {
+		for x := 0; x < r.w; x++ {
+			want := color.NRGBAModel.Convert(img.At(bounds.Min.X+x, bounds.Min.Y+y)).(color.NRGBA)
+			got := r.At(x, y)
+			if want.A == 0 && got.A == 0 {
+				continue
+			}
+			within := func(a, b uint8) bool {
+				d := int(a) - int(b)
+				return d <= tolerance && d >= -tolerance
+			}
+			if !within(want.R, got.R) || !within(want.G, got.G) || !within(want.B, got.B) || !within(want.A, got.A) {
+				if differ < 5 {
+					t.Errorf("pixel (%d, %d) is %v, expected %v", x, y, got, want)
+				}
+				differ++
+			}
+		}
+	}
+	if differ > 0 {
+		t.Errorf("%d pixels differ", differ)
+	}
+	return r
+}
+
+// convertBytes converts the given image with the given options, and returns the SVG document
+func convertBytes(t *testing.T, img image.Image, opts Options) []byte {
+	t.Helper()
+	pi := NewPixelImage(img, false)
+	pi.SetOptions(opts)
+	pi.Cover()
+	if !pi.Done(0, 0) {
+		t.Fatalf("not all pixels are covered")
+	}
+	return pi.Bytes()
+}
+
+// newTestImage creates an NRGBA image from rows of single-letter pixels, using the given palette
+func newTestImage(palette map[byte]color.NRGBA, rows ...string) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, len(rows[0]), len(rows)))
+	for y, row := range rows {
+		for x := range row {
+			img.SetNRGBA(x, y, palette[row[x]])
+		}
+	}
+	return img
+}
+
+// testPalette is the palette for newTestImage, where '.' is transparent
+var testPalette = map[byte]color.NRGBA{
+	'.': {0, 0, 0, 0},
+	'r': {255, 0, 0, 255},
+	'g': {0, 128, 0, 255},
+	'b': {0, 0, 255, 255},
+	'w': {255, 255, 255, 255},
+	'k': {0, 0, 0, 255},
+	'y': {255, 255, 0, 255},
+	'h': {255, 0, 0, 128}, // half transparent red
+}
+
+func TestRenderSVG(t *testing.T) {
+	svgDocument := []byte(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 4 2"><g fill="red"><rect width="2" height="2"/></g><path fill="#00f" d="M2 0h2v1h-2z"/><rect x="2" y="1" width="2" height="1" fill="rgba(255,0,0,0.5)"/></svg>`)
+	r := renderSVG(t, svgDocument)
+	for _, tc := range []struct {
+		x, y int
+		want color.NRGBA
+	}{
+		{0, 0, color.NRGBA{255, 0, 0, 255}},
+		{1, 1, color.NRGBA{255, 0, 0, 255}},
+		{3, 0, color.NRGBA{0, 0, 255, 255}},
+		{2, 1, color.NRGBA{255, 0, 0, 128}},
+	} {
+		if got := r.At(tc.x, tc.y); got != tc.want {
+			t.Errorf("pixel (%d, %d) is %v, expected %v", tc.x, tc.y, got, tc.want)
+		}
+	}
+}
+
+// svgContains checks that the SVG document contains the given string
+func svgContains(t *testing.T, svgDocument []byte, s string) {
+	t.Helper()
+	if !bytes.Contains(svgDocument, []byte(s)) {
+		t.Errorf("expected %q in %s", s, svgDocument)
+	}
+}
@@ -0,0 +1,81 @@
+package png2svg
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"strconv"
+
+	"github.com/xyproto/tinysvg"
+)
+
+// Rect is a rectangle that has been placed in the SVG document
+type Rect struct {
+	X, Y          int
+	Width, Height int
+	// Fill is the fill color, as it is written in the SVG document, like "#abc"
+	Fill string
+}
+
+// Result is the result of converting an image with Convert.
+// The image is converted once, by Convert. After that, SVG and Rects can be
+// called any number of times, without the pixels being covered again.
+type Result struct {
+	pi *PixelImage
+}
+
+// ErrNoImage is returned by Convert if the given image is nil
+var ErrNoImage = errors.New("no image to convert")
+
+// Convert converts the given image with the given options, and returns a
+// Result that contains both the SVG document and the placed rectangles
+func Convert(img image.Image, opts Options) (*Result, error) {
+	if img == nil {
+		return nil, ErrNoImage
+	}
+	pi := NewPixelImage(img, false)
+	pi.SetOptions(opts)
+	pi.Cover()
+	if pi.TooManyRects() {
+		return nil, ErrTooManyRects
+	}
+	if !pi.Done(0, 0) {
+		return nil, ErrNotCovered
+	}
+	return &Result{pi}, nil
+}
+
+// PixelImage returns the PixelImage that was used for the conversion,
+// for writing the SVG document in other ways, like with WriteSVGGzipTo
+func (r *Result) PixelImage() *PixelImage {
+	return r.pi
+}
+
+// SVG returns the SVG document
+func (r *Result) SVG() string {
+	return string(r.pi.Bytes())
+}
+
+// Rects returns all rectangles, in the order they were placed. If the opaque
+// region was clipped, the clipped background rectangle is not included.
+// The returned slice is a copy, and can be modified.
+func (r *Result) Rects() []Rect {
+	return append([]Rect{}, r.pi.rects...)
+}
+
+// AppendTo adds the converted image to the given tag, like the root tag of an
+// existing tinysvg document, as a <g> tag that is translated by the given offset.
+// The <g> tag is added as the last child of the given tag, so it is drawn on top
+// of the children that are already there, and below children that are added later.
+// The parent document must declare the xlink namespace if the image is mirrored
+// or has a fallback image. If several results are added to the same document,
+// their ids and CSS class names, if any, are the same, and may collide.
+func (r *Result) AppendTo(parent *tinysvg.Tag, offsetX, offsetY int) {
+	var buf bytes.Buffer
+	r.pi.writeGroups(&buf)
+	g := parent.AddNewTag([]byte("g"))
+	if offsetX != 0 || offsetY != 0 {
+		g.AddAttrib("transform", []byte("translate("+strconv.Itoa(offsetX)+","+strconv.Itoa(offsetY)+")"))
+	}
+	g.AppendContent(buf.Bytes())
+}
@@ -0,0 +1,74 @@
+package png2svg
+
+import (
+	"testing"
+
+	"github.com/xyproto/tinysvg"
+)
+
+func TestConvert(t *testing.T) {
+	img := newTestImage(testPalette,
+		"rrb",
+		"rr.",
+	)
+	result, err := Convert(img, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkRendersAs(t, []byte(result.SVG()), img, 0)
+
+	want := []Rect{
+		{0, 0, 2, 2, "red"},
+		{2, 0, 1, 1, "#00f"},
+	}
+	rects := result.Rects()
+	if len(rects) != len(want) {
+		t.Fatalf("expected %d rectangles, got %v", len(want), rects)
+	}
+	for i := range want {
+		if rects[i] != want[i] {
+			t.Errorf("rectangle %d is %v, expected %v", i, rects[i], want[i])
+		}
+	}
+
+	// Modifying the returned rectangles does not change the result
+	rects[0].Fill = "#0f0"
+	rects = append(rects[:1], Rect{X: 9})
+	if got := result.Rects(); got[0] != want[0] || len(got) != len(want) || got[1] != want[1] {
+		t.Errorf("the rectangles of the result were modified: %v", got)
+	}
+	checkRendersAs(t, []byte(result.SVG()), img, 0)
+
+	if _, err := Convert(nil, Options{}); err != ErrNoImage {
+		t.Errorf("expected ErrNoImage, got %v", err)
+	}
+	if _, err := Convert(noiseImage(16, 16, 4), Options{MaxRects: 5}); err != ErrTooManyRects {
+		t.Errorf("expected ErrTooManyRects, got %v", err)
+	}
+}
+
+func TestAppendTo(t *testing.T) {
+	img := newTestImage(testPalette,
+		"rrb",
+		"rr.",
+	)
+	result, err := Convert(img, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	document, svgTag := tinysvg.NewTinySVG(6, 4)
+	background := svgTag.AddRect(0, 0, 6, 4)
+	background.Fill("#ff0")
+	result.AppendTo(svgTag, 2, 1)
+	result.AppendTo(svgTag, 0, 0)
+	// A rectangle that is added later is drawn on top of the image
+	top := svgTag.AddRect(0, 0, 1, 1)
+	top.Fill("#000")
+
+	checkRendersAs(t, document.Bytes(), newTestImage(testPalette,
+		"krbyyy",
+		"rrrrby",
+		"yyrryy",
+		"yyyyyy",
+	), 0)
+}
@@ -0,0 +1,57 @@
+package png2svg
+
+import (
+	"bytes"
+	"html"
+	"sort"
+	"strconv"
+)
+
+// SetGroupByRow can be used for grouping the rectangles by the row they start at,
+// instead of by fill color, for animating the rows independently with CSS or SMIL.
+// Each row is placed in a <g data-row="y"> tag, and each rectangle gets its own fill.
+// If transform is not empty, it is used as the transform attribute of each row,
+// like "translate(-100,0)", as the starting point of an animation.
+func (pi *PixelImage) SetGroupByRow(enabled bool, transform string) {
+	pi.groupByRow = enabled
+	pi.rowTransform = transform
+}
+
+// writeRows writes all rectangles to the given buffer, grouped by the row they start at.
+// Within each row, the rectangles are sorted by their x position.
+func (pi *PixelImage) writeRows(buf *bytes.Buffer) {
+	groupIndex := make(map[string]int, len(pi.groupOrder))
+	for i, group := range pi.groupOrder {
+		groupIndex[string(group.fill)] = i
+	}
+	rects := make([]Rect, len(pi.rects))
+	copy(rects, pi.rects)
+	sort.SliceStable(rects, func(i, j int) bool {
+		if rects[i].Y != rects[j].Y {
+			return rects[i].Y < rects[j].Y
+		}
+		return rects[i].X < rects[j].X
+	})
+	for i, rect := range rects {
+		if i == 0 || rect.Y != rects[i-1].Y {
+			if i > 0 {
+				buf.WriteString("</g>")
+			}
+			buf.WriteString("<g data-row=\"")
+			buf.WriteString(strconv.Itoa(rect.Y))
+			if pi.rowTransform != "" {
+				buf.WriteString("\" transform=\"")
+				buf.WriteString(html.EscapeString(pi.rowTransform))
+			}
+			buf.WriteString("\">")
+		}
+		pi.writeRect(buf, rect.X, rect.Y, rect.Width, rect.Height)
+		// Insert the fill attribute before the closing "/>"
+		buf.Truncate(buf.Len() - 2)
+		pi.writeFill(buf, groupIndex[rect.Fill], []byte(rect.Fill))
+		buf.WriteString("\"/>")
+	}
+	if len(rects) > 0 {
+		buf.WriteString("</g>")
+	}
+}
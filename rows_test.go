@@ -0,0 +1,62 @@
+package png2svg
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strconv"
+	"testing"
+)
+
+func TestGroupByRow(t *testing.T) {
+	img := newTestImage(testPalette,
+		"rrbb",
+		"rrgg",
+		"w.gg",
+		"kkky",
+	)
+	flat := convertBytes(t, img, Options{})
+	for _, transform := range []string{"", "translate(-4,0)"} {
+		svgDocument := convertBytes(t, img, Options{GroupByRow: true, RowTransform: transform})
+		var root svgNode
+		if err := xml.Unmarshal(svgDocument, &root); err != nil {
+			t.Fatal(err)
+		}
+		lastRow, rects := -1, 0
+		for _, group := range root.Children {
+			dataRow, _ := group.attr("data-row")
+			row, err := strconv.Atoi(dataRow)
+			if err != nil || row <= lastRow {
+				t.Fatalf("expected increasing data-row attributes, got %q after %d", dataRow, lastRow)
+			}
+			lastRow = row
+			if got, _ := group.attr("transform"); got != transform {
+				t.Errorf("expected the transform %q, got %q", transform, got)
+			}
+			lastX := -1.0
+			for _, rect := range group.Children {
+				rects++
+				if y := rect.number(t, "y", 0); y != float64(row) {
+					t.Errorf("a rectangle at y=%v is in row %d", y, row)
+				}
+				if _, ok := rect.attr("fill"); !ok {
+					t.Errorf("expected each rectangle in row %d to have a fill", row)
+				}
+				x := rect.number(t, "x", 0)
+				if x <= lastX {
+					t.Errorf("expected the rectangles in row %d to be sorted by x", row)
+				}
+				lastX = x
+			}
+		}
+		if want := bytes.Count(flat, []byte("<rect")); rects != want {
+			t.Errorf("expected %d rectangles, got %d", want, rects)
+		}
+		if transform == "" {
+			// Without a transform, the rows render just like the groups by color
+			checkRendersAs(t, svgDocument, img, 0)
+		} else if r := renderSVG(t, svgDocument); r.Painted(0, 0) > 0 || r.Painted(3, 3) > 0 {
+			// Each row is moved out of view, as the starting point of an animation
+			t.Error("expected the transform to move the rows out of view")
+		}
+	}
+}
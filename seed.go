@@ -0,0 +1,36 @@
+package png2svg
+
+// SeedFunc is a function that selects the next pixel to place a box at, when
+// covering the image with expanding boxes. It should return the coordinate of a
+// pixel that is not yet covered (see Covered and Uncovered), and true, or false
+// if there are no more pixels it wants to select. The box is then expanded to
+// the right and downwards, as usual.
+type SeedFunc func(pi *PixelImage) (x, y int, ok bool)
+
+// SetSeedFunc can be used for replacing the search for the first uncovered pixel
+// with a custom seed selection strategy, like largest region first or center out.
+// If the function returns a pixel that is outside of the image or already covered,
+// or returns false while there are uncovered pixels left, the remaining pixels
+// are covered in row order. Set to nil for using the order given by SetOrder.
+func (pi *PixelImage) SetSeedFunc(seed SeedFunc) {
+	pi.seed = seed
+}
+
+// coverBoxesWithSeedFunc covers the pixels by placing boxes at the pixels
+// that are selected by the seed function, until it returns false.
+// Returns false if the seed function returned an invalid pixel.
+func (pi *PixelImage) coverBoxesWithSeedFunc(pink bool) bool {
+	for !pi.stopCovering() {
+		x, y, ok := pi.seed(pi)
+		if !ok {
+			return true
+		}
+		if x < 0 || y < 0 || x >= pi.w || y >= pi.h || pi.Covered(x, y) {
+			return false
+		}
+		box := pi.CreateBox(x, y)
+		expanded := pi.Expand(box)
+		pi.CoverBox(box, expanded && pink, pi.colorOptimize)
+	}
+	return true
+}
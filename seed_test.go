@@ -0,0 +1,47 @@
+package png2svg
+
+import (
+	"testing"
+)
+
+func TestSeedFunc(t *testing.T) {
+	img := newTestImage(testPalette,
+		"rrrr",
+		"rbbr",
+		"rrrr",
+	)
+
+	// Start at the last uncovered pixel, from the bottom right
+	var seeds [][2]int
+	lastFirst := func(pi *PixelImage) (int, int, bool) {
+		uncovered := pi.Uncovered()
+		if len(uncovered) == 0 {
+			return 0, 0, false
+		}
+		p := uncovered[len(uncovered)-1]
+		seeds = append(seeds, [2]int{p.X, p.Y})
+		return p.X, p.Y, true
+	}
+	svgDocument := convertBytes(t, img, Options{SeedFunc: lastFirst})
+	checkRendersAs(t, svgDocument, img, 0)
+	if len(seeds) == 0 || seeds[0] != [2]int{3, 2} {
+		t.Errorf("expected the first box to be placed at (3, 2), got the seeds %v", seeds)
+	}
+
+	// A seed function that returns an invalid pixel or stops early leaves the rest to the row order
+	for name, seed := range map[string]SeedFunc{
+		"outside": func(pi *PixelImage) (int, int, bool) { return -1, 5, true },
+		// (1, 1) is covered after the first call
+		"covered": func(pi *PixelImage) (int, int, bool) { return 1, 1, true },
+		"stops":   func(pi *PixelImage) (int, int, bool) { return 0, 0, false },
+	} {
+		pi := NewPixelImage(img, false)
+		pi.SetSeedFunc(seed)
+		pi.Cover()
+		if !pi.Done(0, 0) {
+			t.Errorf("%s: expected all pixels to be covered", name)
+			continue
+		}
+		checkRendersAs(t, pi.Bytes(), img, 0)
+	}
+}
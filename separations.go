@@ -0,0 +1,127 @@
+package png2svg
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// SetSeparations can be used for writing each fill color as a separate layer, for
+// print separations. Each layer is placed in a <g id="separation-N"> tag (or a <path>,
+// if SetPaths is used), ordered from the lightest to the darkest color, so that the
+// darker inks are printed on top. The spot color names are optional, and map hex
+// colors on the form "#rrggbb" to names, like "PANTONE 485 C", which are written
+// as data-spot attributes.
+func (pi *PixelImage) SetSeparations(enabled bool, spotColors map[string]string) {
+	pi.separations = enabled
+	pi.spotColors = spotColors
+}
+
+// separation is the rectangles of one color group, together with the
+// luminance of the color and the index of the color group
+type separation struct {
+	group     int
+	luminance float64
+	rects     []Rect
+}
+
+// separationOrder returns the color groups, ordered from the lightest to the darkest color
+func (pi *PixelImage) separationOrder() []separation {
+	byFill := make(map[string][]Rect, len(pi.groupOrder))
+	for _, rect := range pi.rects {
+		byFill[rect.Fill] = append(byFill[rect.Fill], rect)
+	}
+	var separations []separation
+	for i, group := range pi.groupOrder {
+		rects := byFill[string(group.fill)]
+		if len(rects) == 0 {
+			continue
+		}
+		// The color of the pixels, before it is quantized
+		p := pi.pixels[rects[0].Y*pi.w+rects[0].X]
+		luminance := 0.299*float64(p.r) + 0.587*float64(p.g) + 0.114*float64(p.b)
+		separations = append(separations, separation{i, luminance, rects})
+	}
+	sort.SliceStable(separations, func(i, j int) bool {
+		return separations[i].luminance > separations[j].luminance
+	})
+	return separations
+}
+
+// spotColor returns the spot color name for the given color group, if there is one.
+// The fill color is looked up first, then the color of the pixels.
+func (pi *PixelImage) spotColor(s separation) string {
+	if len(pi.spotColors) == 0 {
+		return ""
+	}
+	if name, ok := pi.spotColors[strings.ToLower(string(longColor(pi.groupOrder[s.group].hex)))]; ok {
+		return name
+	}
+	p := pi.pixels[s.rects[0].Y*pi.w+s.rects[0].X]
+	return pi.spotColors[string(hexColor(p.r, p.g, p.b))]
+}
+
+// writeSeparations writes the rectangles of each color as a separate layer,
+// from the lightest to the darkest color
+func (pi *PixelImage) writeSeparations(buf *bytes.Buffer) {
+	for n, s := range pi.separationOrder() {
+		group := pi.groupOrder[s.group]
+		if pi.paths {
+			buf.WriteString("<path")
+		} else {
+			buf.WriteString("<g")
+		}
+		buf.WriteString(" id=\"")
+		buf.WriteString(pi.classPrefix)
+		buf.WriteString("separation-")
+		buf.WriteString(strconv.Itoa(n))
+		if name := pi.spotColor(s); name != "" {
+			buf.WriteString("\" data-spot=\"")
+			buf.WriteString(html.EscapeString(name))
+		}
+		buf.WriteByte('"')
+		pi.writeFill(buf, s.group, group.fill)
+		if pi.paths {
+			buf.WriteString("\" d=\"")
+			buf.Write(pathData(s.rects))
+			buf.WriteString("\"/>")
+			continue
+		}
+		buf.WriteString("\">")
+		if pi.tooltips {
+			writeTooltip(buf, group.hex)
+		}
+		for _, rect := range s.rects {
+			pi.writeRect(buf, rect.X, rect.Y, rect.Width, rect.Height)
+		}
+		buf.WriteString("</g>")
+	}
+}
+
+// ReadSpotColors reads a JSON file that maps hex colors to spot color names,
+// like {"#e03c31": "PANTONE 485 C"}. The colors can be on the form "#rgb" or
+// "#rrggbb", and are returned on the form "#rrggbb", as used by SetSeparations.
+func ReadSpotColors(filename string) (map[string]string, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var names map[string]string
+	if err := json.Unmarshal(data, &names); err != nil {
+		return nil, fmt.Errorf("could not parse %s: %v", filename, err)
+	}
+	spotColors := make(map[string]string, len(names))
+	for s, name := range names {
+		c, err := ParseHexColor(s)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse %s: %q: %v", filename, s, err)
+		}
+		spotColors[string(hexColor(int(c.R), int(c.G), int(c.B)))] = name
+	}
+	return spotColors, nil
+}
@@ -0,0 +1,79 @@
+package png2svg
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestSeparations(t *testing.T) {
+	img := newTestImage(testPalette,
+		"kbrw",
+		"kbrw",
+		"wwrk",
+	)
+	spotColors := map[string]string{"#ff0000": "PANTONE 485 C", "#000000": "Black & White"}
+	for _, paths := range []bool{false, true} {
+		svgDocument := convertBytes(t, img, Options{Separations: true, SpotColors: spotColors, Paths: paths})
+		checkRendersAs(t, svgDocument, img, 0)
+
+		var root svgNode
+		if err := xml.Unmarshal(svgDocument, &root); err != nil {
+			t.Fatal(err)
+		}
+		// From the lightest to the darkest color
+		wantFills := []string{"#fff", "red", "#00f", "#000"}
+		wantSpots := []string{"", "PANTONE 485 C", "", "Black & White"}
+		if len(root.Children) != len(wantFills) {
+			t.Fatalf("paths %v: expected %d layers, got %d", paths, len(wantFills), len(root.Children))
+		}
+		for i, layer := range root.Children {
+			if id, _ := layer.attr("id"); id != "separation-"+strconv.Itoa(i) {
+				t.Errorf("paths %v: expected the id separation-%d, got %q", paths, i, id)
+			}
+			if fill, _ := layer.attr("fill"); fill != wantFills[i] {
+				t.Errorf("paths %v: expected layer %d to be %s, got %s", paths, i, wantFills[i], fill)
+			}
+			if spot, _ := layer.attr("data-spot"); spot != wantSpots[i] {
+				t.Errorf("paths %v: expected the spot color %q for layer %d, got %q", paths, wantSpots[i], i, spot)
+			}
+		}
+	}
+}
+
+func TestReadSpotColors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "png2svg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "spot.json")
+
+	if err := ioutil.WriteFile(filename, []byte(`{"#E03C31": "PANTONE 485 C", "#fff": "Paper"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	spotColors, err := ReadSpotColors(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"#e03c31": "PANTONE 485 C", "#ffffff": "Paper"}
+	if !reflect.DeepEqual(spotColors, want) {
+		t.Errorf("expected %v, got %v", want, spotColors)
+	}
+
+	for _, data := range []string{`{"#e03c3": "PANTONE 485 C"}`, `["#e03c31"]`} {
+		if err := ioutil.WriteFile(filename, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := ReadSpotColors(filename); err == nil {
+			t.Errorf("expected an error for %s", data)
+		}
+	}
+	if _, err := ReadSpotColors(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
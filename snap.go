@@ -0,0 +1,34 @@
+package png2svg
+
+import "fmt"
+
+// SetSnapExtremes changes the color of the pixels that are within the given tolerance
+// of pure black to #000000, and the pixels that are within the given tolerance of pure
+// white to #ffffff. The tolerance is the largest allowed difference per color channel.
+// This is useful for scanned line art, where off-black and off-white pixels would
+// otherwise split up regions that should be solid. Returns the number of pixels that were changed.
+func (pi *PixelImage) SetSnapExtremes(tolerance int) int {
+	pi.snapExtremes = tolerance
+	count := 0
+	for _, p := range pi.pixels {
+		if p.a == 0 {
+			continue
+		}
+		switch {
+		case p.r <= tolerance && p.g <= tolerance && p.b <= tolerance:
+			if p.r != 0 || p.g != 0 || p.b != 0 {
+				p.r, p.g, p.b = 0, 0, 0
+				count++
+			}
+		case p.r >= 0xff-tolerance && p.g >= 0xff-tolerance && p.b >= 0xff-tolerance:
+			if p.r != 0xff || p.g != 0xff || p.b != 0xff {
+				p.r, p.g, p.b = 0xff, 0xff, 0xff
+				count++
+			}
+		}
+	}
+	if pi.verbose {
+		fmt.Printf("Snapped %d pixels to black or white.\n", count)
+	}
+	return count
+}
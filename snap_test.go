@@ -0,0 +1,48 @@
+package png2svg
+
+import (
+	"image/color"
+	"testing"
+)
+
+func TestSnapExtremes(t *testing.T) {
+	palette := map[byte]color.NRGBA{
+		'k': {0, 0, 0, 255},
+		'a': {8, 4, 0, 255},       // near black
+		'd': {8, 9, 0, 255},       // too far from black, for a tolerance of 8
+		'w': {255, 255, 255, 255}, // white
+		'e': {250, 247, 255, 255}, // near white
+		'f': {245, 255, 255, 255}, // too far from white, for a tolerance of 9
+		'h': {3, 3, 3, 128},       // translucent near black
+		'.': {},
+		'r': {255, 0, 0, 255},
+	}
+	img := newTestImage(palette,
+		"kaad",
+		"weef",
+		"h.rk",
+	)
+	for _, tc := range []struct {
+		tolerance int
+		changed   int
+		want      []string
+	}{
+		{0, 0, []string{"kaad", "weef", "h.rk"}},
+		{8, 5, []string{"kkkd", "wwwf", "h.rk"}},
+		{9, 6, []string{"kkkk", "wwwf", "h.rk"}},
+		{10, 7, []string{"kkkk", "wwww", "h.rk"}},
+	} {
+		pi := NewPixelImage(img, false)
+		if got := pi.SetSnapExtremes(tc.tolerance); got != tc.changed {
+			t.Errorf("tolerance %d: expected %d pixels to be changed, got %d", tc.tolerance, tc.changed, got)
+		}
+		pi.SetColorFormat(ColorRGBA)
+		pi.Cover()
+		// The translucent pixel is snapped to black, but keeps its alpha
+		want := newTestImage(palette, tc.want...)
+		if tc.tolerance >= 3 {
+			want.SetNRGBA(0, 2, color.NRGBA{0, 0, 0, 128})
+		}
+		checkRendersAs(t, pi.Bytes(), want, 1)
+	}
+}
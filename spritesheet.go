@@ -0,0 +1,104 @@
+package png2svg
+
+import (
+	"bytes"
+	"image"
+	"strconv"
+
+	"github.com/xyproto/tinysvg"
+)
+
+// sprite is an image that is placed at the given offset in a sprite sheet
+type sprite struct {
+	img  image.Image
+	x, y int
+}
+
+// SpriteSheet can be used for placing several images within one SVG image.
+// Each image is converted separately and placed within a translated <g> tag.
+type SpriteSheet struct {
+	sprites []sprite
+	opts    Options
+}
+
+// NewSpriteSheet creates a new and empty SpriteSheet
+func NewSpriteSheet() *SpriteSheet {
+	return &SpriteSheet{}
+}
+
+// SetColorOptimize can be used to set the colorOptimize flag,
+// for using only 4096 colors.
+func (ss *SpriteSheet) SetColorOptimize(enabled bool) {
+	ss.opts.ColorOptimize = enabled
+}
+
+// SetQuantizeRounding can be used to select how color channels are rounded
+// when only 4096 colors are used. The default is RoundFloor.
+func (ss *SpriteSheet) SetQuantizeRounding(rounding Rounding) {
+	ss.opts.Rounding = rounding
+}
+
+// SetOptions sets the options that are used when converting each image
+func (ss *SpriteSheet) SetOptions(opts Options) {
+	ss.opts = opts
+}
+
+// Add adds an image to the sprite sheet, with the upper left corner at (x, y)
+func (ss *SpriteSheet) Add(img image.Image, x, y int) {
+	ss.sprites = append(ss.sprites, sprite{img, x, y})
+}
+
+// Size returns the width and height that is needed for containing all the added images
+func (ss *SpriteSheet) Size() (int, int) {
+	var w, h int
+	for _, s := range ss.sprites {
+		if right := s.x + s.img.Bounds().Dx(); right > w {
+			w = right
+		}
+		if bottom := s.y + s.img.Bounds().Dy(); bottom > h {
+			h = bottom
+		}
+	}
+	return w, h
+}
+
+// Bytes converts all the added images and returns the rendered SVG document as bytes
+func (ss *SpriteSheet) Bytes() []byte {
+	var (
+		buf        bytes.Buffer
+		needsXlink bool
+	)
+	writeViews(&buf, ss.opts.Views)
+	for i, s := range ss.sprites {
+		pi := NewPixelImage(s.img, false)
+		pi.SetOptions(ss.opts)
+		pi.classPrefix = "s" + strconv.Itoa(i)
+		pi.Cover()
+		needsXlink = needsXlink || pi.usesXlink()
+
+		if s.x == 0 && s.y == 0 {
+			buf.WriteString("<g>")
+		} else {
+			buf.WriteString("<g transform=\"translate(")
+			buf.WriteString(strconv.Itoa(s.x))
+			buf.WriteByte(',')
+			buf.WriteString(strconv.Itoa(s.y))
+			buf.WriteString(")\">")
+		}
+		pi.writeGroups(&buf)
+		buf.WriteString("</g>")
+	}
+
+	_, svgTag := tinysvg.NewTinySVG(ss.Size())
+	svgTag.AppendContent(buf.Bytes())
+	svgDocument := svgTag.Bytes()
+	if needsXlink {
+		svgDocument = addXlinkNamespace(svgDocument)
+	}
+	return optimize(append([]byte(xmlHeader), svgDocument...))
+}
+
+// WriteSVG will save the sprite sheet as an SVG image, or write it to stdout if filename is "-"
+func (ss *SpriteSheet) WriteSVG(filename string) error {
+	return writeFile(filename, ss.Bytes())
+}
@@ -0,0 +1,43 @@
+package png2svg
+
+import (
+	"image"
+	"image/draw"
+	"testing"
+)
+
+func TestSpriteSheet(t *testing.T) {
+	ship := newTestImage(testPalette,
+		"...rr...",
+		"..rrrr..",
+		".rrbbrr.",
+		"rrbbbbrr",
+		"rrrrrrrr",
+		"r.r..r.r",
+		"r......r",
+		"........",
+	)
+	alien := newTestImage(testPalette,
+		"..g..g..",
+		"...gg...",
+		"..gggg..",
+		".gkggkg.",
+		"gggggggg",
+		"g.gggg.g",
+		"g.g..g.g",
+		"...gg...",
+	)
+	ss := NewSpriteSheet()
+	ss.Add(ship, 0, 0)
+	ss.Add(alien, 8, 0)
+	if w, h := ss.Size(); w != 16 || h != 8 {
+		t.Fatalf("expected the size 16x8, got %dx%d", w, h)
+	}
+	svgDocument := ss.Bytes()
+	svgContains(t, svgDocument, `<g transform="translate(8,0)">`)
+
+	sheet := image.NewNRGBA(image.Rect(0, 0, 16, 8))
+	draw.Draw(sheet, image.Rect(0, 0, 8, 8), ship, image.Point{}, draw.Src)
+	draw.Draw(sheet, image.Rect(8, 0, 16, 8), alien, image.Point{}, draw.Src)
+	checkRendersAs(t, svgDocument, sheet, 0)
+}
@@ -0,0 +1,42 @@
+package png2svg
+
+// Stats contains information about the SVG elements that has been created
+type Stats struct {
+	// Rects is the number of rectangles, including the rectangles of the clipped opaque region
+	// and the rectangles that are filled with gradients
+	Rects int
+	// Colors is the number of distinct fill colors
+	Colors int
+	// Components is the number of connected components, if SetComponents is used
+	Components int
+	// EdgeRects is the number of rectangles that cover the edges of the transparent regions, if SetHybrid is used
+	EdgeRects int
+	// LargestRects and RowRects are the number of expanding rectangles that OrderLargest
+	// and OrderRow would place, if OrderLargest is used. The order that places fewer
+	// rectangles is used, so RowRects minus LargestRects is the improvement, if positive.
+	LargestRects, RowRects int
+}
+
+// Stats returns information about the SVG elements that has been created so far
+func (pi *PixelImage) Stats() Stats {
+	var stats Stats
+	for _, group := range pi.groupOrder {
+		stats.Rects += group.count
+	}
+	stats.Rects += len(pi.gradientRects)
+	stats.Colors = len(pi.groupOrder)
+	stats.EdgeRects = pi.edgeRects
+	stats.LargestRects, stats.RowRects = pi.largestRects, pi.rowRects
+	if pi.components {
+		stats.Components = len(pi.componentRects())
+	}
+	if pi.clipRegion != nil {
+		// The clip path rectangles and the background rectangle
+		stats.Rects += pi.clipRegion.count + 1
+		stats.Colors++
+	}
+	if pi.evenOddRegion != nil {
+		stats.Colors++
+	}
+	return stats
+}
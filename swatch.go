@@ -0,0 +1,99 @@
+package png2svg
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+const (
+	swatchWidth   = 64 // the width of each swatch, in pixels
+	swatchHeight  = 16 // the height of each swatch, in pixels
+	swatchColumns = 8  // the largest number of swatches per row
+	glyphScale    = 2  // the size of each pixel of the label glyphs, in pixels
+)
+
+// glyphs is a 3x5 pixel font for the labels of the swatches. Each row of a
+// glyph is given by the three lowest bits, where the highest bit is the left pixel.
+var glyphs = map[byte][5]uint8{
+	'0': {0x7, 0x5, 0x5, 0x5, 0x7},
+	'1': {0x2, 0x6, 0x2, 0x2, 0x7},
+	'2': {0x7, 0x1, 0x7, 0x4, 0x7},
+	'3': {0x7, 0x1, 0x3, 0x1, 0x7},
+	'4': {0x5, 0x5, 0x7, 0x1, 0x1},
+	'5': {0x7, 0x4, 0x7, 0x1, 0x7},
+	'6': {0x7, 0x4, 0x7, 0x5, 0x7},
+	'7': {0x7, 0x1, 0x1, 0x2, 0x2},
+	'8': {0x7, 0x5, 0x7, 0x5, 0x7},
+	'9': {0x7, 0x5, 0x7, 0x1, 0x7},
+	'a': {0x2, 0x5, 0x7, 0x5, 0x5},
+	'b': {0x6, 0x5, 0x6, 0x5, 0x6},
+	'c': {0x3, 0x4, 0x4, 0x4, 0x3},
+	'd': {0x6, 0x5, 0x5, 0x5, 0x6},
+	'e': {0x7, 0x4, 0x6, 0x4, 0x7},
+	'f': {0x7, 0x4, 0x6, 0x4, 0x4},
+}
+
+// drawLabel draws the given text with the glyphs, with the upper left corner at (x, y)
+func drawLabel(img *image.NRGBA, x, y int, text string, c color.NRGBA) {
+	for _, r := range []byte(text) {
+		glyph := glyphs[r]
+		for row, bits := range glyph {
+			for col := 0; col < 3; col++ {
+				if bits&(4>>uint(col)) == 0 {
+					continue
+				}
+				for dy := 0; dy < glyphScale; dy++ {
+					for dx := 0; dx < glyphScale; dx++ {
+						img.SetNRGBA(x+col*glyphScale+dx, y+row*glyphScale+dy, c)
+					}
+				}
+			}
+		}
+		x += 4 * glyphScale
+	}
+}
+
+// Swatch returns an image with one swatch per distinct color of the pixels that are
+// not transparent, most used first, from left to right and then from top to bottom.
+// Each swatch is labeled with the hex color, like "ff8800", in black or white, depending
+// on which is most readable. The colors are the colors of the pixels, after the options
+// that modify the pixels have been applied, but before they are shortened by SetColorOptimize.
+func (pi *PixelImage) Swatch() *image.NRGBA {
+	colors := pi.DominantColors(0)
+	columns := swatchColumns
+	if len(colors) < columns {
+		columns = len(colors)
+	}
+	rows := 0
+	if columns > 0 {
+		rows = (len(colors) + columns - 1) / columns
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, columns*swatchWidth, rows*swatchHeight))
+	for i, cc := range colors {
+		x, y := (i%swatchColumns)*swatchWidth, (i/swatchColumns)*swatchHeight
+		for sy := y; sy < y+swatchHeight; sy++ {
+			for sx := x; sx < x+swatchWidth; sx++ {
+				img.SetNRGBA(sx, sy, cc.Color)
+			}
+		}
+		labelColor := color.NRGBA{0xff, 0xff, 0xff, 0xff}
+		if 0.299*float64(cc.Color.R)+0.587*float64(cc.Color.G)+0.114*float64(cc.Color.B) > 127 {
+			labelColor = color.NRGBA{0, 0, 0, 0xff}
+		}
+		label := string(hexColor(int(cc.Color.R), int(cc.Color.G), int(cc.Color.B))[1:])
+		drawLabel(img, x+glyphScale*2, y+(swatchHeight-5*glyphScale)/2, label, labelColor)
+	}
+	return img
+}
+
+// WriteSwatch will save the swatches that are returned by Swatch as a PNG image,
+// or write it to stdout if filename is "-"
+func (pi *PixelImage) WriteSwatch(filename string) error {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, pi.Swatch()); err != nil {
+		return err
+	}
+	return writeFile(filename, buf.Bytes())
+}
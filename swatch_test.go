@@ -0,0 +1,119 @@
+package png2svg
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSwatch(t *testing.T) {
+	img := newTestImage(testPalette,
+		"bbbbbbbbb",
+		"bbwwwwwkk",
+		"yyyy...rk",
+	)
+	want := []color.NRGBA{testPalette['b'], testPalette['w'], testPalette['y'], testPalette['k'], testPalette['r']}
+	pi := NewPixelImage(img, false)
+	swatch := pi.Swatch()
+	if got := swatch.Bounds(); got != image.Rect(0, 0, len(want)*swatchWidth, swatchHeight) {
+		t.Fatalf("expected one row of %d swatches, got the bounds %v", len(want), got)
+	}
+	for i, c := range want {
+		x := i * swatchWidth
+		// The corners are not covered by the label
+		if got := swatch.NRGBAAt(x, 0); got != c {
+			t.Errorf("expected swatch %d to be %v, got %v", i, c, got)
+		}
+		if got := swatch.NRGBAAt(x+swatchWidth-1, swatchHeight-1); got != c {
+			t.Errorf("expected swatch %d to be %v all the way, got %v", i, c, got)
+		}
+		// The label is black on light colors and white on dark colors
+		labelColor := color.NRGBA{0xff, 0xff, 0xff, 0xff}
+		if c == testPalette['w'] || c == testPalette['y'] {
+			labelColor = color.NRGBA{0, 0, 0, 0xff}
+		}
+		labelPixels := 0
+		for y := 0; y < swatchHeight; y++ {
+			for sx := x; sx < x+swatchWidth; sx++ {
+				if swatch.NRGBAAt(sx, y) == labelColor {
+					labelPixels++
+				}
+			}
+		}
+		if labelPixels == 0 {
+			t.Errorf("expected swatch %d to have a label in %v", i, labelColor)
+		}
+	}
+
+	// Transparent images have no swatches
+	if got := NewPixelImage(newTestImage(testPalette, ".."), false).Swatch().Bounds(); !got.Empty() {
+		t.Errorf("expected no swatches for a transparent image, got %v", got)
+	}
+
+	// More than swatchColumns colors are wrapped to the next row
+	many := NewPixelImage(noiseImage(8, 8, swatchColumns+1), false)
+	if got := many.Swatch().Bounds(); got != image.Rect(0, 0, swatchColumns*swatchWidth, 2*swatchHeight) {
+		t.Errorf("expected two rows of swatches, got the bounds %v", got)
+	}
+}
+
+func TestDrawLabel(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 4*glyphScale, 5*glyphScale))
+	black := color.NRGBA{0, 0, 0, 0xff}
+	drawLabel(img, 0, 0, "1", black)
+	// The glyph for 1 has a pixel in the middle of the top row, and a full bottom row
+	for _, tc := range []struct {
+		x, y int
+		set  bool
+	}{
+		{0, 0, false},
+		{1, 0, true},
+		{2, 0, false},
+		{0, 4, true},
+		{1, 4, true},
+		{2, 4, true},
+		{3, 4, false},
+	} {
+		if got := img.NRGBAAt(tc.x*glyphScale, tc.y*glyphScale) == black; got != tc.set {
+			t.Errorf("expected the glyph pixel (%d, %d) to be set: %v", tc.x, tc.y, tc.set)
+		}
+	}
+}
+
+func TestWriteSwatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "png2svg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	pi := NewPixelImage(newTestImage(testPalette, "rgb"), false)
+	filename := filepath.Join(dir, "swatch.png")
+	if err := pi.WriteSwatch(filename); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	decoded, err := png.Decode(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	swatch := pi.Swatch()
+	if decoded.Bounds() != swatch.Bounds() {
+		t.Fatalf("expected the bounds %v, got %v", swatch.Bounds(), decoded.Bounds())
+	}
+	for y := 0; y < swatch.Bounds().Dy(); y++ {
+		for x := 0; x < swatch.Bounds().Dx(); x++ {
+			if got := color.NRGBAModel.Convert(decoded.At(x, y)); got != swatch.NRGBAAt(x, y) {
+				t.Fatalf("pixel (%d, %d) is %v, expected %v", x, y, got, swatch.NRGBAAt(x, y))
+			}
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package png2svg
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+)
+
+// Mirror is the kind of symmetry that was found in the image
+type Mirror int
+
+const (
+	// MirrorNone means that the image is not symmetric, or that symmetry is not checked for
+	MirrorNone Mirror = iota
+	// MirrorHorizontal means that the right half is the left half, mirrored
+	MirrorHorizontal
+	// MirrorVertical means that the bottom half is the top half, mirrored
+	MirrorVertical
+)
+
+// SetSymmetry can be used for checking if the image is symmetric, left-right or
+// top-bottom, when covering the pixels. If it is, only one half is covered, and
+// the other half is written as a mirrored <use> of the first half.
+func (pi *PixelImage) SetSymmetry(enabled bool) {
+	pi.symmetry = enabled
+}
+
+// Symmetry returns the symmetry that was found when covering the pixels
+func (pi *PixelImage) Symmetry() Mirror {
+	return pi.mirror
+}
+
+// samePixel returns true if the pixels at the given indices look the same
+func (pi *PixelImage) samePixel(i, j int) bool {
+	p, q := pi.pixels[i], pi.pixels[j]
+	if p.a == 0 && q.a == 0 {
+		return true
+	}
+	return p.r == q.r && p.g == q.g && p.b == q.b && p.a == q.a
+}
+
+// translucentMiddle returns true if the width or height is odd, and the middle column
+// or row has partially transparent pixels. The middle line is drawn both by the first
+// half and by the mirrored half, which blends partially transparent pixels twice.
+func (pi *PixelImage) translucentMiddle(mirror Mirror) bool {
+	for i, p := range pi.pixels {
+		x, y := i%pi.w, i/pi.w
+		middle := (mirror == MirrorHorizontal && pi.w%2 == 1 && x == pi.w/2) ||
+			(mirror == MirrorVertical && pi.h%2 == 1 && y == pi.h/2)
+		if middle && p.a > 0 && p.a < 255 {
+			return true
+		}
+	}
+	return false
+}
+
+// findSymmetry checks if the image is symmetric, left-right first and then top-bottom.
+// An image is not treated as symmetric if the middle line has partially transparent pixels.
+func (pi *PixelImage) findSymmetry() Mirror {
+	symmetric := func(mirrored func(x, y int) int) bool {
+		for y := 0; y < pi.h; y++ {
+			for x := 0; x < pi.w; x++ {
+				if !pi.samePixel(y*pi.w+x, mirrored(x, y)) {
+					return false
+				}
+			}
+		}
+		return true
+	}
+	if pi.w > 1 && symmetric(func(x, y int) int { return y*pi.w + pi.w - 1 - x }) && !pi.translucentMiddle(MirrorHorizontal) {
+		return MirrorHorizontal
+	}
+	if pi.h > 1 && symmetric(func(x, y int) int { return (pi.h-1-y)*pi.w + x }) && !pi.translucentMiddle(MirrorVertical) {
+		return MirrorVertical
+	}
+	return MirrorNone
+}
+
+// mirroredHalf returns true if the pixel at the given index is in the second half
+// of a symmetric image, which is drawn by the mirrored <use>.
+// If the width or height is odd, the middle column or row belongs to the first half.
+func (pi *PixelImage) mirroredHalf(i int) bool {
+	x, y := i%pi.w, i/pi.w
+	return (pi.mirror == MirrorHorizontal && x >= (pi.w+1)/2) || (pi.mirror == MirrorVertical && y >= (pi.h+1)/2)
+}
+
+// coverMirroredHalf checks for symmetry, and if the image is symmetric, marks the
+// pixels of the second half as covered, since they are covered by the mirrored <use>.
+func (pi *PixelImage) coverMirroredHalf() {
+	pi.mirror = pi.findSymmetry()
+	for i, p := range pi.pixels {
+		if pi.mirroredHalf(i) {
+			p.covered = true
+		}
+	}
+	if pi.verbose && pi.mirror != MirrorNone {
+		fmt.Println("The image is symmetric, only one half is covered.")
+	}
+}
+
+// writeMirrored writes the shapes within a <g> tag, and a <use> tag that mirrors them
+func (pi *PixelImage) writeMirrored(buf *bytes.Buffer) {
+	id := pi.classPrefix + "half"
+	buf.WriteString("<g id=\"")
+	buf.WriteString(id)
+	buf.WriteString("\">")
+	pi.writeShapes(buf)
+	buf.WriteString("</g><use xlink:href=\"#")
+	buf.WriteString(id)
+	buf.WriteString("\" transform=\"")
+	if pi.mirror == MirrorHorizontal {
+		buf.WriteString("matrix(-1 0 0 1 " + strconv.Itoa(pi.w) + " 0)")
+	} else {
+		buf.WriteString("matrix(1 0 0 -1 0 " + strconv.Itoa(pi.h) + ")")
+	}
+	buf.WriteString("\"/>")
+}
+
+// xlinkNamespace is the namespace declaration that is needed for xlink:href
+const xlinkNamespace = ` xmlns:xlink="http://www.w3.org/1999/xlink"`
+
+// usesXlink returns true if the shapes are written with xlink:href attributes,
+// either for a mirrored half, for a fallback image or for deduplicated shapes
+func (pi *PixelImage) usesXlink() bool {
+	return pi.mirror != MirrorNone || pi.fallback > 0 || (pi.dedup && len(pi.dedupShapes()) > 0)
+}
+
+// addXlinkNamespace adds the xlink namespace to the first <svg> tag in the given document
+func addXlinkNamespace(svgDocument []byte) []byte {
+	return bytes.Replace(svgDocument, []byte("<svg"), []byte("<svg"+xlinkNamespace), 1)
+}
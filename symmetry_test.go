@@ -0,0 +1,59 @@
+package png2svg
+
+import (
+	"testing"
+)
+
+func TestSymmetry(t *testing.T) {
+	for _, tc := range []struct {
+		rows []string
+		want Mirror
+	}{
+		{[]string{"rbbr", "gkkg"}, MirrorHorizontal},
+		{[]string{"rbr", "gkg"}, MirrorHorizontal},
+		{[]string{"rb", "gk", "rb"}, MirrorVertical},
+		{[]string{"rb", "gk"}, MirrorNone},
+		// Partially transparent pixels that are not on the middle line are mirrored
+		{[]string{"hbbh", "gkkg"}, MirrorHorizontal},
+		{[]string{"hbh", "gkg"}, MirrorHorizontal},
+		// The middle line would be drawn twice, and partially transparent pixels would be blended twice
+		{[]string{"rhr", "gkg"}, MirrorNone},
+		{[]string{"rb", "hh", "rb"}, MirrorNone},
+		// Fully transparent pixels on the middle line are not drawn at all
+		{[]string{"r.r", "gkg"}, MirrorHorizontal},
+	} {
+		img := newTestImage(testPalette, tc.rows...)
+		pi := NewPixelImage(img, false)
+		pi.SetOptions(Options{Symmetry: true, ColorFormat: ColorRGBA})
+		pi.Cover()
+		if got := pi.Symmetry(); got != tc.want {
+			t.Errorf("%v: expected the symmetry %d, got %d", tc.rows, tc.want, got)
+		}
+		r := checkRendersAs(t, pi.Bytes(), img, 1)
+		for y := 0; y < r.h; y++ {
+			for x := 0; x < r.w; x++ {
+				if want, got := img.NRGBAAt(x, y).A, r.At(x, y).A; want != got {
+					t.Errorf("%v: pixel (%d, %d) has the alpha %d, expected %d", tc.rows, x, y, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestSymmetryWithClip(t *testing.T) {
+	for _, rows := range [][]string{
+		{"rwwr", "rbbr", "wbbw"},
+		{"rwr", "bbb", "w.w"},
+		{"rw", "bb", "rw"},
+	} {
+		img := newTestImage(testPalette, rows...)
+		pi := NewPixelImage(img, false)
+		pi.SetOptions(Options{Clip: true, Symmetry: true})
+		pi.Cover()
+		if pi.Symmetry() == MirrorNone {
+			t.Errorf("%v: expected the image to be mirrored", rows)
+		}
+		// The clipped background is mirrored along with the first half, without covering it
+		checkRendersAs(t, pi.Bytes(), img, 0)
+	}
+}
@@ -0,0 +1,74 @@
+package png2svg
+
+import (
+	"bytes"
+	"errors"
+	"html"
+	"strconv"
+	"strings"
+)
+
+// View is a named region of the SVG image, that is written as a <view> tag,
+// so that the region can be shown on its own with an URL fragment, like image.svg#name
+type View struct {
+	Name          string
+	X, Y          int
+	Width, Height int
+}
+
+// ErrInvalidView is returned by ParseView if the view is not on the form name:x,y,w,h
+var ErrInvalidView = errors.New("invalid view, use name:x,y,w,h")
+
+// ParseView parses a view on the form "name:x,y,w,h", like "home:0,0,16,16".
+// The name is used as an id, and can not contain whitespace.
+func ParseView(s string) (View, error) {
+	colon := strings.LastIndex(s, ":")
+	if colon < 1 || strings.ContainsAny(s[:colon], " \t\r\n") {
+		return View{}, ErrInvalidView
+	}
+	fields := strings.Split(s[colon+1:], ",")
+	if len(fields) != 4 {
+		return View{}, ErrInvalidView
+	}
+	var numbers [4]int
+	for i, field := range fields {
+		n, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || n < 0 {
+			return View{}, ErrInvalidView
+		}
+		numbers[i] = n
+	}
+	if numbers[2] == 0 || numbers[3] == 0 {
+		return View{}, ErrInvalidView
+	}
+	return View{s[:colon], numbers[0], numbers[1], numbers[2], numbers[3]}, nil
+}
+
+// String returns the view on the form "name:x,y,w,h", as used by ParseView
+func (v View) String() string {
+	return v.Name + ":" + strconv.Itoa(v.X) + "," + strconv.Itoa(v.Y) + "," + strconv.Itoa(v.Width) + "," + strconv.Itoa(v.Height)
+}
+
+// SetViews can be used for adding named regions to the SVG image, as <view> tags.
+// This is useful for sprite sheets, where each icon can then be used with an
+// URL fragment, like sprites.svg#home.
+func (pi *PixelImage) SetViews(views []View) {
+	pi.views = views
+}
+
+// writeViews writes a <view> tag for each of the given views
+func writeViews(buf *bytes.Buffer, views []View) {
+	for _, v := range views {
+		buf.WriteString("<view id=\"")
+		buf.WriteString(html.EscapeString(v.Name))
+		buf.WriteString("\" viewBox=\"")
+		buf.WriteString(strconv.Itoa(v.X))
+		buf.WriteByte(' ')
+		buf.WriteString(strconv.Itoa(v.Y))
+		buf.WriteByte(' ')
+		buf.WriteString(strconv.Itoa(v.Width))
+		buf.WriteByte(' ')
+		buf.WriteString(strconv.Itoa(v.Height))
+		buf.WriteString("\"/>")
+	}
+}
@@ -0,0 +1,68 @@
+package png2svg
+
+import (
+	"encoding/xml"
+	"fmt"
+	"testing"
+)
+
+func TestParseView(t *testing.T) {
+	for _, tc := range []struct {
+		s    string
+		want View
+		err  error
+	}{
+		{"home:0,0,16,16", View{"home", 0, 0, 16, 16}, nil},
+		{"icon-search:16, 0, 16, 8", View{"icon-search", 16, 0, 16, 8}, nil},
+		{"a:b:1,2,3,4", View{"a:b", 1, 2, 3, 4}, nil},
+		{":0,0,16,16", View{}, ErrInvalidView},
+		{"my icon:0,0,16,16", View{}, ErrInvalidView},
+		{"home:0,0,16", View{}, ErrInvalidView},
+		{"home:0,0,0,16", View{}, ErrInvalidView},
+		{"home:-1,0,16,16", View{}, ErrInvalidView},
+		{"home:x,0,16,16", View{}, ErrInvalidView},
+		{"home", View{}, ErrInvalidView},
+	} {
+		got, err := ParseView(tc.s)
+		if got != tc.want || err != tc.err {
+			t.Errorf("ParseView(%q) = %v, %v, expected %v, %v", tc.s, got, err, tc.want, tc.err)
+		}
+		if err == nil {
+			if again, err := ParseView(got.String()); again != got || err != nil {
+				t.Errorf("expected %q to parse as %v, got %v, %v", got.String(), got, again, err)
+			}
+		}
+	}
+}
+
+func TestViews(t *testing.T) {
+	img := newTestImage(testPalette,
+		"rrbb",
+		"rrbb",
+	)
+	views := []View{{"left", 0, 0, 2, 2}, {"a&b", 2, 0, 2, 2}}
+	svgDocument := convertBytes(t, img, Options{Views: views})
+	checkRendersAs(t, svgDocument, img, 0)
+
+	var root svgNode
+	if err := xml.Unmarshal(svgDocument, &root); err != nil {
+		t.Fatal(err)
+	}
+	var got []View
+	for _, child := range root.Children {
+		if child.XMLName.Local != "view" {
+			continue
+		}
+		id, _ := child.attr("id")
+		viewBox, _ := child.attr("viewBox")
+		var v View
+		if _, err := fmt.Sscan(viewBox, &v.X, &v.Y, &v.Width, &v.Height); err != nil {
+			t.Fatalf("invalid viewBox %q: %v", viewBox, err)
+		}
+		v.Name = id
+		got = append(got, v)
+	}
+	if len(got) != len(views) || got[0] != views[0] || got[1] != views[1] {
+		t.Errorf("expected the views %v, got %v", views, got)
+	}
+}